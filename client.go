@@ -2,6 +2,7 @@ package executor
 
 import (
 	"io"
+	"time"
 
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/routing-info/internalroutes"
@@ -20,12 +21,18 @@ type Client interface {
 	ListContainers(lager.Logger) ([]Container, error)
 	GetBulkMetrics(lager.Logger) (map[string]Metrics, error)
 	RemainingResources(lager.Logger) (ExecutorResources, error)
+	// TenantResources is kept apart from RemainingResources because a tenant
+	// quota is itself an ExecutorResources, which therefore cannot hold the
+	// tenants.
+	TenantResources(lager.Logger) (map[string]TenantResources, error)
 	TotalResources(lager.Logger) (ExecutorResources, error)
 	GetFiles(logger lager.Logger, guid string, path string) (io.ReadCloser, error)
+	GetStepStatus(logger lager.Logger, guid string) (StepStatus, error)
 	VolumeDrivers(logger lager.Logger) ([]string, error)
 	SubscribeToEvents(lager.Logger) (EventSource, error)
 	Healthy(lager.Logger) bool
 	SetHealthy(lager.Logger, bool)
+	Drain(lager.Logger, DrainOptions) error
 	Cleanup(lager.Logger)
 }
 
@@ -40,13 +47,22 @@ type AllocationRequest struct {
 	Guid string
 	Resource
 	Tags
+
+	// SidecarResource is reserved on top of Resource for the sidecars of the
+	// container
+	SidecarResource Resource
 }
 
 func NewAllocationRequest(guid string, resource *Resource, tags Tags) AllocationRequest {
+	return NewAllocationRequestWithSidecars(guid, resource, &Resource{}, tags)
+}
+
+func NewAllocationRequestWithSidecars(guid string, resource, sidecars *Resource, tags Tags) AllocationRequest {
 	return AllocationRequest{
-		Guid:     guid,
-		Resource: *resource,
-		Tags:     tags,
+		Guid:            guid,
+		Resource:        *resource,
+		Tags:            tags,
+		SidecarResource: *sidecars,
 	}
 }
 
@@ -87,6 +103,42 @@ func NewRunRequest(guid string, runInfo *RunInfo, tags Tags) RunRequest {
 	}
 }
 
+const (
+	LifecycleTag  = "lifecycle"
+	TaskLifecycle = "task"
+	LRPLifecycle  = "lrp"
+
+	DefaultDrainMaxConcurrentStops = 1
+	DefaultDrainStopTimeout        = 1 * time.Minute
+)
+
+var DefaultDrainStopOrder = []Tags{
+	{LifecycleTag: TaskLifecycle},
+	{LifecycleTag: LRPLifecycle},
+}
+
+// Containers matching the tags of an earlier StopOrder entry are stopped
+// before any container matching a later entry. Containers that do not match
+// any entry are stopped last.
+type DrainOptions struct {
+	MaxConcurrentStops int           `json:"max_concurrent_stops"`
+	StopOrder          []Tags        `json:"stop_order"`
+	StopTimeout        time.Duration `json:"stop_timeout"`
+}
+
+func (o DrainOptions) WithDefaults() DrainOptions {
+	if o.MaxConcurrentStops <= 0 {
+		o.MaxConcurrentStops = DefaultDrainMaxConcurrentStops
+	}
+	if o.StopOrder == nil {
+		o.StopOrder = DefaultDrainStopOrder
+	}
+	if o.StopTimeout <= 0 {
+		o.StopTimeout = DefaultDrainStopTimeout
+	}
+	return o
+}
+
 type UpdateRequest struct {
 	Guid           string
 	InternalRoutes internalroutes.InternalRoutes `json:"internal_routes"`
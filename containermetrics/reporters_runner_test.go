@@ -12,6 +12,7 @@ import (
 	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/executor/containermetrics"
 	efakes "code.cloudfoundry.org/executor/fakes"
+	"code.cloudfoundry.org/go-loggregator/v8/rpc/loggregator_v2"
 	"code.cloudfoundry.org/lager/lagertest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -1090,6 +1091,56 @@ var _ = Describe("ReportersRunner", func() {
 		})
 	})
 
+	Context("when the container has sidecars", func() {
+		BeforeEach(func() {
+			containers := []executor.Container{
+				{
+					Guid: "container-0",
+					RunInfo: executor.RunInfo{
+						Sidecars: []executor.Sidecar{
+							{MemoryMB: 64, DiskMB: 128},
+							{MemoryMB: 32},
+						},
+					},
+				},
+			}
+			fakeExecutorClient.ListContainersReturnsOnCall(0, containers, nil)
+
+			metricsMap := map[string]executor.Metrics{
+				"container-0": {
+					executor.MetricsConfig{Tags: map[string]string{"source_id": "some-source-id", "instance_id": "1"}},
+					executor.ContainerMetrics{},
+				},
+			}
+			fakeExecutorClient.GetBulkMetricsReturns(metricsMap, nil)
+		})
+
+		sentSidecarMetrics := func(name string) map[string]int {
+			values := map[string]int{}
+			for i := 0; i < fakeMetronClient.SendMebiBytesCallCount(); i++ {
+				metric, value, opts := fakeMetronClient.SendMebiBytesArgsForCall(i)
+				if metric != name {
+					continue
+				}
+				envelope := &loggregator_v2.Envelope{Tags: map[string]string{}}
+				for _, opt := range opts {
+					opt(envelope)
+				}
+				Expect(envelope.Tags).To(HaveKeyWithValue("source_id", "some-source-id"))
+				Expect(envelope.Tags).To(HaveKeyWithValue("instance_id", "1"))
+				values[envelope.Tags["sidecar"]] = value
+			}
+			return values
+		}
+
+		It("emits the memory and disk quotas of every sidecar", func() {
+			fakeClock.WaitForWatcherAndIncrement(interval)
+			Eventually(fakeExecutorClient.GetBulkMetricsCallCount).Should(Equal(2))
+			Eventually(func() map[string]int { return sentSidecarMetrics("SidecarMemoryQuota") }).Should(Equal(map[string]int{"0": 64, "1": 32}))
+			Eventually(func() map[string]int { return sentSidecarMetrics("SidecarDiskQuota") }).Should(Equal(map[string]int{"0": 128, "1": 0}))
+		})
+	})
+
 	Context("when metric tags are not provided", func() {
 		BeforeEach(func() {
 			containers := []executor.Container{
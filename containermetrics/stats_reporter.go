@@ -7,9 +7,15 @@ import (
 
 	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/executor"
+	loggregator "code.cloudfoundry.org/go-loggregator/v8"
 	"code.cloudfoundry.org/lager"
 )
 
+const (
+	sidecarMemoryMetric = "SidecarMemoryQuota"
+	sidecarDiskMetric   = "SidecarDiskQuota"
+)
+
 type cpuInfo struct {
 	timeSpentInCPU time.Duration
 	timeOfSample   time.Time
@@ -61,7 +67,7 @@ func (reporter *StatsReporter) Report(logger lager.Logger, containers []executor
 			metric.MemoryLimitInBytes = uint64(float64(metric.MemoryLimitInBytes) - reporter.proxyMemoryAllocation)
 		}
 
-		repMetrics, cpu := reporter.calculateAndSendMetrics(logger, metric.MetricsConfig, metric.ContainerMetrics, previousCPUInfo, timeStamp)
+		repMetrics, cpu := reporter.calculateAndSendMetrics(logger, metric.MetricsConfig, metric.ContainerMetrics, container.Sidecars, previousCPUInfo, timeStamp)
 		if cpu != nil {
 			cpuInfos[guid] = cpu
 		}
@@ -81,6 +87,7 @@ func (reporter *StatsReporter) calculateAndSendMetrics(
 	logger lager.Logger,
 	metricsConfig executor.MetricsConfig,
 	containerMetrics executor.ContainerMetrics,
+	sidecars []executor.Sidecar,
 	previousInfo *cpuInfo,
 	now time.Time,
 ) (*CachedContainerMetrics, *cpuInfo) {
@@ -122,6 +129,8 @@ func (reporter *StatsReporter) calculateAndSendMetrics(
 				"tags":          metricsConfig.Tags,
 			})
 		}
+
+		reporter.sendSidecarMetrics(logger, metricsConfig.Tags, sidecars)
 	}
 
 	return &CachedContainerMetrics{
@@ -134,6 +143,29 @@ func (reporter *StatsReporter) calculateAndSendMetrics(
 	}, &currentInfo
 }
 
+// sendSidecarMetrics reports the memory and disk quotas of every sidecar,
+// which are part of the container limits but not available to the app.
+func (reporter *StatsReporter) sendSidecarMetrics(logger lager.Logger, containerTags map[string]string, sidecars []executor.Sidecar) {
+	for i, sidecar := range sidecars {
+		tags := make(map[string]string, len(containerTags)+1)
+		for k, v := range containerTags {
+			tags[k] = v
+		}
+		tags["sidecar"] = strconv.Itoa(i)
+		tagOption := loggregator.WithEnvelopeTags(tags)
+
+		err := reporter.metronClient.SendMebiBytes(sidecarMemoryMetric, int(sidecar.MemoryMB), tagOption)
+		if err != nil {
+			logger.Error("failed-to-send-sidecar-memory-metric", err, lager.Data{"sidecar": i})
+		}
+
+		err = reporter.metronClient.SendMebiBytes(sidecarDiskMetric, int(sidecar.DiskMB), tagOption)
+		if err != nil {
+			logger.Error("failed-to-send-sidecar-disk-metric", err, lager.Data{"sidecar": i})
+		}
+	}
+}
+
 func calculateInfo(containerMetrics executor.ContainerMetrics, previousInfo *cpuInfo, now time.Time) (cpuInfo, float64) {
 	timeOfSample := now
 	if containerMetrics.ContainerAgeInNanoseconds != 0 {
@@ -0,0 +1,771 @@
+package chunkeddownloader
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/cacheddownloader"
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	DefaultChunkSize   = 16 * 1024 * 1024
+	DefaultParallelism = 4
+
+	// ChunkAttempts is the number of times a chunk is requested before the
+	// download fails. Every attempt resumes where the previous one stopped.
+	ChunkAttempts = 3
+)
+
+var (
+	ErrDownloadCancelled  = errors.New("download cancelled")
+	ErrRangesNotSupported = errors.New("server does not support range requests")
+	ErrContentChanged     = errors.New("content changed during download")
+
+	errNotModified = errors.New("not modified")
+)
+
+type ChecksumMismatchError struct {
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch: %s expected %s, got %s", e.Algorithm, e.Expected, e.Actual)
+}
+
+// IsRetryable is false, as downloading the same content again does not
+// change its checksum.
+func (e *ChecksumMismatchError) IsRetryable() bool {
+	return false
+}
+
+// ProgressFunc is called with the number of bytes downloaded so far and the
+// total size of the download. Calls are never concurrent.
+type ProgressFunc func(downloaded, total int64)
+
+//go:generate counterfeiter -o fake_chunkeddownloader/fake_chunkeddownloader.go . ChunkedDownloader
+
+// ChunkedDownloader downloads a file with parallel HTTP range requests into a
+// temporary file, whose path is returned. The caller removes the file.
+// ErrRangesNotSupported is returned when the server cannot serve ranges.
+//
+// Fetch returns the download as a tar stream like cacheddownloader does, and
+// keeps it in the cache under cacheKey when both are given.
+type ChunkedDownloader interface {
+	Download(
+		logger lager.Logger,
+		url *url.URL,
+		checksum cacheddownloader.ChecksumInfoType,
+		progress ProgressFunc,
+		cancel <-chan struct{},
+	) (string, int64, error)
+
+	Fetch(
+		logger lager.Logger,
+		url *url.URL,
+		cacheKey string,
+		checksum cacheddownloader.ChecksumInfoType,
+		progress ProgressFunc,
+		cancel <-chan struct{},
+	) (io.ReadCloser, int64, error)
+}
+
+// Cache is the part of *cacheddownloader.FileCache that keeps fetched content.
+type Cache interface {
+	Add(logger lager.Logger, cacheKey, sourcePath string, size int64, cachingInfo cacheddownloader.CachingInfoType) (*cacheddownloader.CachedFile, error)
+	Get(logger lager.Logger, cacheKey string) (*cacheddownloader.CachedFile, cacheddownloader.CachingInfoType, error)
+}
+
+type downloader struct {
+	httpClient  *http.Client
+	cache       Cache
+	tempDir     string
+	chunkSize   int64
+	parallelism int
+
+	partialsLock sync.Mutex
+	partials     map[string]bool
+}
+
+type chunk struct {
+	start int64
+	end   int64
+}
+
+func New(tempDir string, chunkSize int64, parallelism int, timeout time.Duration, tlsConfig *tls.Config) ChunkedDownloader {
+	return NewWithCache(tempDir, chunkSize, parallelism, timeout, tlsConfig, nil)
+}
+
+func NewWithCache(
+	tempDir string,
+	chunkSize int64,
+	parallelism int,
+	timeout time.Duration,
+	tlsConfig *tls.Config,
+	cache Cache,
+) ChunkedDownloader {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		Dial: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).Dial,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     tlsConfig,
+		MaxIdleConnsPerHost: parallelism,
+	}
+
+	return &downloader{
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		},
+		cache:       cache,
+		tempDir:     tempDir,
+		chunkSize:   chunkSize,
+		parallelism: parallelism,
+		partials:    map[string]bool{},
+	}
+}
+
+func (d *downloader) Download(
+	logger lager.Logger,
+	url *url.URL,
+	checksum cacheddownloader.ChecksumInfoType,
+	progress ProgressFunc,
+	cancel <-chan struct{},
+) (string, int64, error) {
+	path, size, _, err := d.download(logger, url, checksum, cacheddownloader.CachingInfoType{}, progress, cancel)
+	return path, size, err
+}
+
+func (d *downloader) Fetch(
+	logger lager.Logger,
+	url *url.URL,
+	cacheKey string,
+	checksum cacheddownloader.ChecksumInfoType,
+	progress ProgressFunc,
+	cancel <-chan struct{},
+) (io.ReadCloser, int64, error) {
+	if d.cache == nil || cacheKey == "" {
+		path, size, _, err := d.download(logger, url, checksum, cacheddownloader.CachingInfoType{}, progress, cancel)
+		if err != nil {
+			return nil, 0, err
+		}
+		return d.transform(logger, path, size, "", cacheddownloader.CachingInfoType{})
+	}
+
+	// the key is prefixed so it cannot collide with the hashed keys of the
+	// cached downloader sharing the cache
+	cacheKey = "chunked-" + cacheKey
+
+	cachedFile, cachedInfo, err := d.cache.Get(logger, cacheKey)
+	if err != nil {
+		cachedFile = nil
+		cachedInfo = cacheddownloader.CachingInfoType{}
+	}
+
+	path, size, cachingInfo, err := d.download(logger, url, checksum, cachedInfo, progress, cancel)
+	if err == errNotModified {
+		logger.Info("serving-from-cache", lager.Data{"cache-key": cacheKey})
+		return cachedFile, size, nil
+	}
+	if cachedFile != nil {
+		cachedFile.Close()
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// without validators a cached copy could never be served again
+	if cachingInfo == (cacheddownloader.CachingInfoType{}) {
+		cacheKey = ""
+	}
+
+	return d.transform(logger, path, size, cacheKey, cachingInfo)
+}
+
+// transform turns the downloaded file into a tar stream. With a cache key
+// the stream is moved into the cache and served from there.
+func (d *downloader) transform(
+	logger lager.Logger,
+	path string,
+	size int64,
+	cacheKey string,
+	cachingInfo cacheddownloader.CachingInfoType,
+) (io.ReadCloser, int64, error) {
+	defer os.Remove(path)
+
+	tarPath := path + ".tar"
+	tarSize, err := cacheddownloader.TarTransform(path, tarPath)
+	if err != nil {
+		logger.Error("tar-transform-failed", err)
+		os.Remove(tarPath)
+		return nil, 0, err
+	}
+
+	if cacheKey != "" {
+		cachedFile, err := d.cache.Add(logger, cacheKey, tarPath, tarSize, cachingInfo)
+		if err == nil {
+			os.Remove(tarPath)
+			return cachedFile, size, nil
+		}
+		logger.Info("not-cached", lager.Data{"error": err.Error()})
+	}
+
+	tarFile, err := os.Open(tarPath)
+	if err != nil {
+		logger.Error("open-tar-failed", err)
+		os.Remove(tarPath)
+		return nil, 0, err
+	}
+
+	return &tempFileReader{File: tarFile}, size, nil
+}
+
+// download fetches the file unless the server reports the same caching info
+// as cachedInfo, in which case errNotModified is returned.
+func (d *downloader) download(
+	logger lager.Logger,
+	url *url.URL,
+	checksum cacheddownloader.ChecksumInfoType,
+	cachedInfo cacheddownloader.CachingInfoType,
+	progress ProgressFunc,
+	cancel <-chan struct{},
+) (string, int64, cacheddownloader.CachingInfoType, error) {
+	logger = logger.Session("chunked-download", lager.Data{"host": url.Host})
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	go func() {
+		select {
+		case <-cancel:
+			cancelCtx()
+		case <-ctx.Done():
+		}
+	}()
+
+	size, cachingInfo, err := d.probe(ctx, url)
+	if err != nil {
+		if isCancelled(cancel) {
+			return "", 0, cachingInfo, ErrDownloadCancelled
+		}
+		logger.Info("probe-failed", lager.Data{"error": err.Error()})
+		return "", 0, cachingInfo, err
+	}
+
+	if cachedInfo != (cacheddownloader.CachingInfoType{}) && cachedInfo == cachingInfo {
+		return "", size, cachingInfo, errNotModified
+	}
+
+	validator := cachingInfo.ETag
+	// weak entity tags cannot be used with If-Range
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = cachingInfo.LastModified
+	}
+	logger.Info("starting", lager.Data{"size": size, "chunk-size": d.chunkSize, "parallelism": d.parallelism})
+
+	target, err := d.open(logger, url, validator, size)
+	if err != nil {
+		logger.Error("failed-to-create-temp-file", err)
+		return "", 0, cachingInfo, err
+	}
+
+	succeeded := false
+	keep := true
+	defer func() {
+		if !succeeded {
+			target.abort(keep)
+		}
+	}()
+
+	var progressLock sync.Mutex
+	var downloaded int64
+	report := func(n int64) {
+		progressLock.Lock()
+		defer progressLock.Unlock()
+
+		downloaded += n
+		if progress != nil {
+			progress(downloaded, size)
+		}
+	}
+	if resumed := target.finishedBytes(size, d.chunkSize); resumed > 0 {
+		logger.Info("resuming", lager.Data{"downloaded": resumed})
+		report(resumed)
+	}
+
+	chunks := make(chan chunk)
+	errs := make(chan error, d.parallelism)
+	wg := sync.WaitGroup{}
+	for i := 0; i < d.parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range chunks {
+				err := d.fetchChunk(ctx, logger, url, validator, target.file, c, report)
+				if err == nil {
+					err = target.finish(c)
+				}
+				if err != nil {
+					errs <- err
+					cancelCtx()
+					return
+				}
+			}
+		}()
+	}
+
+FEED_CHUNKS:
+	for start := int64(0); start < size; start += d.chunkSize {
+		if target.finished[start] {
+			continue
+		}
+
+		end := start + d.chunkSize - 1
+		if end >= size {
+			end = size - 1
+		}
+
+		select {
+		case chunks <- chunk{start: start, end: end}:
+		case <-ctx.Done():
+			break FEED_CHUNKS
+		}
+	}
+	close(chunks)
+	wg.Wait()
+	close(errs)
+
+	if isCancelled(cancel) {
+		logger.Info("cancelled")
+		return "", 0, cachingInfo, ErrDownloadCancelled
+	}
+
+	if err := <-errs; err != nil {
+		logger.Error("failed", err)
+		keep = err != ErrContentChanged
+		return "", 0, cachingInfo, err
+	}
+
+	err = verifyChecksum(target.file, checksum)
+	if err != nil {
+		logger.Error("failed-checksum-validation", err)
+		keep = false
+		return "", 0, cachingInfo, err
+	}
+
+	path, err := target.complete()
+	if err != nil {
+		logger.Error("failed-to-close-file", err)
+		return "", 0, cachingInfo, err
+	}
+
+	logger.Info("succeeded")
+	succeeded = true
+	return path, size, cachingInfo, nil
+}
+
+// probe requests the first byte of the file to learn its size and the
+// validators that make sure all chunks are taken from the same content.
+func (d *downloader) probe(ctx context.Context, url *url.URL) (int64, cacheddownloader.CachingInfoType, error) {
+	var cachingInfo cacheddownloader.CachingInfoType
+
+	req, err := http.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return 0, cachingInfo, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, cachingInfo, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		return 0, cachingInfo, ErrRangesNotSupported
+	default:
+		return 0, cachingInfo, fmt.Errorf("Download failed: Status code %d", resp.StatusCode)
+	}
+
+	contentRange := resp.Header.Get("Content-Range")
+	i := strings.LastIndex(contentRange, "/")
+	if i < 0 {
+		return 0, cachingInfo, ErrRangesNotSupported
+	}
+
+	size, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	if err != nil || size <= 0 {
+		return 0, cachingInfo, ErrRangesNotSupported
+	}
+
+	cachingInfo.ETag = resp.Header.Get("ETag")
+	cachingInfo.LastModified = resp.Header.Get("Last-Modified")
+	return size, cachingInfo, nil
+}
+
+func (d *downloader) fetchChunk(
+	ctx context.Context,
+	logger lager.Logger,
+	url *url.URL,
+	validator string,
+	file *os.File,
+	c chunk,
+	report func(int64),
+) error {
+	start := c.start
+
+	var err error
+	for attempt := 0; attempt < ChunkAttempts; attempt++ {
+		var n int64
+		n, err = d.fetchRange(ctx, url, validator, file, start, c.end, report)
+		start += n
+		if err == nil || ctx.Err() != nil || err == ErrContentChanged {
+			return err
+		}
+
+		logger.Info("resuming-chunk", lager.Data{
+			"start":     c.start,
+			"end":       c.end,
+			"resume-at": start,
+			"attempt":   attempt,
+			"error":     err.Error(),
+		})
+	}
+
+	return err
+}
+
+func (d *downloader) fetchRange(
+	ctx context.Context,
+	url *url.URL,
+	validator string,
+	file *os.File,
+	start int64,
+	end int64,
+	report func(int64),
+) (int64, error) {
+	req, err := http.NewRequest("GET", url.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	if validator != "" {
+		req.Header.Set("If-Range", validator)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// the server ignores the range when If-Range no longer matches
+		return 0, ErrContentChanged
+	default:
+		return 0, fmt.Errorf("Download failed: Status code %d", resp.StatusCode)
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", start)) {
+		return 0, fmt.Errorf("unexpected content range: %q", resp.Header.Get("Content-Range"))
+	}
+
+	var written int64
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			if start+written+int64(n) > end+1 {
+				return written, errors.New("received more data than requested")
+			}
+
+			_, err := file.WriteAt(buf[:n], start+written)
+			if err != nil {
+				return written, err
+			}
+
+			written += int64(n)
+			report(int64(n))
+		}
+
+		if readErr == io.EOF {
+			if start+written <= end {
+				return written, io.ErrUnexpectedEOF
+			}
+			return written, nil
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+}
+
+func verifyChecksum(file *os.File, checksum cacheddownloader.ChecksumInfoType) error {
+	if checksum.Algorithm == "" && checksum.Value == "" {
+		return nil
+	}
+
+	var h hash.Hash
+	switch strings.ToLower(checksum.Algorithm) {
+	case "md5":
+		h = md5.New()
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	default:
+		return fmt.Errorf("unsupported checksum algorithm: %q", checksum.Algorithm)
+	}
+
+	_, err := file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return err
+	}
+
+	actual := hex.EncodeToString(h.Sum(nil))
+	if actual != strings.ToLower(checksum.Value) {
+		return &ChecksumMismatchError{Algorithm: checksum.Algorithm, Expected: checksum.Value, Actual: actual}
+	}
+
+	return nil
+}
+
+func isCancelled(cancel <-chan struct{}) bool {
+	select {
+	case <-cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// partialDownload is the file a download is written to. Content with a
+// validator is written to a file named after its URL and validator, and the
+// chunks that are done are journaled next to it. When the download fails the
+// file is kept, so that a retry only fetches the missing chunks.
+type partialDownload struct {
+	file     *os.File
+	journal  *os.File
+	finished map[int64]bool
+	release  func()
+
+	journalLock sync.Mutex
+}
+
+func (d *downloader) open(logger lager.Logger, url *url.URL, validator string, size int64) (*partialDownload, error) {
+	if validator != "" {
+		sum := sha256.Sum256([]byte(url.String() + "\n" + validator))
+		path := filepath.Join(d.tempDir, "chunked-download-"+hex.EncodeToString(sum[:]))
+
+		// a download of the same content that is still running keeps its file
+		if release, ok := d.claim(path); ok {
+			target, err := resumePartialDownload(path, size)
+			if err == nil {
+				target.release = release
+				return target, nil
+			}
+			release()
+			logger.Error("failed-to-open-partial-download", err)
+		}
+	}
+
+	file, err := ioutil.TempFile(d.tempDir, "chunked-download-")
+	if err != nil {
+		return nil, err
+	}
+
+	err = file.Truncate(size)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return &partialDownload{file: file, finished: map[int64]bool{}, release: func() {}}, nil
+}
+
+func (d *downloader) claim(path string) (func(), bool) {
+	d.partialsLock.Lock()
+	defer d.partialsLock.Unlock()
+
+	if d.partials[path] {
+		return nil, false
+	}
+	d.partials[path] = true
+
+	return func() {
+		d.partialsLock.Lock()
+		defer d.partialsLock.Unlock()
+		delete(d.partials, path)
+	}, true
+}
+
+func resumePartialDownload(path string, size int64) (*partialDownload, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	journal, err := os.OpenFile(path+".chunks", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	target := &partialDownload{file: file, journal: journal, finished: map[int64]bool{}, release: func() {}}
+
+	info, err := file.Stat()
+	if err == nil && info.Size() == size {
+		scanner := bufio.NewScanner(journal)
+		for scanner.Scan() {
+			start, err := strconv.ParseInt(scanner.Text(), 10, 64)
+			if err == nil {
+				target.finished[start] = true
+			}
+		}
+		return target, nil
+	}
+
+	err = file.Truncate(size)
+	if err == nil {
+		err = journal.Truncate(0)
+	}
+	if err != nil {
+		target.abort(false)
+		return nil, err
+	}
+
+	return target, nil
+}
+
+func (p *partialDownload) finishedBytes(size, chunkSize int64) int64 {
+	var n int64
+	for start := range p.finished {
+		end := start + chunkSize
+		if end > size {
+			end = size
+		}
+		n += end - start
+	}
+	return n
+}
+
+func (p *partialDownload) finish(c chunk) error {
+	if p.journal == nil {
+		return nil
+	}
+
+	p.journalLock.Lock()
+	defer p.journalLock.Unlock()
+
+	_, err := fmt.Fprintf(p.journal, "%d\n", c.start)
+	return err
+}
+
+// complete closes the finished download and returns a path that no later
+// download of the same content will write to.
+func (p *partialDownload) complete() (string, error) {
+	defer p.release()
+
+	path := p.file.Name()
+	err := p.file.Close()
+	if err != nil {
+		p.abort(false)
+		return "", err
+	}
+
+	if p.journal == nil {
+		return path, nil
+	}
+
+	p.journal.Close()
+	os.Remove(p.journal.Name())
+
+	completed, err := ioutil.TempFile(filepath.Dir(path), "chunked-download-")
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	completed.Close()
+
+	err = os.Rename(path, completed.Name())
+	if err != nil {
+		os.Remove(path)
+		os.Remove(completed.Name())
+		return "", err
+	}
+
+	return completed.Name(), nil
+}
+
+// abort closes the download, keeping the chunks done so far for a retry when
+// the download can be resumed.
+func (p *partialDownload) abort(keep bool) {
+	defer p.release()
+
+	p.file.Close()
+	if p.journal != nil {
+		p.journal.Close()
+	}
+
+	if keep && p.journal != nil {
+		return
+	}
+
+	os.Remove(p.file.Name())
+	if p.journal != nil {
+		os.Remove(p.journal.Name())
+	}
+}
+
+// tempFileReader removes the file once it is closed
+type tempFileReader struct {
+	*os.File
+}
+
+func (r *tempFileReader) Close() error {
+	err := r.File.Close()
+	os.Remove(r.File.Name())
+	return err
+}
@@ -0,0 +1,13 @@
+package chunkeddownloader_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestChunkedDownloader(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ChunkedDownloader Suite")
+}
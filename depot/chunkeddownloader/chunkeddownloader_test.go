@@ -0,0 +1,343 @@
+package chunkeddownloader_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/cacheddownloader"
+	"code.cloudfoundry.org/executor/depot/chunkeddownloader"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ChunkedDownloader", func() {
+	var (
+		logger     *lagertest.TestLogger
+		tempDir    string
+		content    []byte
+		etag       string
+		server     *httptest.Server
+		handler    http.HandlerFunc
+		serverURL  *url.URL
+		checksum   cacheddownloader.ChecksumInfoType
+		cancel     chan struct{}
+		downloader chunkeddownloader.ChunkedDownloader
+
+		progressLock sync.Mutex
+		progress     [][2]int64
+
+		requestLock sync.Mutex
+		ranges      []string
+	)
+
+	serveContent := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "droplet", time.Time{}, bytes.NewReader(content))
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+
+		var err error
+		tempDir, err = ioutil.TempDir("", "chunked-download")
+		Expect(err).NotTo(HaveOccurred())
+
+		content = []byte(strings.Repeat("0123456789abcdef", 64))
+		etag = `"v1"`
+		checksum = cacheddownloader.ChecksumInfoType{}
+		cancel = make(chan struct{})
+		progress = nil
+		ranges = nil
+		handler = serveContent
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestLock.Lock()
+			defer requestLock.Unlock()
+
+			ranges = append(ranges, r.Header.Get("Range"))
+			handler(w, r)
+		}))
+
+		serverURL, err = url.Parse(server.URL + "/droplet")
+		Expect(err).NotTo(HaveOccurred())
+
+		downloader = chunkeddownloader.New(tempDir, 100, 3, time.Minute, nil)
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(tempDir)
+	})
+
+	download := func() (string, int64, error) {
+		return downloader.Download(logger, serverURL, checksum, func(downloaded, total int64) {
+			progressLock.Lock()
+			defer progressLock.Unlock()
+			progress = append(progress, [2]int64{downloaded, total})
+		}, cancel)
+	}
+
+	It("downloads the file in chunks", func() {
+		path, size, err := download()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size).To(BeEquivalentTo(len(content)))
+
+		downloaded, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(downloaded).To(Equal(content))
+
+		Expect(ranges).To(ContainElement("bytes=0-0"))
+		Expect(ranges).To(ContainElement("bytes=0-99"))
+		Expect(ranges).To(ContainElement("bytes=1000-1023"))
+	})
+
+	It("reports the progress of the download", func() {
+		_, _, err := download()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(progress).NotTo(BeEmpty())
+		for i := 1; i < len(progress); i++ {
+			Expect(progress[i][0]).To(BeNumerically(">", progress[i-1][0]))
+		}
+		Expect(progress[len(progress)-1]).To(Equal([2]int64{int64(len(content)), int64(len(content))}))
+	})
+
+	Context("when a checksum is given", func() {
+		BeforeEach(func() {
+			sum := sha256.Sum256(content)
+			checksum = cacheddownloader.ChecksumInfoType{Algorithm: "sha256", Value: hex.EncodeToString(sum[:])}
+		})
+
+		It("validates the checksum", func() {
+			_, _, err := download()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		Context("when the checksum does not match", func() {
+			BeforeEach(func() {
+				checksum.Value = "deadbeef"
+			})
+
+			It("fails and removes the file", func() {
+				_, _, err := download()
+				Expect(err).To(BeAssignableToTypeOf(&chunkeddownloader.ChecksumMismatchError{}))
+
+				files, err := ioutil.ReadDir(tempDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(files).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("when the server does not support range requests", func() {
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.Write(content)
+			}
+		})
+
+		It("returns ErrRangesNotSupported", func() {
+			_, _, err := download()
+			Expect(err).To(Equal(chunkeddownloader.ErrRangesNotSupported))
+		})
+	})
+
+	Context("when a chunk fails part way", func() {
+		BeforeEach(func() {
+			failed := false
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") == "bytes=100-199" && !failed {
+					failed = true
+					w.Header().Set("Content-Range", fmt.Sprintf("bytes 100-199/%d", len(content)))
+					w.Header().Set("Content-Length", "100")
+					w.WriteHeader(http.StatusPartialContent)
+					w.Write(content[100:150])
+					w.(http.Flusher).Flush()
+					panic(http.ErrAbortHandler)
+				}
+				serveContent(w, r)
+			}
+		})
+
+		It("resumes the chunk where it stopped", func() {
+			path, _, err := download()
+			Expect(err).NotTo(HaveOccurred())
+
+			downloaded, err := ioutil.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(downloaded).To(Equal(content))
+			Expect(ranges).To(ContainElement("bytes=150-199"))
+		})
+	})
+
+	Context("when a failed download is retried", func() {
+		BeforeEach(func() {
+			downloader = chunkeddownloader.New(tempDir, 100, 1, time.Minute, nil)
+
+			failing := true
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Range") == "bytes=200-299" && failing {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				serveContent(w, r)
+			}
+
+			_, _, err := download()
+			Expect(err).To(HaveOccurred())
+
+			failing = false
+			ranges = nil
+			progress = nil
+		})
+
+		It("only fetches the chunks that are missing", func() {
+			path, _, err := download()
+			Expect(err).NotTo(HaveOccurred())
+
+			downloaded, err := ioutil.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(downloaded).To(Equal(content))
+
+			Expect(ranges).NotTo(ContainElement("bytes=0-99"))
+			Expect(ranges).NotTo(ContainElement("bytes=100-199"))
+			Expect(ranges).To(ContainElement("bytes=200-299"))
+			Expect(progress[0]).To(Equal([2]int64{200, int64(len(content))}))
+		})
+
+		Context("when the content has changed since", func() {
+			BeforeEach(func() {
+				etag = `"v2"`
+			})
+
+			It("downloads all of the chunks again", func() {
+				_, _, err := download()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ranges).To(ContainElement("bytes=0-99"))
+			})
+		})
+	})
+
+	Describe("Fetch", func() {
+		var (
+			cacheDir string
+			cacheKey string
+		)
+
+		BeforeEach(func() {
+			var err error
+			cacheDir, err = ioutil.TempDir("", "chunked-download-cache")
+			Expect(err).NotTo(HaveOccurred())
+
+			cacheKey = "the-cache-key"
+			downloader = chunkeddownloader.NewWithCache(tempDir, 100, 3, time.Minute, nil, cacheddownloader.NewCache(cacheDir, 1024*1024))
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(cacheDir)
+		})
+
+		fetch := func() []byte {
+			stream, size, err := downloader.Fetch(logger, serverURL, cacheKey, checksum, nil, cancel)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(BeEquivalentTo(len(content)))
+			defer stream.Close()
+
+			tarReader := tar.NewReader(stream)
+			_, err = tarReader.Next()
+			Expect(err).NotTo(HaveOccurred())
+
+			fetched, err := ioutil.ReadAll(tarReader)
+			Expect(err).NotTo(HaveOccurred())
+			return fetched
+		}
+
+		It("returns the content as a tar stream", func() {
+			Expect(fetch()).To(Equal(content))
+		})
+
+		It("serves the content from the cache while it has not changed", func() {
+			fetch()
+			ranges = nil
+
+			Expect(fetch()).To(Equal(content))
+			Expect(ranges).To(Equal([]string{"bytes=0-0"}))
+		})
+
+		Context("when the content changes", func() {
+			It("downloads it again", func() {
+				fetch()
+				ranges = nil
+				etag = `"v2"`
+
+				fetch()
+				Expect(ranges).To(ContainElement("bytes=0-99"))
+			})
+		})
+
+		Context("without a cache key", func() {
+			BeforeEach(func() {
+				cacheKey = ""
+			})
+
+			It("downloads the content every time", func() {
+				fetch()
+				ranges = nil
+
+				fetch()
+				Expect(ranges).To(ContainElement("bytes=0-99"))
+			})
+		})
+	})
+
+	Context("when the content changes during the download", func() {
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				serveContent(w, r)
+				etag = `"v2"`
+			}
+		})
+
+		It("returns ErrContentChanged", func() {
+			_, _, err := download()
+			Expect(err).To(Equal(chunkeddownloader.ErrContentChanged))
+		})
+	})
+
+	Context("when the server fails", func() {
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		})
+
+		It("returns an error", func() {
+			_, _, err := download()
+			Expect(err).To(MatchError("Download failed: Status code 500"))
+		})
+	})
+
+	Context("when the download is cancelled", func() {
+		BeforeEach(func() {
+			close(cancel)
+		})
+
+		It("returns ErrDownloadCancelled", func() {
+			_, _, err := download()
+			Expect(err).To(Equal(chunkeddownloader.ErrDownloadCancelled))
+		})
+	})
+})
@@ -0,0 +1 @@
+package fake_chunkeddownloader // import "code.cloudfoundry.org/executor/depot/chunkeddownloader/fake_chunkeddownloader"
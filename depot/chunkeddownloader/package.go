@@ -0,0 +1 @@
+package chunkeddownloader // import "code.cloudfoundry.org/executor/depot/chunkeddownloader"
@@ -15,6 +15,7 @@ import (
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/volman"
 	"github.com/tedsuo/ifrit"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -40,7 +41,9 @@ type ContainerStore interface {
 	List(logger lager.Logger) []executor.Container
 	Metrics(logger lager.Logger) (map[string]executor.ContainerMetrics, error)
 	RemainingResources(logger lager.Logger) executor.ExecutorResources
+	TenantResources(logger lager.Logger) map[string]executor.TenantResources
 	GetFiles(logger lager.Logger, guid, sourcePath string) (io.ReadCloser, error)
+	GetStepStatus(logger lager.Logger, guid string) (executor.StepStatus, error)
 
 	// Cleanup
 	NewRegistryPruner(logger lager.Logger) ifrit.Runner
@@ -56,10 +59,19 @@ type ContainerConfig struct {
 	MaxCPUShares uint64
 	SetCPUWeight bool
 
-	ReservedExpirationTime             time.Duration
-	ReapInterval                       time.Duration
-	MaxLogLinesPerSecond               int
-	MetricReportInterval time.Duration
+	ReservedExpirationTime time.Duration
+	ReapInterval           time.Duration
+	MaxLogLinesPerSecond   int
+	MetricReportInterval   time.Duration
+
+	// containers are assigned to a tenant by the value of their
+	// TenantQuotaTag tag
+	TenantQuotaTag     string
+	TenantQuotas       map[string]executor.ExecutorResources
+	DefaultTenantQuota executor.ExecutorResources
+
+	// a nil Tracer disables tracing
+	Tracer trace.Tracer
 }
 
 type containerStore struct {
@@ -115,7 +127,7 @@ func New(
 		dependencyManager:             dependencyManager,
 		volumeManager:                 volumeManager,
 		credManager:                   credManager,
-		containers:                    newNodeMap(totalCapacity),
+		containers:                    newNodeMap(totalCapacity, &containerConfig),
 		eventEmitter:                  eventEmitter,
 		transformer:                   transformer,
 		clock:                         clock,
@@ -143,28 +155,30 @@ func (cs *containerStore) Reserve(logger lager.Logger, req *executor.AllocationR
 	defer logger.Debug("complete")
 
 	container := executor.NewReservedContainerFromAllocationRequest(req, cs.clock.Now().UnixNano())
-
-	err := cs.containers.Add(
-		newStoreNode(&cs.containerConfig,
-			cs.useDeclarativeHealthCheck,
-			cs.declarativeHealthcheckPath,
-			container,
-			cs.gardenClient,
-			cs.clock,
-			cs.dependencyManager,
-			cs.volumeManager,
-			cs.credManager,
-			cs.eventEmitter,
-			cs.transformer,
-			cs.trustedSystemCertificatesPath,
-			cs.metronClient,
-			cs.proxyConfigHandler,
-			cs.rootFSSizer,
-			cs.cellID,
-			cs.enableUnproxiedPortMappings,
-			cs.advertisePreferenceForInstanceAddress,
-		))
-
+	container.MemoryMB += req.SidecarResource.MemoryMB
+	container.DiskMB += req.SidecarResource.DiskMB
+
+	node := newStoreNode(&cs.containerConfig,
+		cs.useDeclarativeHealthCheck,
+		cs.declarativeHealthcheckPath,
+		container,
+		cs.gardenClient,
+		cs.clock,
+		cs.dependencyManager,
+		cs.volumeManager,
+		cs.credManager,
+		cs.eventEmitter,
+		cs.transformer,
+		cs.trustedSystemCertificatesPath,
+		cs.metronClient,
+		cs.proxyConfigHandler,
+		cs.rootFSSizer,
+		cs.cellID,
+		cs.enableUnproxiedPortMappings,
+		cs.advertisePreferenceForInstanceAddress,
+	)
+
+	err := cs.containers.Add(node, &req.SidecarResource)
 	if err != nil {
 		logger.Error("failed-to-reserve", err)
 		return executor.Container{}, err
@@ -185,12 +199,15 @@ func (cs *containerStore) Initialize(logger lager.Logger, req *executor.RunReque
 		return err
 	}
 
-	err = node.Initialize(logger, req)
+	tags := executor.Tags{}
+	tags.Add(node.Info().Tags)
+	tags.Add(req.Tags)
+	err = cs.transformer.Validate(logger, req.RunInfo, tags)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return cs.containers.Initialize(logger, req)
 }
 
 func (cs *containerStore) Create(logger lager.Logger, guid string) (executor.Container, error) {
@@ -297,7 +314,9 @@ func (cs *containerStore) Get(logger lager.Logger, guid string) (executor.Contai
 		return executor.Container{}, err
 	}
 
-	return node.Info(), nil
+	container := node.Info()
+	container.Health = node.Health()
+	return container, nil
 }
 
 func (cs *containerStore) List(logger lager.Logger) []executor.Container {
@@ -370,6 +389,19 @@ func (cs *containerStore) RemainingResources(logger lager.Logger) executor.Execu
 	return cs.containers.RemainingResources()
 }
 
+func (cs *containerStore) TenantResources(logger lager.Logger) map[string]executor.TenantResources {
+	return cs.containers.TenantResources()
+}
+
+func (cs *containerStore) GetStepStatus(logger lager.Logger, guid string) (executor.StepStatus, error) {
+	node, err := cs.containers.Get(guid)
+	if err != nil {
+		return executor.StepStatus{}, err
+	}
+
+	return node.StepStatus(), nil
+}
+
 func (cs *containerStore) GetFiles(logger lager.Logger, guid, sourcePath string) (io.ReadCloser, error) {
 	logger = logger.Session("containerstore-getfiles")
 
@@ -20,6 +20,7 @@ import (
 	eventfakes "code.cloudfoundry.org/executor/depot/event/fakes"
 	"code.cloudfoundry.org/executor/depot/log_streamer"
 	"code.cloudfoundry.org/executor/depot/steps"
+	"code.cloudfoundry.org/executor/depot/tracing"
 	"code.cloudfoundry.org/executor/depot/transformer"
 	"code.cloudfoundry.org/executor/depot/transformer/faketransformer"
 	"code.cloudfoundry.org/executor/initializer/configuration/configurationfakes"
@@ -39,6 +40,8 @@ import (
 	"github.com/tedsuo/ifrit/fake_runner"
 	"github.com/tedsuo/ifrit/ginkgomon"
 	"github.com/tedsuo/ifrit/grouper"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 var _ = Describe("Container Store", func() {
@@ -229,6 +232,34 @@ var _ = Describe("Container Store", func() {
 			Expect(remainingCapacity.Containers).To(Equal(totalCapacity.Containers - 1))
 		})
 
+		Context("when resources are reserved for sidecars", func() {
+			BeforeEach(func() {
+				req.SidecarResource = executor.Resource{MemoryMB: 64, DiskMB: 128}
+			})
+
+			It("adds them to the container allocation", func() {
+				container, err := containerStore.Reserve(logger, req)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(container.MemoryMB).To(Equal(req.MemoryMB + 64))
+				Expect(container.DiskMB).To(Equal(req.DiskMB + 128))
+
+				remainingCapacity := containerStore.RemainingResources(logger)
+				Expect(remainingCapacity.MemoryMB).To(Equal(totalCapacity.MemoryMB - req.MemoryMB - 64))
+				Expect(remainingCapacity.DiskMB).To(Equal(totalCapacity.DiskMB - req.DiskMB - 128))
+			})
+
+			Context("when the cell does not have room for the sidecars", func() {
+				BeforeEach(func() {
+					req.SidecarResource.MemoryMB = totalCapacity.MemoryMB - req.MemoryMB + 1
+				})
+
+				It("fails with insufficient resources", func() {
+					_, err := containerStore.Reserve(logger, req)
+					Expect(err).To(Equal(executor.ErrInsufficientResourcesAvailable))
+				})
+			})
+		})
+
 		Context("when the container guid is already reserved", func() {
 			BeforeEach(func() {
 				_, err := containerStore.Reserve(logger, req)
@@ -251,6 +282,157 @@ var _ = Describe("Container Store", func() {
 				Expect(err).To(Equal(executor.ErrInsufficientResourcesAvailable))
 			})
 		})
+
+		Context("when tenant quotas are configured", func() {
+			BeforeEach(func() {
+				containerConfig.TenantQuotaTag = "tenant"
+				containerConfig.TenantQuotas = map[string]executor.ExecutorResources{
+					"tenant-a": executor.NewExecutorResources(2048, 0, 0),
+				}
+				containerConfig.DefaultTenantQuota = executor.NewExecutorResources(0, 0, 1)
+
+				containerStore = containerstore.New(
+					containerConfig,
+					&totalCapacity,
+					gardenClient,
+					dependencyManager,
+					volumeManager,
+					credManager,
+					clock,
+					eventEmitter,
+					megatron,
+					"/var/vcap/data/cf-system-trusted-certs",
+					fakeMetronClient,
+					fakeRootFSSizer,
+					false,
+					"/var/vcap/packages/healthcheck",
+					proxyManager,
+					cellID,
+					true,
+					advertisePreferenceForInstanceAddress,
+				)
+
+				req.Tags = executor.Tags{"tenant": "tenant-a"}
+			})
+
+			It("reports the usage of the tenant", func() {
+				_, err := containerStore.Reserve(logger, req)
+				Expect(err).NotTo(HaveOccurred())
+
+				tenants := containerStore.TenantResources(logger)
+				Expect(tenants).To(HaveKeyWithValue("tenant-a", executor.TenantResources{
+					Quota: executor.NewExecutorResources(2048, 0, 0),
+					Used:  executor.NewExecutorResources(1024, 1024, 1),
+				}))
+			})
+
+			Context("when the reservation would exceed the tenant quota", func() {
+				BeforeEach(func() {
+					_, err := containerStore.Reserve(logger, req)
+					Expect(err).NotTo(HaveOccurred())
+
+					req = &executor.AllocationRequest{
+						Guid:     "other-container-guid",
+						Tags:     req.Tags,
+						Resource: executor.Resource{MemoryMB: 1025, DiskMB: 1024},
+					}
+				})
+
+				It("fails with tenant quota exceeded", func() {
+					_, err := containerStore.Reserve(logger, req)
+					Expect(err).To(Equal(executor.ErrTenantQuotaExceeded))
+				})
+
+				It("does not decrement the remaining capacity", func() {
+					_, err := containerStore.Reserve(logger, req)
+					Expect(err).To(HaveOccurred())
+
+					remainingCapacity := containerStore.RemainingResources(logger)
+					Expect(remainingCapacity.Containers).To(Equal(totalCapacity.Containers - 1))
+				})
+			})
+
+			Context("when the sidecars would exceed the tenant quota", func() {
+				BeforeEach(func() {
+					req.SidecarResource = executor.Resource{MemoryMB: 1025}
+				})
+
+				It("fails the reservation", func() {
+					_, err := containerStore.Reserve(logger, req)
+					Expect(err).To(Equal(executor.ErrTenantQuotaExceeded))
+				})
+			})
+
+			Context("when the sidecars of the run request exceed the tenant quota", func() {
+				BeforeEach(func() {
+					_, err := containerStore.Reserve(logger, req)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("fails to initialize and leaves the tenant usage untouched", func() {
+					runReq := &executor.RunRequest{
+						Guid: req.Guid,
+						RunInfo: executor.RunInfo{
+							Sidecars: []executor.Sidecar{{Action: &models.Action{}, MemoryMB: 1025}},
+						},
+						Tags: req.Tags,
+					}
+
+					err := containerStore.Initialize(logger, runReq)
+					Expect(err).To(Equal(executor.ErrTenantQuotaExceeded))
+
+					tenants := containerStore.TenantResources(logger)
+					Expect(tenants["tenant-a"].Used).To(Equal(executor.NewExecutorResources(1024, 1024, 1)))
+				})
+			})
+
+			Context("when the tenant has no explicit quota", func() {
+				BeforeEach(func() {
+					req.Tags = executor.Tags{"tenant": "tenant-b"}
+					_, err := containerStore.Reserve(logger, req)
+					Expect(err).NotTo(HaveOccurred())
+					req.Guid = "other-container-guid"
+				})
+
+				It("applies the default quota", func() {
+					_, err := containerStore.Reserve(logger, req)
+					Expect(err).To(Equal(executor.ErrTenantQuotaExceeded))
+				})
+
+				It("frees the tenant usage when a container is destroyed", func() {
+					err := containerStore.Destroy(logger, containerGuid)
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = containerStore.Reserve(logger, req)
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("keeps reporting the tenant once it has no containers left", func() {
+					err := containerStore.Destroy(logger, containerGuid)
+					Expect(err).NotTo(HaveOccurred())
+
+					tenants := containerStore.TenantResources(logger)
+					Expect(tenants).To(HaveKeyWithValue("tenant-b", executor.TenantResources{
+						Quota: executor.NewExecutorResources(0, 0, 1),
+						Used:  executor.NewExecutorResources(0, 0, 0),
+					}))
+				})
+			})
+
+			Context("when the container does not have the tenant tag", func() {
+				BeforeEach(func() {
+					req.Tags = executor.Tags{}
+				})
+
+				It("is not subject to any tenant quota", func() {
+					_, err := containerStore.Reserve(logger, req)
+					Expect(err).NotTo(HaveOccurred())
+
+					tenants := containerStore.TenantResources(logger)
+					Expect(tenants).NotTo(HaveKey(""))
+				})
+			})
+		})
 	})
 
 	Describe("Initialize", func() {
@@ -306,6 +488,143 @@ var _ = Describe("Container Store", func() {
 				Expect(container.RunInfo).To(Equal(runInfo))
 				Expect(container.Tags).To(Equal(runTags))
 			})
+
+			It("validates the steps of the run request", func() {
+				err := containerStore.Initialize(logger, req)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(megatron.ValidateCallCount()).To(Equal(1))
+				_, validatedRunInfo, validatedTags := megatron.ValidateArgsForCall(0)
+				Expect(validatedRunInfo).To(Equal(runInfo))
+				Expect(validatedTags).To(Equal(runTags))
+			})
+
+			It("validates the steps against the tags of the reservation and the run request", func() {
+				allocationReq := &executor.AllocationRequest{
+					Guid: "lrp-guid",
+					Tags: executor.Tags{executor.LifecycleTag: executor.LRPLifecycle},
+				}
+				_, err := containerStore.Reserve(logger, allocationReq)
+				Expect(err).NotTo(HaveOccurred())
+
+				req.Guid = "lrp-guid"
+				err = containerStore.Initialize(logger, req)
+				Expect(err).NotTo(HaveOccurred())
+
+				_, _, validatedTags := megatron.ValidateArgsForCall(0)
+				Expect(validatedTags).To(Equal(executor.Tags{
+					executor.LifecycleTag: executor.LRPLifecycle,
+					"Beep":                "Boop",
+				}))
+			})
+
+			Context("when the steps are invalid", func() {
+				var validationErr error
+
+				BeforeEach(func() {
+					validationErr = executor.NewStepsInvalidError([]string{"action: is required"})
+					megatron.ValidateReturns(validationErr)
+				})
+
+				It("returns the validation error", func() {
+					err := containerStore.Initialize(logger, req)
+					Expect(err).To(Equal(validationErr))
+				})
+
+				It("leaves the container reserved", func() {
+					containerStore.Initialize(logger, req)
+
+					container, err := containerStore.Get(logger, req.Guid)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(container.State).To(Equal(executor.StateReserved))
+				})
+			})
+
+			Context("when the run request has sidecars with resources", func() {
+				BeforeEach(func() {
+					req.RunInfo.Sidecars = []executor.Sidecar{
+						{Action: &models.Action{}, MemoryMB: 64, DiskMB: 128},
+						{Action: &models.Action{}, MemoryMB: 32},
+					}
+				})
+
+				It("adds the sidecar resources to the container allocation", func() {
+					err := containerStore.Initialize(logger, req)
+					Expect(err).NotTo(HaveOccurred())
+
+					container, err := containerStore.Get(logger, req.Guid)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(container.MemoryMB).To(Equal(96))
+					Expect(container.DiskMB).To(Equal(128))
+
+					remainingCapacity := containerStore.RemainingResources(logger)
+					Expect(remainingCapacity.MemoryMB).To(Equal(totalCapacity.MemoryMB - 96))
+					Expect(remainingCapacity.DiskMB).To(Equal(totalCapacity.DiskMB - 128))
+					Expect(remainingCapacity.Containers).To(Equal(totalCapacity.Containers - 1))
+				})
+
+				It("gives the sidecar resources back when the container is destroyed", func() {
+					err := containerStore.Initialize(logger, req)
+					Expect(err).NotTo(HaveOccurred())
+
+					err = containerStore.Destroy(logger, containerGuid)
+					Expect(err).NotTo(HaveOccurred())
+
+					remainingCapacity := containerStore.RemainingResources(logger)
+					Expect(remainingCapacity.MemoryMB).To(Equal(totalCapacity.MemoryMB))
+					Expect(remainingCapacity.DiskMB).To(Equal(totalCapacity.DiskMB))
+				})
+
+				Context("when the sidecar resources were reserved with the container", func() {
+					BeforeEach(func() {
+						err := containerStore.Destroy(logger, containerGuid)
+						Expect(err).NotTo(HaveOccurred())
+
+						_, err = containerStore.Reserve(logger, &executor.AllocationRequest{
+							Guid:            containerGuid,
+							Tags:            executor.Tags{},
+							SidecarResource: executor.Resource{MemoryMB: 96, DiskMB: 128},
+						})
+						Expect(err).NotTo(HaveOccurred())
+					})
+
+					It("does not reserve them again", func() {
+						err := containerStore.Initialize(logger, req)
+						Expect(err).NotTo(HaveOccurred())
+
+						container, err := containerStore.Get(logger, req.Guid)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(container.MemoryMB).To(Equal(96))
+
+						remainingCapacity := containerStore.RemainingResources(logger)
+						Expect(remainingCapacity.MemoryMB).To(Equal(totalCapacity.MemoryMB - 96))
+						Expect(remainingCapacity.DiskMB).To(Equal(totalCapacity.DiskMB - 128))
+					})
+				})
+
+				Context("when the cell does not have room for the sidecars", func() {
+					BeforeEach(func() {
+						req.RunInfo.Sidecars[0].MemoryMB = int32(totalCapacity.MemoryMB + 1)
+					})
+
+					It("fails with insufficient resources", func() {
+						err := containerStore.Initialize(logger, req)
+						Expect(err).To(Equal(executor.ErrInsufficientResourcesAvailable))
+					})
+
+					It("leaves the container reserved and the capacity untouched", func() {
+						containerStore.Initialize(logger, req)
+
+						container, err := containerStore.Get(logger, req.Guid)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(container.State).To(Equal(executor.StateReserved))
+						Expect(container.MemoryMB).To(Equal(0))
+
+						remainingCapacity := containerStore.RemainingResources(logger)
+						Expect(remainingCapacity.MemoryMB).To(Equal(totalCapacity.MemoryMB))
+					})
+				})
+			})
 		})
 
 		Context("when the container exists but is not reserved", func() {
@@ -928,6 +1247,22 @@ var _ = Describe("Container Store", func() {
 				})
 			})
 
+			Context("when verifying the signature of bind mounts fails", func() {
+				BeforeEach(func() {
+					dependencyManager.DownloadCachedDependenciesReturns(containerstore.BindMounts{}, &containerstore.SignatureVerificationError{Name: "buildpack", Err: errors.New("no")})
+				})
+
+				It("completes with a distinct failure reason", func() {
+					_, err := containerStore.Create(logger, containerGuid)
+					Expect(err).To(HaveOccurred())
+
+					container, err := containerStore.Get(logger, containerGuid)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(container.RunResult.Failed).To(BeTrue())
+					Expect(container.RunResult.FailureReason).To(Equal(containerstore.VerifyCachedDependenciesFailed))
+				})
+			})
+
 			Context("when egress rules are requested", func() {
 				BeforeEach(func() {
 					egressRules := []*models.SecurityGroupRule{
@@ -1349,6 +1684,134 @@ var _ = Describe("Container Store", func() {
 					Expect(cfg.ProxyTLSPorts).To(ConsistOf(uint16(61001), uint16(61002), uint16(61443)))
 				})
 
+				It("tracks the health of the container through the config", func() {
+					_, err := containerStore.Create(logger, containerGuid)
+					Expect(err).NotTo(HaveOccurred())
+					megatron.StepsRunnerStub = func(logger lager.Logger, c executor.Container, gc garden.Container, logStreamer log_streamer.LogStreamer, cfg transformer.Config) (ifrit.Runner, error) {
+						passing := ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+							return nil
+						})
+						failing := ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+							return errors.New("unhealthy")
+						})
+						return steps.NewHealthCheckStep(passing, failing, logger, clock, logStreamer, logStreamer, time.Second, cfg.HealthTracker), nil
+					}
+					Expect(containerStore.Run(logger, containerGuid)).NotTo(HaveOccurred())
+
+					Eventually(func() *executor.ContainerHealth {
+						container, err := containerStore.Get(logger, containerGuid)
+						Expect(err).NotTo(HaveOccurred())
+						return container.Health
+					}).Should(And(
+						Not(BeNil()),
+						WithTransform(func(h *executor.ContainerHealth) executor.CheckStatus { return h.Liveness }, Equal(executor.CheckStatusFailing)),
+					))
+				})
+
+				It("emits the progress of the steps as events", func() {
+					_, err := containerStore.Create(logger, containerGuid)
+					Expect(err).NotTo(HaveOccurred())
+					megatron.StepsRunnerReturns(ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+						return nil
+					}), nil)
+					Expect(containerStore.Run(logger, containerGuid)).NotTo(HaveOccurred())
+					Eventually(megatron.StepsRunnerCallCount).Should(Equal(1))
+					_, _, _, _, cfg := megatron.StepsRunnerArgsForCall(0)
+
+					cfg.OnProgress("download droplet", executor.StepPhaseFailed, time.Second, errors.New("boom"))
+
+					progressEvents := func() []executor.Event {
+						events := []executor.Event{}
+						for i := 0; i < eventEmitter.EmitCallCount(); i++ {
+							if event, ok := eventEmitter.EmitArgsForCall(i).(executor.StepProgressEvent); ok {
+								events = append(events, event)
+							}
+						}
+						return events
+					}
+					Eventually(progressEvents).Should(ConsistOf(executor.StepProgressEvent{
+						Guid:     containerGuid,
+						Step:     "download droplet",
+						Phase:    executor.StepPhaseFailed,
+						Duration: time.Second,
+						Error:    "boom",
+					}))
+				})
+
+				It("emits an event and a counter when a sidecar restarts", func() {
+					_, err := containerStore.Create(logger, containerGuid)
+					Expect(err).NotTo(HaveOccurred())
+					megatron.StepsRunnerReturns(ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+						return nil
+					}), nil)
+					Expect(containerStore.Run(logger, containerGuid)).NotTo(HaveOccurred())
+					Eventually(megatron.StepsRunnerCallCount).Should(Equal(1))
+					_, _, _, _, cfg := megatron.StepsRunnerArgsForCall(0)
+
+					cfg.OnSidecarRestart(1, 2, errors.New("sidecar crashed"))
+
+					restartEvents := func() []executor.SidecarRestartedEvent {
+						events := []executor.SidecarRestartedEvent{}
+						for i := 0; i < eventEmitter.EmitCallCount(); i++ {
+							if event, ok := eventEmitter.EmitArgsForCall(i).(executor.SidecarRestartedEvent); ok {
+								events = append(events, event)
+							}
+						}
+						return events
+					}
+					Eventually(restartEvents).Should(HaveLen(1))
+					event := restartEvents()[0]
+					Expect(event.Container().Guid).To(Equal(containerGuid))
+					Expect(event.Sidecar).To(Equal(1))
+					Expect(event.RestartCount).To(Equal(2))
+					Expect(event.Reason).To(Equal("sidecar crashed"))
+
+					counters := []string{}
+					for i := 0; i < fakeMetronClient.IncrementCounterCallCount(); i++ {
+						counters = append(counters, fakeMetronClient.IncrementCounterArgsForCall(i))
+					}
+					Expect(counters).To(ContainElement(containerstore.SidecarRestartedCount))
+				})
+
+				It("records the restart count and emits an event and a counter when the action restarts", func() {
+					_, err := containerStore.Create(logger, containerGuid)
+					Expect(err).NotTo(HaveOccurred())
+					megatron.StepsRunnerReturns(ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+						<-signals
+						return nil
+					}), nil)
+					Expect(containerStore.Run(logger, containerGuid)).NotTo(HaveOccurred())
+					Eventually(megatron.StepsRunnerCallCount).Should(Equal(1))
+					_, _, _, _, cfg := megatron.StepsRunnerArgsForCall(0)
+
+					cfg.OnRestart(3, errors.New("action crashed"))
+
+					container, err := containerStore.Get(logger, containerGuid)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(container.RestartCount).To(Equal(3))
+
+					restartEvents := func() []executor.ContainerRestartedEvent {
+						events := []executor.ContainerRestartedEvent{}
+						for i := 0; i < eventEmitter.EmitCallCount(); i++ {
+							if event, ok := eventEmitter.EmitArgsForCall(i).(executor.ContainerRestartedEvent); ok {
+								events = append(events, event)
+							}
+						}
+						return events
+					}
+					Eventually(restartEvents).Should(HaveLen(1))
+					event := restartEvents()[0]
+					Expect(event.Container().Guid).To(Equal(containerGuid))
+					Expect(event.Container().RestartCount).To(Equal(3))
+					Expect(event.Reason).To(Equal("action crashed"))
+
+					counters := []string{}
+					for i := 0; i < fakeMetronClient.IncrementCounterCallCount(); i++ {
+						counters = append(counters, fakeMetronClient.IncrementCounterArgsForCall(i))
+					}
+					Expect(counters).To(ContainElement(containerstore.ContainerRestartedCount))
+				})
+
 				It("bind mounts envoy", func() {
 					_, err := containerStore.Create(logger, containerGuid)
 					Expect(err).NotTo(HaveOccurred())
@@ -1918,6 +2381,50 @@ var _ = Describe("Container Store", func() {
 							Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal(containerstore.ContainerCompletedCount))
 						})
 
+						Context("when the error carries an output excerpt", func() {
+							BeforeEach(func() {
+								var testRunner ifrit.RunFunc = func(signals <-chan os.Signal, ready chan<- struct{}) error {
+									close(ready)
+									return steps.NewEmittableError(nil, "Exited with status 1").WithOutputExcerpt("[stderr] oops")
+								}
+								megatron.StepsRunnerReturns(testRunner, nil)
+							})
+
+							It("includes the excerpt in the run result", func() {
+								err := containerStore.Run(logger, containerGuid)
+								Expect(err).NotTo(HaveOccurred())
+
+								Eventually(containerState(containerGuid)).Should(Equal(executor.StateCompleted))
+
+								container, err := containerStore.Get(logger, containerGuid)
+								Expect(err).NotTo(HaveOccurred())
+								Expect(container.RunResult.FailureReason).To(Equal("Exited with status 1\n[stderr] oops"))
+								Expect(container.RunResult.OutputExcerpt).To(Equal("[stderr] oops"))
+							})
+						})
+
+						Context("when the message of the error does not include the output excerpt", func() {
+							BeforeEach(func() {
+								var testRunner ifrit.RunFunc = func(signals <-chan os.Signal, ready chan<- struct{}) error {
+									close(ready)
+									inner := steps.NewEmittableError(nil, "Exited with status 1").WithOutputExcerpt("[stderr] oops")
+									return steps.NewEmittableError(inner, "Failed to start")
+								}
+								megatron.StepsRunnerReturns(testRunner, nil)
+							})
+
+							It("appends the excerpt to the failure reason", func() {
+								err := containerStore.Run(logger, containerGuid)
+								Expect(err).NotTo(HaveOccurred())
+
+								Eventually(containerState(containerGuid)).Should(Equal(executor.StateCompleted))
+
+								container, err := containerStore.Get(logger, containerGuid)
+								Expect(err).NotTo(HaveOccurred())
+								Expect(container.RunResult.FailureReason).To(Equal("Failed to start\n[stderr] oops"))
+							})
+						})
+
 						Context("when run fails with ErrExceededGracefulShutdownInterval", func() {
 							BeforeEach(func() {
 								var testRunner ifrit.RunFunc = func(signals <-chan os.Signal, ready chan<- struct{}) error {
@@ -2329,6 +2836,60 @@ var _ = Describe("Container Store", func() {
 			Expect(credManager.RemoveCredDirCallCount()).To(Equal(1))
 		})
 
+		Context("when tracing is enabled", func() {
+			const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+			var exporter *tracetest.InMemoryExporter
+
+			BeforeEach(func() {
+				exporter = tracetest.NewInMemoryExporter()
+				containerConfig.Tracer = sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)).Tracer("test")
+
+				containerStore = containerstore.New(
+					containerConfig,
+					&totalCapacity,
+					gardenClient,
+					dependencyManager,
+					volumeManager,
+					credManager,
+					clock,
+					eventEmitter,
+					megatron,
+					"/var/vcap/data/cf-system-trusted-certs",
+					fakeMetronClient,
+					fakeRootFSSizer,
+					false,
+					"/var/vcap/packages/healthcheck",
+					proxyManager,
+					cellID,
+					true,
+					advertisePreferenceForInstanceAddress,
+				)
+
+				runReq.Tags = executor.Tags{tracing.TraceIDTag: traceID}
+			})
+
+			It("records the container lifecycle in the trace from the run request", func() {
+				err := containerStore.Destroy(logger, containerGuid)
+				Expect(err).NotTo(HaveOccurred())
+
+				spans := exporter.GetSpans()
+				names := []string{}
+				for _, span := range spans {
+					names = append(names, span.Name)
+					Expect(span.SpanContext.TraceID().String()).To(Equal(traceID))
+				}
+				Expect(names).To(ConsistOf("download-cached-dependencies", "garden-create", "container"))
+
+				root := spans[len(spans)-1]
+				Expect(root.Name).To(Equal("container"))
+				Expect(root.StartTime.UnixNano()).To(Equal(clock.Now().UnixNano()))
+				for _, span := range spans[:len(spans)-1] {
+					Expect(span.Parent.SpanID()).To(Equal(root.SpanContext.SpanID()))
+				}
+			})
+		})
+
 		Context("when there are volumes mounted", func() {
 			BeforeEach(func() {
 				someConfig := map[string]interface{}{"some-config": "interface"}
@@ -2711,6 +3272,13 @@ var _ = Describe("Container Store", func() {
 			Expect(container.Guid).To(Equal(containerGuid))
 		})
 
+		It("has no health before the health checks of the container have started", func() {
+			container, err := containerStore.Get(logger, containerGuid)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(container.Health).To(BeNil())
+		})
+
 		Context("when the container does not exist", func() {
 			It("returns an ErrContainerNotFound", func() {
 				_, err := containerStore.Get(logger, "")
@@ -2719,6 +3287,28 @@ var _ = Describe("Container Store", func() {
 		})
 	})
 
+	Describe("GetStepStatus", func() {
+		BeforeEach(func() {
+			_, err := containerStore.Reserve(logger, &executor.AllocationRequest{Guid: containerGuid})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns the pending step tree of a container that has not run", func() {
+			status, err := containerStore.GetStepStatus(logger, containerGuid)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(status.Name).To(Equal(containerGuid))
+			Expect(status.State).To(Equal(executor.StepPending))
+		})
+
+		Context("when the container does not exist", func() {
+			It("returns an ErrContainerNotFound", func() {
+				_, err := containerStore.GetStepStatus(logger, "")
+				Expect(err).To(Equal(executor.ErrContainerNotFound))
+			})
+		})
+	})
+
 	Describe("List", func() {
 		var container1, container2 executor.Container
 
@@ -72,6 +72,20 @@ type FakeContainerStore struct {
 		result1 io.ReadCloser
 		result2 error
 	}
+	GetStepStatusStub        func(lager.Logger, string) (executor.StepStatus, error)
+	getStepStatusMutex       sync.RWMutex
+	getStepStatusArgsForCall []struct {
+		arg1 lager.Logger
+		arg2 string
+	}
+	getStepStatusReturns struct {
+		result1 executor.StepStatus
+		result2 error
+	}
+	getStepStatusReturnsOnCall map[int]struct {
+		result1 executor.StepStatus
+		result2 error
+	}
 	InitializeStub        func(lager.Logger, *executor.RunRequest) error
 	initializeMutex       sync.RWMutex
 	initializeArgsForCall []struct {
@@ -179,6 +193,17 @@ type FakeContainerStore struct {
 	stopReturnsOnCall map[int]struct {
 		result1 error
 	}
+	TenantResourcesStub        func(lager.Logger) map[string]executor.TenantResources
+	tenantResourcesMutex       sync.RWMutex
+	tenantResourcesArgsForCall []struct {
+		arg1 lager.Logger
+	}
+	tenantResourcesReturns struct {
+		result1 map[string]executor.TenantResources
+	}
+	tenantResourcesReturnsOnCall map[int]struct {
+		result1 map[string]executor.TenantResources
+	}
 	UpdateStub        func(lager.Logger, *executor.UpdateRequest) error
 	updateMutex       sync.RWMutex
 	updateArgsForCall []struct {
@@ -485,6 +510,71 @@ func (fake *FakeContainerStore) GetFilesReturnsOnCall(i int, result1 io.ReadClos
 	}{result1, result2}
 }
 
+func (fake *FakeContainerStore) GetStepStatus(arg1 lager.Logger, arg2 string) (executor.StepStatus, error) {
+	fake.getStepStatusMutex.Lock()
+	ret, specificReturn := fake.getStepStatusReturnsOnCall[len(fake.getStepStatusArgsForCall)]
+	fake.getStepStatusArgsForCall = append(fake.getStepStatusArgsForCall, struct {
+		arg1 lager.Logger
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetStepStatusStub
+	fakeReturns := fake.getStepStatusReturns
+	fake.recordInvocation("GetStepStatus", []interface{}{arg1, arg2})
+	fake.getStepStatusMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeContainerStore) GetStepStatusCallCount() int {
+	fake.getStepStatusMutex.RLock()
+	defer fake.getStepStatusMutex.RUnlock()
+	return len(fake.getStepStatusArgsForCall)
+}
+
+func (fake *FakeContainerStore) GetStepStatusCalls(stub func(lager.Logger, string) (executor.StepStatus, error)) {
+	fake.getStepStatusMutex.Lock()
+	defer fake.getStepStatusMutex.Unlock()
+	fake.GetStepStatusStub = stub
+}
+
+func (fake *FakeContainerStore) GetStepStatusArgsForCall(i int) (lager.Logger, string) {
+	fake.getStepStatusMutex.RLock()
+	defer fake.getStepStatusMutex.RUnlock()
+	argsForCall := fake.getStepStatusArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeContainerStore) GetStepStatusReturns(result1 executor.StepStatus, result2 error) {
+	fake.getStepStatusMutex.Lock()
+	defer fake.getStepStatusMutex.Unlock()
+	fake.GetStepStatusStub = nil
+	fake.getStepStatusReturns = struct {
+		result1 executor.StepStatus
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainerStore) GetStepStatusReturnsOnCall(i int, result1 executor.StepStatus, result2 error) {
+	fake.getStepStatusMutex.Lock()
+	defer fake.getStepStatusMutex.Unlock()
+	fake.GetStepStatusStub = nil
+	if fake.getStepStatusReturnsOnCall == nil {
+		fake.getStepStatusReturnsOnCall = make(map[int]struct {
+			result1 executor.StepStatus
+			result2 error
+		})
+	}
+	fake.getStepStatusReturnsOnCall[i] = struct {
+		result1 executor.StepStatus
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeContainerStore) Initialize(arg1 lager.Logger, arg2 *executor.RunRequest) error {
 	fake.initializeMutex.Lock()
 	ret, specificReturn := fake.initializeReturnsOnCall[len(fake.initializeArgsForCall)]
@@ -1044,6 +1134,67 @@ func (fake *FakeContainerStore) StopReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeContainerStore) TenantResources(arg1 lager.Logger) map[string]executor.TenantResources {
+	fake.tenantResourcesMutex.Lock()
+	ret, specificReturn := fake.tenantResourcesReturnsOnCall[len(fake.tenantResourcesArgsForCall)]
+	fake.tenantResourcesArgsForCall = append(fake.tenantResourcesArgsForCall, struct {
+		arg1 lager.Logger
+	}{arg1})
+	stub := fake.TenantResourcesStub
+	fakeReturns := fake.tenantResourcesReturns
+	fake.recordInvocation("TenantResources", []interface{}{arg1})
+	fake.tenantResourcesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeContainerStore) TenantResourcesCallCount() int {
+	fake.tenantResourcesMutex.RLock()
+	defer fake.tenantResourcesMutex.RUnlock()
+	return len(fake.tenantResourcesArgsForCall)
+}
+
+func (fake *FakeContainerStore) TenantResourcesCalls(stub func(lager.Logger) map[string]executor.TenantResources) {
+	fake.tenantResourcesMutex.Lock()
+	defer fake.tenantResourcesMutex.Unlock()
+	fake.TenantResourcesStub = stub
+}
+
+func (fake *FakeContainerStore) TenantResourcesArgsForCall(i int) lager.Logger {
+	fake.tenantResourcesMutex.RLock()
+	defer fake.tenantResourcesMutex.RUnlock()
+	argsForCall := fake.tenantResourcesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeContainerStore) TenantResourcesReturns(result1 map[string]executor.TenantResources) {
+	fake.tenantResourcesMutex.Lock()
+	defer fake.tenantResourcesMutex.Unlock()
+	fake.TenantResourcesStub = nil
+	fake.tenantResourcesReturns = struct {
+		result1 map[string]executor.TenantResources
+	}{result1}
+}
+
+func (fake *FakeContainerStore) TenantResourcesReturnsOnCall(i int, result1 map[string]executor.TenantResources) {
+	fake.tenantResourcesMutex.Lock()
+	defer fake.tenantResourcesMutex.Unlock()
+	fake.TenantResourcesStub = nil
+	if fake.tenantResourcesReturnsOnCall == nil {
+		fake.tenantResourcesReturnsOnCall = make(map[int]struct {
+			result1 map[string]executor.TenantResources
+		})
+	}
+	fake.tenantResourcesReturnsOnCall[i] = struct {
+		result1 map[string]executor.TenantResources
+	}{result1}
+}
+
 func (fake *FakeContainerStore) Update(arg1 lager.Logger, arg2 *executor.UpdateRequest) error {
 	fake.updateMutex.Lock()
 	ret, specificReturn := fake.updateReturnsOnCall[len(fake.updateArgsForCall)]
@@ -1119,6 +1270,8 @@ func (fake *FakeContainerStore) Invocations() map[string][][]interface{} {
 	defer fake.getMutex.RUnlock()
 	fake.getFilesMutex.RLock()
 	defer fake.getFilesMutex.RUnlock()
+	fake.getStepStatusMutex.RLock()
+	defer fake.getStepStatusMutex.RUnlock()
 	fake.initializeMutex.RLock()
 	defer fake.initializeMutex.RUnlock()
 	fake.listMutex.RLock()
@@ -1137,6 +1290,8 @@ func (fake *FakeContainerStore) Invocations() map[string][][]interface{} {
 	defer fake.runMutex.RUnlock()
 	fake.stopMutex.RLock()
 	defer fake.stopMutex.RUnlock()
+	fake.tenantResourcesMutex.RLock()
+	defer fake.tenantResourcesMutex.RUnlock()
 	fake.updateMutex.RLock()
 	defer fake.updateMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
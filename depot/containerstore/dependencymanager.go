@@ -9,6 +9,7 @@ import (
 	"code.cloudfoundry.org/cacheddownloader"
 	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/depot/signatureverifier"
 	"code.cloudfoundry.org/garden"
 	"code.cloudfoundry.org/lager"
 )
@@ -21,13 +22,27 @@ type DependencyManager interface {
 	Stop(logger lager.Logger)
 }
 
+// SignatureVerificationError is returned when a cached dependency is not
+// signed by a trusted key.
+type SignatureVerificationError struct {
+	Name string
+	Err  error
+}
+
+func (e *SignatureVerificationError) Error() string {
+	return fmt.Sprintf("verifying signature of %s failed: %s", e.Name, e.Err)
+}
+
 type dependencyManager struct {
 	cache               cacheddownloader.CachedDownloader
+	verifier            signatureverifier.Verifier
 	downloadRateLimiter chan struct{}
 }
 
-func NewDependencyManager(cache cacheddownloader.CachedDownloader, downloadRateLimiter chan struct{}) DependencyManager {
-	return &dependencyManager{cache, downloadRateLimiter}
+// NewDependencyManager creates a DependencyManager. verifier may be nil, in
+// which case signatures of cached dependencies are not verified.
+func NewDependencyManager(cache cacheddownloader.CachedDownloader, verifier signatureverifier.Verifier, downloadRateLimiter chan struct{}) DependencyManager {
+	return &dependencyManager{cache, verifier, downloadRateLimiter}
 }
 
 func (bm *dependencyManager) Stop(logger lager.Logger) {
@@ -105,25 +120,50 @@ func (bm *dependencyManager) downloadCachedDependency(logger lager.Logger, mount
 		return nil, err
 	}
 
-	logger.Debug("fetching-cache-dependency", lager.Data{"download-url": downloadURL.String(), "cache-key": mount.CacheKey})
+	checksum := cacheddownloader.ChecksumInfoType{
+		Algorithm: mount.ChecksumAlgorithm,
+		Value:     mount.ChecksumValue,
+	}
+
+	cacheKey := mount.CacheKey
+	if bm.verifier != nil {
+		cacheKey = signatureverifier.CacheKey(cacheKey, checksum)
+	}
+
+	logger.Debug("fetching-cache-dependency", lager.Data{"download-url": downloadURL.String(), "cache-key": cacheKey})
 	dirPath, downloadedSize, err := bm.cache.FetchAsDirectory(
 		logger.Session("downloader"),
 		downloadURL,
-		mount.CacheKey,
-		cacheddownloader.ChecksumInfoType{
-			Algorithm: mount.ChecksumAlgorithm,
-			Value:     mount.ChecksumValue,
-		},
+		cacheKey,
+		checksum,
 		nil,
 	)
 	if err != nil {
-		logger.Error("failed-fetching-cache-dependency", err, lager.Data{"download-url": downloadURL.String(), "cache-key": mount.CacheKey})
+		logger.Error("failed-fetching-cache-dependency", err, lager.Data{"download-url": downloadURL.String(), "cache-key": cacheKey})
 		if mount.Name != "" {
 			metronClient.SendAppLog(fmt.Sprintf("Downloading %s failed", mount.Name), sourceName, tags)
 		}
 		return nil, err
 	}
-	logger.Debug("fetched-cache-dependency", lager.Data{"download-url": downloadURL.String(), "cache-key": mount.CacheKey, "size": downloadedSize})
+	logger.Debug("fetched-cache-dependency", lager.Data{"download-url": downloadURL.String(), "cache-key": cacheKey, "size": downloadedSize})
+
+	// the signature is verified against the checksum the fetched content was
+	// validated with
+	if bm.verifier != nil {
+		err = bm.verifier.Verify(logger.Session("verifier"), downloadURL, checksum, nil)
+		if err != nil {
+			logger.Error("failed-verifying-cache-dependency", err, lager.Data{"download-url": downloadURL.String(), "cache-key": cacheKey})
+			bm.cache.CloseDirectory(logger, cacheKey, dirPath)
+			if mount.Name != "" {
+				metronClient.SendAppLog(fmt.Sprintf("Verifying signature of %s failed", mount.Name), sourceName, tags)
+			}
+			name := mount.Name
+			if name == "" {
+				name = mount.CacheKey
+			}
+			return nil, &SignatureVerificationError{Name: name, Err: err}
+		}
+	}
 
 	if downloadedSize != 0 {
 		if mount.Name != "" {
@@ -134,7 +174,7 @@ func (bm *dependencyManager) downloadCachedDependency(logger lager.Logger, mount
 			metronClient.SendAppLog(fmt.Sprintf("Downloaded %s", mount.Name), sourceName, tags)
 		}
 	}
-	return newCachedBindMount(mount.CacheKey, newBindMount(dirPath, mount.To)), nil
+	return newCachedBindMount(cacheKey, newBindMount(dirPath, mount.To)), nil
 }
 
 func (bm *dependencyManager) ReleaseCachedDependencies(logger lager.Logger, keys []BindMountCacheKey) error {
@@ -13,14 +13,30 @@ type nodeMap struct {
 	lock  *sync.RWMutex
 
 	remainingResources *executor.ExecutorResources
+
+	tenantQuotaTag     string
+	tenantQuotas       map[string]executor.ExecutorResources
+	defaultTenantQuota executor.ExecutorResources
+	tenantUsage        map[string]*executor.ExecutorResources
+	containerTenants   map[string]string
+
+	// reservedSidecars holds the part of each allocation that was reserved
+	// for sidecars
+	reservedSidecars map[string]executor.Resource
 }
 
-func newNodeMap(totalCapacity *executor.ExecutorResources) *nodeMap {
+func newNodeMap(totalCapacity *executor.ExecutorResources, config *ContainerConfig) *nodeMap {
 	capacity := totalCapacity.Copy()
 	return &nodeMap{
 		nodes:              make(map[string]*storeNode),
 		lock:               &sync.RWMutex{},
 		remainingResources: &capacity,
+		tenantQuotaTag:     config.TenantQuotaTag,
+		tenantQuotas:       config.TenantQuotas,
+		defaultTenantQuota: config.DefaultTenantQuota,
+		tenantUsage:        make(map[string]*executor.ExecutorResources),
+		containerTenants:   make(map[string]string),
+		reservedSidecars:   make(map[string]executor.Resource),
 	}
 }
 
@@ -34,10 +50,48 @@ func (n *nodeMap) Contains(guid string) bool {
 func (n *nodeMap) RemainingResources() executor.ExecutorResources {
 	n.lock.RLock()
 	defer n.lock.RUnlock()
+
 	return n.remainingResources.Copy()
 }
 
-func (n *nodeMap) Add(node *storeNode) error {
+func (n *nodeMap) TenantResources() map[string]executor.TenantResources {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	if n.tenantQuotaTag == "" {
+		return nil
+	}
+
+	tenants := make(map[string]executor.TenantResources)
+	for tenant, quota := range n.tenantQuotas {
+		tenants[tenant] = executor.TenantResources{Quota: quota}
+	}
+	for tenant, usage := range n.tenantUsage {
+		tenants[tenant] = executor.TenantResources{
+			Quota: n.tenantQuota(tenant),
+			Used:  *usage,
+		}
+	}
+	return tenants
+}
+
+func (n *nodeMap) tenant(info *executor.Container) string {
+	if n.tenantQuotaTag == "" {
+		return ""
+	}
+	return info.Tags[n.tenantQuotaTag]
+}
+
+func (n *nodeMap) tenantQuota(tenant string) executor.ExecutorResources {
+	if quota, ok := n.tenantQuotas[tenant]; ok {
+		return quota
+	}
+	return n.defaultTenantQuota
+}
+
+// Add reserves the resources of the node, which include the sidecars
+// resources given separately.
+func (n *nodeMap) Add(node *storeNode, sidecars *executor.Resource) error {
 	n.lock.Lock()
 	defer n.lock.Unlock()
 
@@ -46,16 +100,119 @@ func (n *nodeMap) Add(node *storeNode) error {
 		return executor.ErrContainerGuidNotAvailable
 	}
 
+	tenant := n.tenant(&info)
+	usage := n.tenantUsage[tenant]
+	if tenant != "" {
+		if usage == nil {
+			usage = &executor.ExecutorResources{}
+		}
+
+		quota := n.tenantQuota(tenant)
+		if !usage.WithinQuota(&quota, &info.Resource) {
+			return executor.ErrTenantQuotaExceeded
+		}
+	}
+
 	ok := n.remainingResources.Subtract(&info.Resource)
 	if !ok {
 		return executor.ErrInsufficientResourcesAvailable
 	}
 
+	if tenant != "" {
+		usage.Add(&info.Resource)
+		n.tenantUsage[tenant] = usage
+		n.containerTenants[info.Guid] = tenant
+	}
+
+	if sidecars.MemoryMB > 0 || sidecars.DiskMB > 0 {
+		n.reservedSidecars[info.Guid] = *sidecars
+	}
 	n.nodes[info.Guid] = node
 
 	return nil
 }
 
+// Initialize initializes the container. Sidecars that need more memory or
+// disk than was reserved for them grow the allocation, which counts against
+// the cell and tenant capacity like the rest of the container.
+func (n *nodeMap) Initialize(logger lager.Logger, req *executor.RunRequest) error {
+	node, extra, err := n.reserveSidecars(req)
+	if err != nil {
+		logger.Error("failed-to-reserve-sidecar-resources", err)
+		return err
+	}
+
+	err = node.Initialize(logger, req)
+	if err != nil && extra != nil {
+		n.releaseSidecars(node, req.Guid, extra)
+	}
+
+	return err
+}
+
+func (n *nodeMap) reserveSidecars(req *executor.RunRequest) (*storeNode, *executor.Resource, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	node, ok := n.nodes[req.Guid]
+	if !ok {
+		return nil, nil, executor.ErrContainerNotFound
+	}
+
+	sidecars := req.RunInfo.SidecarResource()
+	reserved := n.reservedSidecars[req.Guid]
+	extra := executor.Resource{}
+	if sidecars.MemoryMB > reserved.MemoryMB {
+		extra.MemoryMB = sidecars.MemoryMB - reserved.MemoryMB
+	}
+	if sidecars.DiskMB > reserved.DiskMB {
+		extra.DiskMB = sidecars.DiskMB - reserved.DiskMB
+	}
+	if extra.MemoryMB == 0 && extra.DiskMB == 0 {
+		return node, nil, nil
+	}
+
+	tenant, hasTenant := n.containerTenants[req.Guid]
+	if hasTenant {
+		// growing a container does not add one to the tenant
+		quota := n.tenantQuota(tenant)
+		quota.Containers = 0
+		if !n.tenantUsage[tenant].WithinQuota(&quota, &extra) {
+			return nil, nil, executor.ErrTenantQuotaExceeded
+		}
+	}
+
+	ok = n.remainingResources.Shrink(&extra)
+	if !ok {
+		return nil, nil, executor.ErrInsufficientResourcesAvailable
+	}
+
+	if hasTenant {
+		n.tenantUsage[tenant].Grow(&extra)
+	}
+	node.grow(&extra)
+
+	return node, &extra, nil
+}
+
+// releaseSidecars gives back the resources reserveSidecars took for a node
+// that failed to initialize, unless the node was removed in the meantime
+// and gave them back itself.
+func (n *nodeMap) releaseSidecars(node *storeNode, guid string, extra *executor.Resource) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.nodes[guid] != node {
+		return
+	}
+
+	n.remainingResources.Grow(extra)
+	if tenant, ok := n.containerTenants[guid]; ok {
+		n.tenantUsage[tenant].Shrink(extra)
+	}
+	node.shrink(extra)
+}
+
 func (n *nodeMap) Remove(guid string) {
 	n.lock.Lock()
 	defer n.lock.Unlock()
@@ -71,6 +228,15 @@ func (n *nodeMap) Remove(guid string) {
 func (n *nodeMap) remove(node *storeNode) {
 	info := node.Info()
 	n.remainingResources.Add(&info.Resource)
+
+	if tenant, ok := n.containerTenants[info.Guid]; ok {
+		// the usage of a tenant stays around once it has no containers left, so
+		// that it is reported as zero rather than no longer reported at all
+		n.tenantUsage[tenant].Subtract(&info.Resource)
+		delete(n.containerTenants, info.Guid)
+	}
+
+	delete(n.reservedSidecars, info.Guid)
 	delete(n.nodes, info.Guid)
 }
 
@@ -1,11 +1,13 @@
 package containerstore
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,6 +18,7 @@ import (
 	"code.cloudfoundry.org/executor/depot/event"
 	"code.cloudfoundry.org/executor/depot/log_streamer"
 	"code.cloudfoundry.org/executor/depot/steps"
+	"code.cloudfoundry.org/executor/depot/tracing"
 	"code.cloudfoundry.org/executor/depot/transformer"
 	"code.cloudfoundry.org/executor/initializer/configuration"
 	"code.cloudfoundry.org/garden"
@@ -25,9 +28,13 @@ import (
 	"github.com/hashicorp/errwrap"
 	"github.com/tedsuo/ifrit"
 	"github.com/tedsuo/ifrit/grouper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const DownloadCachedDependenciesFailed = "failed to download cached artifacts"
+const VerifyCachedDependenciesFailed = "failed to verify signature of cached artifacts"
 const ContainerCreationFailedMessage = "failed to create container"
 const ContainerExpirationMessage = "expired container"
 const ContainerMissingMessage = "missing garden container"
@@ -37,6 +44,8 @@ const CredDirFailed = "failed to create credentials directory"
 
 const ContainerCompletedCount = "ContainerCompletedCount"
 const ContainerExitedOnTimeoutCount = "ContainerExitedOnTimeoutCount"
+const ContainerRestartedCount = "ContainerRestartedCount"
+const SidecarRestartedCount = "SidecarRestartedCount"
 
 const maxErrorMsgLength = 1024
 
@@ -65,6 +74,10 @@ type storeNode struct {
 	info               executor.Container
 	bindMountCacheKeys []BindMountCacheKey
 	gardenContainer    garden.Container
+	traceCtx           context.Context
+	traceSpan          trace.Span
+	stepTree           *steps.StepNode
+	healthTracker      *steps.HealthTracker
 
 	clock clock.Clock
 
@@ -138,6 +151,8 @@ func newStoreNode(
 		enableUnproxiedPortMappings:           enableUnproxiedPortMappings,
 		advertisePreferenceForInstanceAddress: advertisePreferenceForInstanceAddress,
 		regenerateCertsCh:                     make(chan struct{}, 1),
+		stepTree:                              steps.NewStepNode(container.Guid),
+		healthTracker:                         steps.NewHealthTracker(),
 	}
 }
 
@@ -159,6 +174,14 @@ func (n *storeNode) Info() executor.Container {
 	return n.info.Copy()
 }
 
+func (n *storeNode) StepStatus() executor.StepStatus {
+	return n.stepTree.Status()
+}
+
+func (n *storeNode) Health() *executor.ContainerHealth {
+	return n.healthTracker.Health()
+}
+
 func (n *storeNode) GetFiles(logger lager.Logger, sourcePath string) (io.ReadCloser, error) {
 	n.infoLock.Lock()
 	gc := n.gardenContainer
@@ -169,6 +192,8 @@ func (n *storeNode) GetFiles(logger lager.Logger, sourcePath string) (io.ReadClo
 	return gc.StreamOut(garden.StreamOutSpec{Path: sourcePath, User: "root"})
 }
 
+// Initialize only transitions the container. The node map grows its
+// allocation for the sidecars beforehand and shrinks it again if this fails.
 func (n *storeNode) Initialize(logger lager.Logger, req *executor.RunRequest) error {
 	logger = logger.Session("node-initialize")
 	n.infoLock.Lock()
@@ -179,9 +204,67 @@ func (n *storeNode) Initialize(logger lager.Logger, req *executor.RunRequest) er
 		logger.Error("failed-to-initialize", err)
 		return err
 	}
+
+	n.startTrace()
 	return nil
 }
 
+// grow and shrink change the allocation of the container for the resources
+// of its sidecars
+func (n *storeNode) grow(extra *executor.Resource) {
+	n.infoLock.Lock()
+	defer n.infoLock.Unlock()
+	n.info.MemoryMB += extra.MemoryMB
+	n.info.DiskMB += extra.DiskMB
+}
+
+func (n *storeNode) shrink(extra *executor.Resource) {
+	n.infoLock.Lock()
+	defer n.infoLock.Unlock()
+	n.info.MemoryMB -= extra.MemoryMB
+	n.info.DiskMB -= extra.DiskMB
+}
+
+// startTrace starts the root span of the container, backdated to when it was
+// reserved, as a child of the trace in the container tags. Callers must hold
+// the infoLock.
+func (n *storeNode) startTrace() {
+	if n.traceSpan != nil {
+		return
+	}
+
+	ctx := tracing.ContextWithTags(context.Background(), n.info.Tags)
+	n.traceCtx, n.traceSpan = n.tracer().Start(ctx, "container",
+		trace.WithTimestamp(time.Unix(0, n.info.AllocatedAt)),
+		trace.WithAttributes(attribute.String("container.guid", n.info.Guid)),
+	)
+}
+
+func (n *storeNode) startSpan(name string) trace.Span {
+	n.infoLock.Lock()
+	n.startTrace()
+	ctx := n.traceCtx
+	n.infoLock.Unlock()
+
+	_, span := n.tracer().Start(ctx, name)
+	return span
+}
+
+func (n *storeNode) tracer() trace.Tracer {
+	if n.config.Tracer == nil {
+		return tracing.NewTracer(nil)
+	}
+	return n.config.Tracer
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
 func (n *storeNode) Create(logger lager.Logger) error {
 	logger = logger.Session("node-create")
 	n.acquireOpLock(logger)
@@ -197,9 +280,15 @@ func (n *storeNode) Create(logger lager.Logger) error {
 	}
 
 	createContainer := func() error {
+		span := n.startSpan("download-cached-dependencies")
 		mounts, err := n.dependencyManager.DownloadCachedDependencies(logger, info.CachedDependencies, info.LogConfig, n.metronClient)
+		endSpan(span, err)
 		if err != nil {
-			n.complete(logger, true, DownloadCachedDependenciesFailed, true)
+			if _, ok := err.(*SignatureVerificationError); ok {
+				n.complete(logger, true, VerifyCachedDependenciesFailed, true)
+			} else {
+				n.complete(logger, true, DownloadCachedDependenciesFailed, true)
+			}
 			return err
 		}
 
@@ -250,7 +339,9 @@ func (n *storeNode) Create(logger lager.Logger) error {
 
 		sourceName, tags := n.info.LogConfig.GetSourceNameAndTagsForLogging()
 		n.metronClient.SendAppLog(fmt.Sprintf("Cell %s creating container for instance %s", n.cellID, n.Info().Guid), sourceName, tags)
+		span = n.startSpan("garden-create")
 		gardenContainer, err := n.createGardenContainer(logger, &info)
+		endSpan(span, err)
 		if err != nil {
 			n.metronClient.SendAppErrorLog(fmt.Sprintf("Cell %s failed to create container for instance %s: %s", n.cellID, n.Info().Guid, err.Error()), sourceName, tags)
 			n.complete(logger, true, fmt.Sprintf("%s: %s", ContainerCreationFailedMessage, err.Error()), true)
@@ -511,11 +602,26 @@ func (n *storeNode) Run(logger lager.Logger) error {
 	for i, p := range n.info.Ports {
 		proxyTLSPorts[i] = p.ContainerTLSProxyPort
 	}
+	n.infoLock.Lock()
+	n.startTrace()
+	traceCtx := n.traceCtx
+	n.infoLock.Unlock()
+
 	cfg := transformer.Config{
 		BindMounts:        n.bindMounts,
 		ProxyTLSPorts:     proxyTLSPorts,
 		CreationStartTime: n.startTime,
 		MetronClient:      n.metronClient,
+		OnRestart: func(restartCount int, err error) {
+			n.restarted(logger, restartCount, err)
+		},
+		OnSidecarRestart: func(sidecar int, restartCount int, err error) {
+			n.sidecarRestarted(logger, sidecar, restartCount, err)
+		},
+		OnProgress:    n.progressed,
+		TraceContext:  traceCtx,
+		StepTree:      n.stepTree,
+		HealthTracker: n.healthTracker,
 	}
 	runner, err := n.transformer.StepsRunner(logger, n.info, n.gardenContainer, logStreamer, cfg)
 	if err != nil {
@@ -559,6 +665,15 @@ func (n *storeNode) completeWithError(logger lager.Logger, err error) {
 	}
 
 	if errorStr != "" {
+		// the excerpt is bounded by the run step that captured it
+		excerpt := steps.OutputExcerpt(err)
+		if excerpt != "" && !strings.Contains(errorStr, excerpt) {
+			errorStr += "\n" + excerpt
+		}
+		n.infoLock.Lock()
+		n.info.RunResult.OutputExcerpt = excerpt
+		n.infoLock.Unlock()
+
 		n.complete(logger, true, errorStr, false)
 		return
 	}
@@ -589,6 +704,55 @@ func (n *storeNode) run(logger lager.Logger, logStreamer log_streamer.LogStreame
 	n.completeWithError(logger, err)
 }
 
+func (n *storeNode) restarted(logger lager.Logger, restartCount int, err error) {
+	reason := restartReason(err)
+	logger.Info("restarting-action", lager.Data{"restart-count": restartCount, "reason": reason})
+
+	n.infoLock.Lock()
+	n.info.RestartCount = restartCount
+	info := n.info.Copy()
+	n.infoLock.Unlock()
+
+	sourceName, tags := info.LogConfig.GetSourceNameAndTagsForLogging()
+	n.metronClient.SendAppLog(fmt.Sprintf("Cell %s restarting instance %s (restart %d)", n.cellID, info.Guid, restartCount), sourceName, tags)
+	n.metronClient.IncrementCounter(ContainerRestartedCount)
+
+	go n.eventEmitter.Emit(executor.NewContainerRestartedEvent(info, reason))
+}
+
+func restartReason(err error) string {
+	if err == nil {
+		return ""
+	}
+	reason := err.Error()
+	if len(reason) > maxErrorMsgLength {
+		reason = strings.ToValidUTF8(reason[:maxErrorMsgLength], "")
+	}
+	return reason
+}
+
+func (n *storeNode) sidecarRestarted(logger lager.Logger, sidecar int, restartCount int, err error) {
+	reason := restartReason(err)
+	logger.Info("restarting-sidecar", lager.Data{"sidecar": sidecar, "restart-count": restartCount, "reason": reason})
+
+	info := n.Info()
+
+	sourceName, tags := info.LogConfig.GetSourceNameAndTagsForLogging()
+	n.metronClient.SendAppLog(fmt.Sprintf("Cell %s restarting sidecar %d of instance %s (restart %d)", n.cellID, sidecar, info.Guid, restartCount), sourceName, tags)
+	n.metronClient.IncrementCounter(SidecarRestartedCount)
+
+	go n.eventEmitter.Emit(executor.NewSidecarRestartedEvent(info, sidecar, restartCount, reason))
+}
+
+func (n *storeNode) progressed(step string, phase executor.StepPhase, duration time.Duration, err error) {
+	event := executor.NewStepProgressEvent(n.Info().Guid, step, phase, duration, err)
+	if len(event.Error) > maxErrorMsgLength {
+		event.Error = strings.ToValidUTF8(event.Error[:maxErrorMsgLength], "")
+	}
+
+	go n.eventEmitter.Emit(event)
+}
+
 func (n *storeNode) Update(logger lager.Logger, req *executor.UpdateRequest) {
 	n.infoLock.Lock()
 	n.info.InternalRoutes = req.InternalRoutes
@@ -645,8 +809,13 @@ func (n *storeNode) Destroy(logger lager.Logger) error {
 
 	n.infoLock.Lock()
 	info := n.info.Copy()
+	n.startTrace()
+	rootSpan := n.traceSpan
 	n.infoLock.Unlock()
 
+	var err error
+	defer func() { endSpan(rootSpan, err) }()
+
 	sourceName, tags := n.info.LogConfig.GetSourceNameAndTagsForLogging()
 
 	n.metronClient.SendAppLog(fmt.Sprintf("Cell %s destroying container for instance %s", n.cellID, info.Guid), sourceName, tags)
@@ -654,7 +823,7 @@ func (n *storeNode) Destroy(logger lager.Logger) error {
 	defer n.removeCredsDir(logger, info)
 	defer n.umountVolumeMounts(logger, info)
 
-	err := n.destroyContainer(logger)
+	err = n.destroyContainer(logger)
 	if err != nil {
 		n.metronClient.SendAppLog(fmt.Sprintf("Cell %s failed to destroy container for instance %s", n.cellID, info.Guid), sourceName, tags)
 		return err
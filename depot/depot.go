@@ -4,6 +4,7 @@ import (
 	"io"
 	"sync"
 
+	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/executor/depot/containerstore"
 	"code.cloudfoundry.org/executor/depot/event"
@@ -25,9 +26,13 @@ type client struct {
 	deletionWorkPool *workpool.WorkPool
 	readWorkPool     *workpool.WorkPool
 	metricsWorkPool  *workpool.WorkPool
+	clock            clock.Clock
 
 	healthyLock sync.RWMutex
 	healthy     bool
+
+	drainingLock sync.RWMutex
+	draining     bool
 }
 
 func NewClient(
@@ -40,6 +45,7 @@ func NewClient(
 	deletionWorkPool *workpool.WorkPool,
 	readWorkPool *workpool.WorkPool,
 	metricsWorkPool *workpool.WorkPool,
+	clock clock.Clock,
 ) executor.Client {
 	return &client{
 		totalCapacity:    totalCapacity,
@@ -51,6 +57,7 @@ func NewClient(
 		deletionWorkPool: deletionWorkPool,
 		readWorkPool:     readWorkPool,
 		metricsWorkPool:  metricsWorkPool,
+		clock:            clock,
 		healthy:          true,
 	}
 }
@@ -67,8 +74,16 @@ func (c *client) AllocateContainers(logger lager.Logger, requests []executor.All
 	logger = logger.Session("allocate-containers")
 	failures := make([]executor.AllocationFailure, 0)
 
+	draining := c.isDraining()
+
 	for i := range requests {
 		req := &requests[i]
+		if draining {
+			logger.Info("rejecting-allocation-while-draining", lager.Data{"guid": req.Guid})
+			failures = append(failures, executor.NewAllocationFailure(req, executor.ErrCellDraining.Error()))
+			continue
+		}
+
 		err := req.Validate()
 		if err != nil {
 			logger.Error("invalid-request", err)
@@ -100,6 +115,19 @@ func (c *client) GetContainer(logger lager.Logger, guid string) (executor.Contai
 	return container, err
 }
 
+func (c *client) GetStepStatus(logger lager.Logger, guid string) (executor.StepStatus, error) {
+	logger = logger.Session("get-step-status", lager.Data{
+		"guid": guid,
+	})
+
+	status, err := c.containerStore.GetStepStatus(logger, guid)
+	if err != nil {
+		logger.Error("failed-to-get-step-status", err)
+	}
+
+	return status, err
+}
+
 func (c *client) RunContainer(logger lager.Logger, request *executor.RunRequest) error {
 	logger = logger.Session("run-container", lager.Data{
 		"guid": request.Guid,
@@ -229,6 +257,11 @@ func (c *client) RemainingResources(logger lager.Logger) (executor.ExecutorResou
 	return c.containerStore.RemainingResources(logger), nil
 }
 
+func (c *client) TenantResources(logger lager.Logger) (map[string]executor.TenantResources, error) {
+	logger = logger.Session("tenant-resources")
+	return c.containerStore.TenantResources(logger), nil
+}
+
 func (c *client) Ping(logger lager.Logger) error {
 	return c.gardenClient.Ping()
 }
@@ -5,6 +5,7 @@ import (
 	"io"
 	"time"
 
+	"code.cloudfoundry.org/clock/fakeclock"
 	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/executor/depot"
 	"code.cloudfoundry.org/executor/depot/containerstore/containerstorefakes"
@@ -29,6 +30,7 @@ var _ = Describe("Depot", func() {
 		gardenClient        *fakes.FakeGardenClient
 		volmanClient        *volmanfakes.FakeManager
 		containerStore      *containerstorefakes.FakeContainerStore
+		fakeClock           *fakeclock.FakeClock
 		resources           executor.ExecutorResources
 		volumeDrivers       []string
 		CreateWorkPoolSize  int
@@ -43,6 +45,7 @@ var _ = Describe("Depot", func() {
 		gardenClient = new(fakes.FakeGardenClient)
 		volmanClient = new(volmanfakes.FakeManager)
 		containerStore = new(containerstorefakes.FakeContainerStore)
+		fakeClock = fakeclock.NewFakeClock(time.Now())
 
 		resources = executor.ExecutorResources{
 			MemoryMB:   1024,
@@ -69,6 +72,7 @@ var _ = Describe("Depot", func() {
 		depotClient = depot.NewClient(
 			resources, containerStore, gardenClient, volmanClient, eventHub,
 			creationWorkPool, deletionWorkPool, readWorkPool, metricsWorkPool,
+			fakeClock,
 		)
 	})
 
@@ -732,6 +736,95 @@ var _ = Describe("Depot", func() {
 			})
 		})
 	})
+
+	Describe("Drain", func() {
+		var (
+			containers []executor.Container
+			stopped    chan string
+		)
+
+		BeforeEach(func() {
+			task := executor.NewContainerFromResource("task-guid", &executor.Resource{}, executor.Tags{executor.LifecycleTag: executor.TaskLifecycle})
+			task.State = executor.StateRunning
+			lrp := executor.NewContainerFromResource("lrp-guid", &executor.Resource{}, executor.Tags{executor.LifecycleTag: executor.LRPLifecycle})
+			lrp.State = executor.StateRunning
+			completed := executor.NewContainerFromResource("completed-guid", &executor.Resource{}, nil)
+			completed.State = executor.StateCompleted
+			containers = []executor.Container{lrp, completed, task}
+
+			containerStore.ListReturns(containers)
+
+			stopped = make(chan string, 3)
+			containerStore.StopStub = func(logger lager.Logger, guid string) error {
+				stopped <- guid
+				return nil
+			}
+			containerStore.GetStub = func(logger lager.Logger, guid string) (executor.Container, error) {
+				return executor.Container{Guid: guid, State: executor.StateCompleted}, nil
+			}
+		})
+
+		It("rejects new allocations with a typed reason", func() {
+			Expect(depotClient.Drain(logger, executor.DrainOptions{})).To(Succeed())
+
+			requests := []executor.AllocationRequest{newAllocationRequest("guid-1")}
+			failures := depotClient.AllocateContainers(logger, requests)
+			Expect(failures).To(HaveLen(1))
+			Expect(failures[0].ErrorMsg).To(Equal(executor.ErrCellDraining.Error()))
+			Expect(containerStore.ReserveCallCount()).To(Equal(0))
+		})
+
+		It("stops tasks before lrps and skips completed containers", func() {
+			Expect(depotClient.Drain(logger, executor.DrainOptions{})).To(Succeed())
+
+			Eventually(stopped).Should(Receive(Equal("task-guid")))
+			Eventually(stopped).Should(Receive(Equal("lrp-guid")))
+			Consistently(stopped).ShouldNot(Receive())
+		})
+
+		It("emits progress events and a completion event", func() {
+			Expect(depotClient.Drain(logger, executor.DrainOptions{})).To(Succeed())
+
+			Eventually(eventHub.EmitCallCount).Should(Equal(3))
+			Expect(eventHub.EmitArgsForCall(0)).To(Equal(executor.NewDrainProgressEvent("task-guid", false, 1, 2)))
+			Expect(eventHub.EmitArgsForCall(1)).To(Equal(executor.NewDrainProgressEvent("lrp-guid", false, 2, 2)))
+			Expect(eventHub.EmitArgsForCall(2)).To(Equal(executor.NewDrainCompleteEvent(2, 0, 2)))
+		})
+
+		It("does nothing when already draining", func() {
+			Expect(depotClient.Drain(logger, executor.DrainOptions{})).To(Succeed())
+			Eventually(eventHub.EmitCallCount).Should(Equal(3))
+
+			Expect(depotClient.Drain(logger, executor.DrainOptions{})).To(Succeed())
+			Consistently(eventHub.EmitCallCount).Should(Equal(3))
+			Expect(containerStore.StopCallCount()).To(Equal(2))
+		})
+
+		Context("when a container does not complete within the stop timeout", func() {
+			BeforeEach(func() {
+				containerStore.GetStub = func(logger lager.Logger, guid string) (executor.Container, error) {
+					if guid == "task-guid" {
+						return executor.Container{Guid: guid, State: executor.StateRunning}, nil
+					}
+					return executor.Container{Guid: guid, State: executor.StateCompleted}, nil
+				}
+			})
+
+			It("moves on and reports the container as timed out", func() {
+				Expect(depotClient.Drain(logger, executor.DrainOptions{StopTimeout: 5 * time.Second})).To(Succeed())
+
+				Eventually(stopped).Should(Receive(Equal("task-guid")))
+				Consistently(stopped).ShouldNot(Receive())
+
+				fakeClock.WaitForNWatchersAndIncrement(5*time.Second, 2)
+
+				Eventually(stopped).Should(Receive(Equal("lrp-guid")))
+				Eventually(eventHub.EmitCallCount).Should(Equal(3))
+				Expect(eventHub.EmitArgsForCall(0)).To(Equal(executor.NewDrainProgressEvent("task-guid", true, 1, 2)))
+				Expect(eventHub.EmitArgsForCall(2)).To(Equal(executor.NewDrainCompleteEvent(1, 1, 2)))
+			})
+		})
+	})
 })
 
 func convertSliceToMap(containers []executor.Container) map[string]executor.Container {
@@ -0,0 +1,140 @@
+package depot
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+)
+
+const DrainPollInterval = 1 * time.Second
+
+// Drain stops every container and rejects new allocations. Draining a cell
+// that is already draining does nothing. There is no way back: a cell drains
+// before it is shut down, and a restarted executor accepts work again.
+func (c *client) Drain(logger lager.Logger, opts executor.DrainOptions) error {
+	logger = logger.Session("drain")
+
+	c.drainingLock.Lock()
+	defer c.drainingLock.Unlock()
+
+	if c.draining {
+		logger.Info("already-draining")
+		return nil
+	}
+	c.draining = true
+
+	go c.drain(logger, opts.WithDefaults())
+	return nil
+}
+
+func (c *client) isDraining() bool {
+	c.drainingLock.RLock()
+	defer c.drainingLock.RUnlock()
+	return c.draining
+}
+
+func (c *client) drain(logger lager.Logger, opts executor.DrainOptions) {
+	groups := drainGroups(c.containerStore.List(logger), opts.StopOrder)
+
+	total := 0
+	for _, group := range groups {
+		total += len(group)
+	}
+
+	logger.Info("starting", lager.Data{
+		"containers":           total,
+		"max-concurrent-stops": opts.MaxConcurrentStops,
+		"stop-timeout":         opts.StopTimeout.String(),
+	})
+
+	var progressLock sync.Mutex
+	var stopped, timedOut int
+
+	for _, group := range groups {
+		limiter := make(chan struct{}, opts.MaxConcurrentStops)
+		wg := sync.WaitGroup{}
+
+		for _, container := range group {
+			limiter <- struct{}{}
+			wg.Add(1)
+
+			go func(guid string) {
+				defer wg.Done()
+				defer func() { <-limiter }()
+
+				completed := c.stopAndWait(logger.Session("stop", lager.Data{"guid": guid}), guid, opts.StopTimeout)
+
+				progressLock.Lock()
+				if completed {
+					stopped++
+				} else {
+					timedOut++
+				}
+				event := executor.NewDrainProgressEvent(guid, !completed, stopped+timedOut, total)
+				progressLock.Unlock()
+
+				c.eventHub.Emit(event)
+			}(container.Guid)
+		}
+
+		wg.Wait()
+	}
+
+	logger.Info("complete", lager.Data{"stopped": stopped, "timed-out": timedOut})
+	c.eventHub.Emit(executor.NewDrainCompleteEvent(stopped, timedOut, total))
+}
+
+// stopAndWait returns false if the container did not complete within the
+// timeout
+func (c *client) stopAndWait(logger lager.Logger, guid string, timeout time.Duration) bool {
+	err := c.containerStore.Stop(logger, guid)
+	if err == executor.ErrContainerNotFound {
+		return true
+	}
+	if err != nil {
+		logger.Error("failed-to-stop-container", err)
+	}
+
+	timeoutTimer := c.clock.NewTimer(timeout)
+	defer timeoutTimer.Stop()
+
+	ticker := c.clock.NewTicker(DrainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		container, err := c.containerStore.Get(logger, guid)
+		if err != nil || container.State == executor.StateCompleted {
+			return true
+		}
+
+		select {
+		case <-ticker.C():
+		case <-timeoutTimer.C():
+			logger.Info("timed-out-waiting-for-container-to-complete")
+			return false
+		}
+	}
+}
+
+func drainGroups(containers []executor.Container, stopOrder []executor.Tags) [][]executor.Container {
+	groups := make([][]executor.Container, len(stopOrder)+1)
+
+	for _, container := range containers {
+		if container.State == executor.StateCompleted {
+			continue
+		}
+
+		rank := len(stopOrder)
+		for i, tags := range stopOrder {
+			if tagsMatch(tags, container.Tags) {
+				rank = i
+				break
+			}
+		}
+		groups[rank] = append(groups[rank], container)
+	}
+
+	return groups
+}
@@ -0,0 +1,13 @@
+package healthcheckscheduler_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestHealthCheckScheduler(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "HealthCheckScheduler Suite")
+}
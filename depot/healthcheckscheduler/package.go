@@ -0,0 +1 @@
+package healthcheckscheduler // import "code.cloudfoundry.org/executor/depot/healthcheckscheduler"
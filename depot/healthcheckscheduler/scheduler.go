@@ -0,0 +1,142 @@
+package healthcheckscheduler
+
+import (
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/workpool"
+)
+
+const (
+	HealthCheckQueueDepth    = "HealthCheckQueueDepth"
+	HealthCheckQueueWaitTime = "HealthCheckQueueWaitTime"
+
+	// MaxStretch caps how much longer than configured the intervals between
+	// health checks get while the work pool is saturated.
+	MaxStretch = 4
+)
+
+// Scheduler spreads health checks over time and backs off while the health
+// check work pool is saturated. It satisfies both steps.WorkPool and
+// steps.Schedule.
+type Scheduler struct {
+	logger         lager.Logger
+	workPool       *workpool.WorkPool
+	size           int
+	jitter         float64
+	metronClient   loggingclient.IngressClient
+	reportInterval time.Duration
+	clock          clock.Clock
+
+	lock         sync.Mutex
+	queued       int
+	maxQueueWait time.Duration
+	random       *rand.Rand
+}
+
+// New returns a Scheduler for a work pool with size workers. Intervals are
+// randomly shortened or lengthened by up to the jitter fraction, which is
+// capped below 1.
+func New(
+	logger lager.Logger,
+	workPool *workpool.WorkPool,
+	size int,
+	jitter float64,
+	metronClient loggingclient.IngressClient,
+	reportInterval time.Duration,
+	clock clock.Clock,
+) *Scheduler {
+	if size < 1 {
+		size = 1
+	}
+	if jitter < 0 {
+		jitter = 0
+	}
+	if jitter > 0.9 {
+		jitter = 0.9
+	}
+
+	return &Scheduler{
+		logger:         logger.Session("healthcheck-scheduler"),
+		workPool:       workPool,
+		size:           size,
+		jitter:         jitter,
+		metronClient:   metronClient,
+		reportInterval: reportInterval,
+		clock:          clock,
+		random:         rand.New(rand.NewSource(clock.Now().UnixNano())),
+	}
+}
+
+func (s *Scheduler) Submit(work func()) {
+	queuedAt := s.clock.Now()
+
+	s.lock.Lock()
+	s.queued++
+	s.lock.Unlock()
+
+	s.workPool.Submit(func() {
+		wait := s.clock.Since(queuedAt)
+
+		s.lock.Lock()
+		s.queued--
+		if wait > s.maxQueueWait {
+			s.maxQueueWait = wait
+		}
+		s.lock.Unlock()
+
+		work()
+	})
+}
+
+// Interval stretches base by the number of checks waiting for a worker
+// relative to the size of the work pool, and then applies the jitter.
+func (s *Scheduler) Interval(base time.Duration) time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stretch := 1 + float64(s.queued)/float64(s.size)
+	if stretch > MaxStretch {
+		stretch = MaxStretch
+	}
+
+	offset := s.jitter * (2*s.random.Float64() - 1)
+	return time.Duration(float64(base) * stretch * (1 + offset))
+}
+
+func (s *Scheduler) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+
+	timer := s.clock.NewTimer(s.reportInterval)
+
+	for {
+		select {
+		case <-signals:
+			s.logger.Info("signalled")
+			return nil
+
+		case <-timer.C():
+			s.lock.Lock()
+			queued := s.queued
+			maxQueueWait := s.maxQueueWait
+			s.maxQueueWait = 0
+			s.lock.Unlock()
+
+			err := s.metronClient.SendMetric(HealthCheckQueueDepth, queued)
+			if err != nil {
+				s.logger.Error("failed-to-send-queue-depth-metric", err)
+			}
+			err = s.metronClient.SendDuration(HealthCheckQueueWaitTime, maxQueueWait)
+			if err != nil {
+				s.logger.Error("failed-to-send-queue-wait-time-metric", err)
+			}
+
+			timer.Reset(s.reportInterval)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package healthcheckscheduler_test
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/executor/depot/healthcheckscheduler"
+	"code.cloudfoundry.org/lager/lagertest"
+	"code.cloudfoundry.org/workpool"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("Scheduler", func() {
+	var (
+		fakeClock        *fakeclock.FakeClock
+		fakeMetronClient *mfakes.FakeIngressClient
+		workPool         *workpool.WorkPool
+		jitter           float64
+		scheduler        *healthcheckscheduler.Scheduler
+		release          chan struct{}
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeMetronClient = new(mfakes.FakeIngressClient)
+		jitter = 0
+		release = make(chan struct{})
+
+		var err error
+		workPool, err = workpool.NewWorkPool(1)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		scheduler = healthcheckscheduler.New(
+			lagertest.NewTestLogger("test"),
+			workPool,
+			1,
+			jitter,
+			fakeMetronClient,
+			time.Minute,
+			fakeClock,
+		)
+	})
+
+	AfterEach(func() {
+		close(release)
+		workPool.Stop()
+	})
+
+	block := func() {
+		started := make(chan struct{})
+		scheduler.Submit(func() {
+			close(started)
+			<-release
+		})
+		Eventually(started).Should(BeClosed())
+	}
+
+	Describe("Interval", func() {
+		It("returns the base interval while the work pool keeps up", func() {
+			Expect(scheduler.Interval(time.Second)).To(Equal(time.Second))
+		})
+
+		It("stretches the interval with the number of queued checks", func() {
+			block()
+			go scheduler.Submit(func() {})
+			go scheduler.Submit(func() {})
+			Eventually(func() time.Duration { return scheduler.Interval(time.Second) }).Should(Equal(3 * time.Second))
+		})
+
+		It("stretches the interval by at most MaxStretch", func() {
+			block()
+			for i := 0; i < 10; i++ {
+				go scheduler.Submit(func() {})
+			}
+			Eventually(func() time.Duration { return scheduler.Interval(time.Second) }).Should(Equal(healthcheckscheduler.MaxStretch * time.Second))
+		})
+
+		Context("with jitter", func() {
+			BeforeEach(func() {
+				jitter = 0.5
+			})
+
+			It("spreads the intervals around the base interval", func() {
+				intervals := map[time.Duration]struct{}{}
+				for i := 0; i < 100; i++ {
+					interval := scheduler.Interval(time.Second)
+					Expect(interval).To(BeNumerically(">=", 500*time.Millisecond))
+					Expect(interval).To(BeNumerically("<=", 1500*time.Millisecond))
+					intervals[interval] = struct{}{}
+				}
+				Expect(len(intervals)).To(BeNumerically(">", 1))
+			})
+		})
+	})
+
+	Describe("Run", func() {
+		var process ifrit.Process
+
+		JustBeforeEach(func() {
+			process = ifrit.Invoke(scheduler)
+		})
+
+		AfterEach(func() {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive())
+		})
+
+		It("reports the queue depth and the longest queue wait since the last report", func() {
+			block()
+			ran := make(chan struct{})
+			go scheduler.Submit(func() { close(ran) })
+			Eventually(func() time.Duration { return scheduler.Interval(time.Second) }).Should(Equal(2 * time.Second))
+
+			fakeClock.WaitForWatcherAndIncrement(time.Minute)
+			Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(1))
+			name, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+			Expect(name).To(Equal(healthcheckscheduler.HealthCheckQueueDepth))
+			Expect(value).To(Equal(1))
+			name, wait, _ := fakeMetronClient.SendDurationArgsForCall(0)
+			Expect(name).To(Equal(healthcheckscheduler.HealthCheckQueueWaitTime))
+			Expect(wait).To(BeZero())
+
+			release <- struct{}{}
+			Eventually(ran).Should(BeClosed())
+
+			fakeClock.WaitForWatcherAndIncrement(time.Minute)
+			Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(2))
+			_, value, _ = fakeMetronClient.SendMetricArgsForCall(1)
+			Expect(value).To(Equal(0))
+			_, wait, _ = fakeMetronClient.SendDurationArgsForCall(1)
+			Expect(wait).To(Equal(time.Minute))
+
+			fakeClock.WaitForWatcherAndIncrement(time.Minute)
+			Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(3))
+			_, wait, _ = fakeMetronClient.SendDurationArgsForCall(2)
+			Expect(wait).To(BeZero())
+		})
+	})
+})
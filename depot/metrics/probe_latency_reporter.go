@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	loggregator "code.cloudfoundry.org/go-loggregator/v8"
+	"code.cloudfoundry.org/lager"
+)
+
+const (
+	healthCheckProbeLatencyBucketMetric = "HealthCheckProbeLatencyBucket"
+	healthCheckProbeCountMetric         = "HealthCheckProbeCount"
+
+	probeTag       = "probe"
+	upperBoundTag  = "le"
+	infiniteBucket = "+Inf"
+)
+
+// ProbeLatencyBuckets are the upper bounds of the probe latency histogram.
+var ProbeLatencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// ProbeLatencyReporter aggregates the latency of the health check probes of
+// all containers on the cell into a histogram per kind of probe, and emits it
+// every interval. Bucket counts are cumulative and cover only the probes
+// observed since the previous report. Observe is safe to call on a nil
+// *ProbeLatencyReporter.
+type ProbeLatencyReporter struct {
+	Interval     time.Duration
+	Clock        clock.Clock
+	Logger       lager.Logger
+	MetronClient loggingclient.IngressClient
+	Tags         map[string]string
+
+	lock   sync.Mutex
+	counts map[string][]int
+}
+
+func (reporter *ProbeLatencyReporter) Observe(probe string, latency time.Duration) {
+	if reporter == nil {
+		return
+	}
+
+	reporter.lock.Lock()
+	defer reporter.lock.Unlock()
+
+	if reporter.counts == nil {
+		reporter.counts = map[string][]int{}
+	}
+	counts, ok := reporter.counts[probe]
+	if !ok {
+		counts = make([]int, len(ProbeLatencyBuckets)+1)
+		reporter.counts[probe] = counts
+	}
+
+	bucket := len(ProbeLatencyBuckets)
+	for i, upperBound := range ProbeLatencyBuckets {
+		if latency <= upperBound {
+			bucket = i
+			break
+		}
+	}
+	counts[bucket]++
+}
+
+func (reporter *ProbeLatencyReporter) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	logger := reporter.Logger.Session("probe-latency-reporter")
+
+	close(ready)
+
+	timer := reporter.Clock.NewTimer(reporter.Interval)
+
+	for {
+		select {
+		case <-signals:
+			logger.Info("signalled")
+			return nil
+
+		case <-timer.C():
+			reporter.lock.Lock()
+			counts := reporter.counts
+			reporter.counts = nil
+			reporter.lock.Unlock()
+
+			for probe, probeCounts := range counts {
+				reporter.sendHistogram(logger, probe, probeCounts)
+			}
+
+			timer.Reset(reporter.Interval)
+		}
+	}
+}
+
+func (reporter *ProbeLatencyReporter) sendHistogram(logger lager.Logger, probe string, counts []int) {
+	total := 0
+	for i, count := range counts {
+		total += count
+
+		upperBound := infiniteBucket
+		if i < len(ProbeLatencyBuckets) {
+			upperBound = ProbeLatencyBuckets[i].String()
+		}
+
+		err := reporter.MetronClient.SendMetric(
+			healthCheckProbeLatencyBucketMetric,
+			total,
+			loggregator.WithEnvelopeTags(reporter.tags(probe, upperBound)),
+		)
+		if err != nil {
+			logger.Error("failed-to-send-probe-latency-bucket-metric", err, lager.Data{"probe": probe, "le": upperBound})
+		}
+	}
+
+	err := reporter.MetronClient.SendMetric(healthCheckProbeCountMetric, total, loggregator.WithEnvelopeTags(reporter.tags(probe, "")))
+	if err != nil {
+		logger.Error("failed-to-send-probe-count-metric", err, lager.Data{"probe": probe})
+	}
+}
+
+func (reporter *ProbeLatencyReporter) tags(probe, upperBound string) map[string]string {
+	tags := map[string]string{probeTag: probe}
+	if upperBound != "" {
+		tags[upperBoundTag] = upperBound
+	}
+	for k, v := range reporter.Tags {
+		tags[k] = v
+	}
+	return tags
+}
@@ -0,0 +1,93 @@
+package metrics_test
+
+import (
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/executor/depot/metrics"
+	"code.cloudfoundry.org/go-loggregator/v8/rpc/loggregator_v2"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("ProbeLatencyReporter", func() {
+	var (
+		fakeClock        *fakeclock.FakeClock
+		fakeMetronClient *mfakes.FakeIngressClient
+		reporter         *metrics.ProbeLatencyReporter
+		process          ifrit.Process
+	)
+
+	sentMetrics := func() map[string]int {
+		sent := map[string]int{}
+		for i := 0; i < fakeMetronClient.SendMetricCallCount(); i++ {
+			name, value, opts := fakeMetronClient.SendMetricArgsForCall(i)
+			e := &loggregator_v2.Envelope{Tags: map[string]string{}}
+			for _, opt := range opts {
+				opt(e)
+			}
+			Expect(e.Tags).To(HaveKeyWithValue("zone", "z1"))
+			sent[name+" "+e.Tags["probe"]+" "+e.Tags["le"]] = value
+		}
+		return sent
+	}
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeMetronClient = new(mfakes.FakeIngressClient)
+		reporter = &metrics.ProbeLatencyReporter{
+			Interval:     time.Minute,
+			Clock:        fakeClock,
+			Logger:       lagertest.NewTestLogger("test"),
+			MetronClient: fakeMetronClient,
+			Tags:         map[string]string{"zone": "z1"},
+		}
+		process = ifrit.Invoke(reporter)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	It("reports a cumulative histogram per kind of probe", func() {
+		reporter.Observe("readiness", 5*time.Millisecond)
+		reporter.Observe("readiness", 200*time.Millisecond)
+		reporter.Observe("readiness", time.Minute)
+		reporter.Observe("liveness", 50*time.Millisecond)
+
+		fakeClock.WaitForWatcherAndIncrement(time.Minute)
+		bucketsPerProbe := len(metrics.ProbeLatencyBuckets) + 2
+		Eventually(fakeMetronClient.SendMetricCallCount).Should(Equal(2 * bucketsPerProbe))
+
+		sent := sentMetrics()
+		Expect(sent).To(HaveKeyWithValue("HealthCheckProbeLatencyBucket readiness 10ms", 1))
+		Expect(sent).To(HaveKeyWithValue("HealthCheckProbeLatencyBucket readiness 100ms", 1))
+		Expect(sent).To(HaveKeyWithValue("HealthCheckProbeLatencyBucket readiness 250ms", 2))
+		Expect(sent).To(HaveKeyWithValue("HealthCheckProbeLatencyBucket readiness 10s", 2))
+		Expect(sent).To(HaveKeyWithValue("HealthCheckProbeLatencyBucket readiness +Inf", 3))
+		Expect(sent).To(HaveKeyWithValue("HealthCheckProbeCount readiness ", 3))
+		Expect(sent).To(HaveKeyWithValue("HealthCheckProbeLatencyBucket liveness 10ms", 0))
+		Expect(sent).To(HaveKeyWithValue("HealthCheckProbeLatencyBucket liveness 50ms", 1))
+		Expect(sent).To(HaveKeyWithValue("HealthCheckProbeCount liveness ", 1))
+	})
+
+	It("only reports the probes observed since the last report", func() {
+		reporter.Observe("readiness", 5*time.Millisecond)
+		fakeClock.WaitForWatcherAndIncrement(time.Minute)
+		Eventually(fakeMetronClient.SendMetricCallCount).Should(Equal(len(metrics.ProbeLatencyBuckets) + 2))
+
+		fakeClock.WaitForWatcherAndIncrement(time.Minute)
+		Consistently(fakeMetronClient.SendMetricCallCount).Should(Equal(len(metrics.ProbeLatencyBuckets) + 2))
+	})
+
+	It("ignores observations on a nil reporter", func() {
+		var nilReporter *metrics.ProbeLatencyReporter
+		Expect(func() { nilReporter.Observe("readiness", time.Second) }).NotTo(Panic())
+	})
+})
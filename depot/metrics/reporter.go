@@ -28,11 +28,18 @@ const (
 
 	containerCount         = "ContainerCount"
 	startingContainerCount = "StartingContainerCount"
+
+	tenantAllocatedMemoryMetric = "TenantAllocatedMemory"
+	tenantAllocatedDiskMetric   = "TenantAllocatedDisk"
+	tenantContainerCount        = "TenantContainerCount"
+
+	tenantTag = "tenant"
 )
 
 type ExecutorSource interface {
 	GetBulkMetrics(logger lager.Logger) (map[string]executor.Metrics, error)
 	RemainingResources(lager.Logger) (executor.ExecutorResources, error)
+	TenantResources(lager.Logger) (map[string]executor.TenantResources, error)
 	TotalResources(lager.Logger) (executor.ExecutorResources, error)
 	ListContainers(lager.Logger) ([]executor.Container, error)
 }
@@ -166,11 +173,40 @@ func (reporter *Reporter) Run(signals <-chan os.Signal, ready chan<- struct{}) e
 				logger.Error("failed-to-send-starting-container-count-metric", err)
 			}
 
+			tenants, err := reporter.ExecutorSource.TenantResources(logger)
+			if err != nil {
+				logger.Error("failed-tenant-resources", err)
+			}
+			for tenant, resources := range tenants {
+				reporter.sendTenantMetrics(logger, tenant, resources.Used)
+			}
+
 			timer.Reset(reporter.Interval)
 		}
 	}
 }
 
+func (reporter *Reporter) sendTenantMetrics(logger lager.Logger, tenant string, used executor.ExecutorResources) {
+	tags := map[string]string{tenantTag: tenant}
+	for k, v := range reporter.Tags {
+		tags[k] = v
+	}
+	tagOption := loggregator.WithEnvelopeTags(tags)
+
+	err := reporter.MetronClient.SendMebiBytes(tenantAllocatedMemoryMetric, used.MemoryMB, tagOption)
+	if err != nil {
+		logger.Error("failed-to-send-tenant-allocated-memory-metric", err, lager.Data{"tenant": tenant})
+	}
+	err = reporter.MetronClient.SendMebiBytes(tenantAllocatedDiskMetric, used.DiskMB, tagOption)
+	if err != nil {
+		logger.Error("failed-to-send-tenant-allocated-disk-metric", err, lager.Data{"tenant": tenant})
+	}
+	err = reporter.MetronClient.SendMetric(tenantContainerCount, used.Containers, tagOption)
+	if err != nil {
+		logger.Error("failed-to-send-tenant-container-count-metric", err, lager.Data{"tenant": tenant})
+	}
+}
+
 func containerIsStarting(container executor.Container) bool {
 	return container.State == executor.StateReserved ||
 		container.State == executor.StateInitializing ||
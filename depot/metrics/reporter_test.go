@@ -217,6 +217,29 @@ var _ = Describe("Reporter", func() {
 		m.RUnlock()
 	})
 
+	Context("when the cell tracks tenant quotas", func() {
+		BeforeEach(func() {
+			executorClient.TenantResourcesReturns(map[string]executor.TenantResources{
+				"tenant-a": {
+					Quota: executor.NewExecutorResources(512, 1024, 10),
+					Used:  executor.NewExecutorResources(256, 512, 2),
+				},
+			}, nil)
+		})
+
+		It("reports the allocated resources of each tenant", func() {
+			Eventually(fakeMetronClient.SendMebiBytesCallCount).Should(Equal(10))
+			Eventually(fakeMetronClient.SendMetricCallCount).Should(Equal(5))
+
+			m.RLock()
+			expectedTags := map[string]string{"foo": "bar", "tenant": "tenant-a"}
+			Expect(metricMap["TenantAllocatedMemory"]).To(Equal(metricEnvelope{value: 256, tags: expectedTags}))
+			Expect(metricMap["TenantAllocatedDisk"]).To(Equal(metricEnvelope{value: 512, tags: expectedTags}))
+			Expect(metricMap["TenantContainerCount"]).To(Equal(metricEnvelope{value: 2, tags: expectedTags}))
+			m.RUnlock()
+		})
+	})
+
 	Context("when getting remaining resources fails", func() {
 		BeforeEach(func() {
 			executorClient.RemainingResourcesReturns(executor.ExecutorResources{}, errors.New("oh no!"))
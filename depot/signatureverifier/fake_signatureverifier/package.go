@@ -0,0 +1 @@
+package fake_signatureverifier // import "code.cloudfoundry.org/executor/depot/signatureverifier/fake_signatureverifier"
@@ -0,0 +1 @@
+package signatureverifier // import "code.cloudfoundry.org/executor/depot/signatureverifier"
@@ -0,0 +1,256 @@
+package signatureverifier
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/cacheddownloader"
+	"code.cloudfoundry.org/lager"
+)
+
+// SignatureSuffix is appended to the path of an artifact URL to locate its
+// detached signature.
+const SignatureSuffix = ".sig"
+
+const maxSignatureSize = 4096
+
+// verificationError is a failed verification that retrying does not fix.
+type verificationError string
+
+func (e verificationError) Error() string {
+	return string(e)
+}
+
+func (e verificationError) IsRetryable() bool {
+	return false
+}
+
+var (
+	ErrChecksumRequired   error = verificationError("a sha256 checksum is required to verify the signature")
+	ErrSignatureNotFound  error = verificationError("signature not found")
+	ErrSignatureInvalid   error = verificationError("signature does not match any trusted key")
+	ErrVerifyCancelled    error = errors.New("signature verification cancelled")
+	ErrUnsupportedKeyType error = errors.New("unsupported public key type, only ed25519 and ECDSA keys are supported")
+)
+
+//go:generate counterfeiter -o fake_signatureverifier/fake_signatureverifier.go . Verifier
+
+// Verifier checks the detached signature of an artifact against a set of
+// trusted public keys. Signatures are made over the SHA-256 digest of the
+// artifact, so the digest is taken from the checksum the artifact was fetched
+// and validated with. Artifacts are fetched under CacheKey, so that content
+// served from the cache was validated against the same checksum.
+type Verifier interface {
+	Verify(
+		logger lager.Logger,
+		url *url.URL,
+		checksum cacheddownloader.ChecksumInfoType,
+		cancel <-chan struct{},
+	) error
+}
+
+// CacheKey binds cacheKey to the SHA-256 checksum of an artifact. The
+// downloaders only cache content that matched the checksum it was fetched
+// with, so content served under the returned key has that digest, whether it
+// was downloaded or cached for an earlier request.
+func CacheKey(cacheKey string, checksum cacheddownloader.ChecksumInfoType) string {
+	if cacheKey == "" {
+		return ""
+	}
+	return cacheKey + "-sha256-" + strings.ToLower(checksum.Value)
+}
+
+type verifier struct {
+	keys       []crypto.PublicKey
+	httpClient *http.Client
+}
+
+// ParsePublicKeys parses PEM encoded PKIX public keys.
+func ParsePublicKeys(pemKeys []string) ([]crypto.PublicKey, error) {
+	keys := make([]crypto.PublicKey, 0, len(pemKeys))
+	for i, pemKey := range pemKeys {
+		block, _ := pem.Decode([]byte(pemKey))
+		if block == nil {
+			return nil, fmt.Errorf("public key %d: no PEM data found", i)
+		}
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("public key %d: %s", i, err)
+		}
+
+		switch key.(type) {
+		case ed25519.PublicKey, *ecdsa.PublicKey:
+		default:
+			return nil, fmt.Errorf("public key %d: %s", i, ErrUnsupportedKeyType)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func New(keys []crypto.PublicKey, timeout time.Duration, tlsConfig *tls.Config) Verifier {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		Dial: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).Dial,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     tlsConfig,
+	}
+
+	return &verifier{
+		keys: keys,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+		},
+	}
+}
+
+func (v *verifier) Verify(
+	logger lager.Logger,
+	url *url.URL,
+	checksum cacheddownloader.ChecksumInfoType,
+	cancel <-chan struct{},
+) error {
+	logger = logger.Session("verify-signature", lager.Data{"host": url.Host})
+
+	if strings.ToLower(checksum.Algorithm) != "sha256" {
+		logger.Info("missing-checksum", lager.Data{"algorithm": checksum.Algorithm})
+		return ErrChecksumRequired
+	}
+
+	digest, err := hex.DecodeString(checksum.Value)
+	if err != nil || len(digest) != 32 {
+		logger.Info("invalid-checksum")
+		return ErrChecksumRequired
+	}
+
+	signature, err := v.fetchSignature(url, cancel)
+	if err != nil {
+		logger.Info("fetching-signature-failed", lager.Data{"error": err.Error()})
+		return err
+	}
+
+	for _, key := range v.keys {
+		if verifyDigest(key, digest, signature) {
+			logger.Info("succeeded")
+			return nil
+		}
+	}
+
+	logger.Info("signature-invalid")
+	return ErrSignatureInvalid
+}
+
+func (v *verifier) fetchSignature(artifactURL *url.URL, cancel <-chan struct{}) ([]byte, error) {
+	signatureURL := *artifactURL
+	signatureURL.Path += SignatureSuffix
+	if signatureURL.RawPath != "" {
+		signatureURL.RawPath += SignatureSuffix
+	}
+
+	req, err := http.NewRequest("GET", signatureURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	go func() {
+		select {
+		case <-cancel:
+			cancelCtx()
+		case <-ctx.Done():
+		}
+	}()
+	req = req.WithContext(ctx)
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		if isCancelled(cancel) {
+			return nil, ErrVerifyCancelled
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrSignatureNotFound
+	default:
+		return nil, fmt.Errorf("Fetching signature failed: Status code %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSignatureSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxSignatureSize {
+		return nil, ErrSignatureInvalid
+	}
+
+	// signatures are usually distributed base64 encoded, but raw signatures
+	// are accepted as well
+	trimmed := bytes.TrimSpace(body)
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(trimmed)))
+	n, err := base64.StdEncoding.Decode(decoded, trimmed)
+	if err == nil {
+		return decoded[:n], nil
+	}
+
+	return body, nil
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+func verifyDigest(key crypto.PublicKey, digest, signature []byte) bool {
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		return len(signature) == ed25519.SignatureSize && ed25519.Verify(k, digest, signature)
+	case *ecdsa.PublicKey:
+		var sig ecdsaSignature
+		rest, err := asn1.Unmarshal(signature, &sig)
+		if err != nil || len(rest) != 0 || sig.R == nil || sig.S == nil {
+			return false
+		}
+		return ecdsa.Verify(k, digest, sig.R, sig.S)
+	default:
+		return false
+	}
+}
+
+func isCancelled(cancel <-chan struct{}) bool {
+	select {
+	case <-cancel:
+		return true
+	default:
+		return false
+	}
+}
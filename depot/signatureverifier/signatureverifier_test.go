@@ -0,0 +1,180 @@
+package signatureverifier_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"code.cloudfoundry.org/cacheddownloader"
+	"code.cloudfoundry.org/executor/depot/signatureverifier"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func encodePublicKey(key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+var _ = Describe("SignatureVerifier", func() {
+	var (
+		logger      *lagertest.TestLogger
+		content     []byte
+		digest      [32]byte
+		checksum    cacheddownloader.ChecksumInfoType
+		signature   []byte
+		publicKey   crypto.PublicKey
+		server      *httptest.Server
+		artifactURL *url.URL
+		cancel      chan struct{}
+		requested   []string
+		verifier    signatureverifier.Verifier
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		content = []byte("droplet")
+		digest = sha256.Sum256(content)
+		checksum = cacheddownloader.ChecksumInfoType{Algorithm: "sha256", Value: hex.EncodeToString(digest[:])}
+		cancel = make(chan struct{})
+		requested = nil
+
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		publicKey = pub
+		signature = []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, digest[:])) + "\n")
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested = append(requested, r.URL.Path)
+			if signature == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(signature)
+		}))
+
+		artifactURL, err = url.Parse(server.URL + "/droplet")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		keys, err := signatureverifier.ParsePublicKeys([]string{encodePublicKey(publicKey)})
+		Expect(err).NotTo(HaveOccurred())
+		verifier = signatureverifier.New(keys, time.Minute, nil)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	verify := func() error {
+		return verifier.Verify(logger, artifactURL, checksum, cancel)
+	}
+
+	It("verifies the signature fetched alongside the artifact", func() {
+		Expect(verify()).To(Succeed())
+		Expect(requested).To(Equal([]string{"/droplet.sig"}))
+	})
+
+	Context("when the signature is made with an ECDSA key", func() {
+		BeforeEach(func() {
+			priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			publicKey = &priv.PublicKey
+
+			signature, err = priv.Sign(rand.Reader, digest[:], crypto.SHA256)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("verifies the raw signature", func() {
+			Expect(verify()).To(Succeed())
+		})
+	})
+
+	Context("when the signature was made by another key", func() {
+		BeforeEach(func() {
+			_, priv, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			signature = ed25519.Sign(priv, digest[:])
+		})
+
+		It("returns ErrSignatureInvalid", func() {
+			Expect(verify()).To(Equal(signatureverifier.ErrSignatureInvalid))
+		})
+	})
+
+	Context("when the checksum is for different content", func() {
+		BeforeEach(func() {
+			other := sha256.Sum256([]byte("injected droplet"))
+			checksum.Value = hex.EncodeToString(other[:])
+		})
+
+		It("returns ErrSignatureInvalid", func() {
+			Expect(verify()).To(Equal(signatureverifier.ErrSignatureInvalid))
+		})
+	})
+
+	Context("when there is no signature", func() {
+		BeforeEach(func() {
+			signature = nil
+		})
+
+		It("returns ErrSignatureNotFound", func() {
+			Expect(verify()).To(Equal(signatureverifier.ErrSignatureNotFound))
+		})
+	})
+
+	Context("when the artifact has no sha256 checksum", func() {
+		BeforeEach(func() {
+			checksum = cacheddownloader.ChecksumInfoType{Algorithm: "md5", Value: "deadbeef"}
+		})
+
+		It("returns ErrChecksumRequired without fetching the signature", func() {
+			Expect(verify()).To(Equal(signatureverifier.ErrChecksumRequired))
+			Expect(requested).To(BeEmpty())
+		})
+	})
+
+	Describe("ParsePublicKeys", func() {
+		It("rejects keys that are not PEM encoded", func() {
+			_, err := signatureverifier.ParsePublicKeys([]string{"not a key"})
+			Expect(err).To(MatchError("public key 0: no PEM data found"))
+		})
+
+		It("rejects unsupported key types", func() {
+			priv, err := rsa.GenerateKey(rand.Reader, 1024)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = signatureverifier.ParsePublicKeys([]string{encodePublicKey(&priv.PublicKey)})
+			Expect(err).To(MatchError(ContainSubstring(signatureverifier.ErrUnsupportedKeyType.Error())))
+		})
+	})
+
+	Describe("CacheKey", func() {
+		It("differs between checksums of the same cache key", func() {
+			key1 := signatureverifier.CacheKey("droplet", cacheddownloader.ChecksumInfoType{Algorithm: "sha256", Value: "AAAA"})
+			key2 := signatureverifier.CacheKey("droplet", cacheddownloader.ChecksumInfoType{Algorithm: "sha256", Value: "bbbb"})
+			Expect(key1).NotTo(Equal(key2))
+			Expect(key1).To(Equal(signatureverifier.CacheKey("droplet", cacheddownloader.ChecksumInfoType{Algorithm: "sha256", Value: "aaaa"})))
+		})
+
+		It("leaves an empty cache key empty", func() {
+			Expect(signatureverifier.CacheKey("", cacheddownloader.ChecksumInfoType{Algorithm: "sha256", Value: "aaaa"})).To(BeEmpty())
+		})
+	})
+})
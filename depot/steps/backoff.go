@@ -0,0 +1,77 @@
+package steps
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager"
+	"github.com/tedsuo/ifrit"
+)
+
+// backoffLoop runs the attempts of the steps that re-run their substep after
+// a backoff. The ready channel is closed the first time an attempt becomes
+// ready, and signals are forwarded to the running attempt.
+type backoffLoop struct {
+	signals <-chan os.Signal
+	ready   chan<- struct{}
+	clock   clock.Clock
+	logger  lager.Logger
+}
+
+func newBackoffLoop(signals <-chan os.Signal, ready chan<- struct{}, clock clock.Clock, logger lager.Logger) *backoffLoop {
+	return &backoffLoop{
+		signals: signals,
+		ready:   ready,
+		clock:   clock,
+		logger:  logger,
+	}
+}
+
+// run returns true if the attempt exited because it was signalled.
+func (l *backoffLoop) run(attempt ifrit.Runner) (bool, error) {
+	process := ifrit.Background(attempt)
+	processReady := process.Ready()
+
+	for {
+		select {
+		case <-processReady:
+			processReady = nil
+			if l.ready != nil {
+				close(l.ready)
+				l.ready = nil
+			}
+		case err := <-process.Wait():
+			return false, err
+		case s := <-l.signals:
+			process.Signal(s)
+			return true, <-process.Wait()
+		}
+	}
+}
+
+// wait returns false if the loop was signalled before the backoff elapsed.
+func (l *backoffLoop) wait(backoff time.Duration) bool {
+	timer := l.clock.NewTimer(backoff)
+	select {
+	case <-timer.C():
+		return true
+	case <-l.signals:
+		timer.Stop()
+		l.logger.Info("cancelled-during-backoff")
+		return false
+	}
+}
+
+// exponentialBackoff doubles the initial backoff for every attempt after the
+// first, up to max.
+func exponentialBackoff(initial, max time.Duration, attempt int) time.Duration {
+	backoff := initial
+	for i := 1; i < attempt && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		return max
+	}
+	return backoff
+}
@@ -9,22 +9,38 @@ import (
 )
 
 type consistentlySucceedsStep struct {
-	create    func() ifrit.Runner
-	clock     clock.Clock
-	frequency time.Duration
+	create           func() ifrit.Runner
+	clock            clock.Clock
+	frequency        time.Duration
+	failureThreshold int
+	schedule         Schedule
 }
 
 // TODO: use a workpool when running the substep
 func NewConsistentlySucceedsStep(create func() ifrit.Runner, frequency time.Duration, clock clock.Clock) ifrit.Runner {
+	return NewConsistentlySucceedsStepWithThreshold(create, frequency, 1, nil, clock)
+}
+
+// NewConsistentlySucceedsStepWithThreshold only fails once failureThreshold
+// substeps in a row have failed, with the error of the last one. A nil
+// schedule runs the substep every frequency.
+func NewConsistentlySucceedsStepWithThreshold(create func() ifrit.Runner, frequency time.Duration, failureThreshold int, schedule Schedule, clock clock.Clock) ifrit.Runner {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+
 	return &consistentlySucceedsStep{
-		create:    create,
-		frequency: frequency,
-		clock:     clock,
+		create:           create,
+		frequency:        frequency,
+		failureThreshold: failureThreshold,
+		schedule:         schedule,
+		clock:            clock,
 	}
 }
 
 func (step *consistentlySucceedsStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
-	t := step.clock.NewTimer(step.frequency)
+	failures := 0
+	t := step.clock.NewTimer(nextInterval(step.schedule, step.frequency))
 
 	close(ready)
 
@@ -41,7 +57,13 @@ func (step *consistentlySucceedsStep) Run(signals <-chan os.Signal, ready chan<-
 
 		select {
 		case err := <-process.Wait():
-			if err != nil {
+			if err == nil {
+				failures = 0
+				break
+			}
+
+			failures++
+			if failures >= step.failureThreshold {
 				return err
 			}
 		case s := <-signals:
@@ -49,6 +71,6 @@ func (step *consistentlySucceedsStep) Run(signals <-chan os.Signal, ready chan<-
 			return <-process.Wait()
 		}
 
-		t.Reset(step.frequency)
+		t.Reset(nextInterval(step.schedule, step.frequency))
 	}
 }
@@ -106,3 +106,61 @@ var _ = Describe("ConsistentlySucceedsStep", func() {
 		})
 	})
 })
+
+var _ = Describe("ConsistentlySucceedsStepWithThreshold", func() {
+	var (
+		process    ifrit.Process
+		fakeRunner *fake_runner.TestRunner
+		fakeClock  *fakeclock.FakeClock
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeRunner = fake_runner.NewTestRunner()
+
+		step := steps.NewConsistentlySucceedsStepWithThreshold(func() ifrit.Runner { return fakeRunner }, time.Second, 3, nil, fakeClock)
+		process = ifrit.Background(step)
+	})
+
+	probe := func(err error) {
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		fakeRunner.TriggerExit(err)
+	}
+
+	It("fails once the threshold of consecutive failures is reached", func() {
+		probe(errors.New("first"))
+		probe(errors.New("second"))
+		Consistently(process.Wait()).ShouldNot(Receive())
+		probe(errors.New("third"))
+		Eventually(process.Wait()).Should(Receive(MatchError("third")))
+	})
+
+	It("starts counting again after a success", func() {
+		probe(errors.New("first"))
+		probe(errors.New("second"))
+		probe(nil)
+		probe(errors.New("third"))
+		probe(errors.New("fourth"))
+		Consistently(process.Wait()).ShouldNot(Receive())
+		probe(errors.New("fifth"))
+		Eventually(process.Wait()).Should(Receive(MatchError("fifth")))
+	})
+})
+
+var _ = Describe("ConsistentlySucceedsStepWithThreshold with a schedule", func() {
+	It("waits for the interval of the schedule between substeps", func() {
+		fakeClock := fakeclock.NewFakeClock(time.Now())
+		fakeRunner := fake_runner.NewTestRunner()
+
+		step := steps.NewConsistentlySucceedsStepWithThreshold(func() ifrit.Runner { return fakeRunner }, time.Second, 1, doublingSchedule{}, fakeClock)
+		process := ifrit.Background(step)
+
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Consistently(fakeRunner.RunCallCount).Should(BeZero())
+		fakeClock.Increment(time.Second)
+		Eventually(fakeRunner.RunCallCount).Should(Equal(1))
+
+		fakeRunner.TriggerExit(errors.New("BOOOOM"))
+		Eventually(process.Wait()).Should(Receive(MatchError("BOOOOM")))
+	})
+})
@@ -5,23 +5,34 @@ import (
 	"io"
 	"net/url"
 	"os"
+	"time"
 
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/bytefmt"
 	"code.cloudfoundry.org/cacheddownloader"
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/executor/depot/chunkeddownloader"
 	"code.cloudfoundry.org/executor/depot/log_streamer"
+	"code.cloudfoundry.org/executor/depot/signatureverifier"
 	"code.cloudfoundry.org/garden"
 	"code.cloudfoundry.org/lager"
 	"github.com/tedsuo/ifrit"
 )
 
+// DownloadProgressInterval is the minimum interval between two progress
+// reports of a chunked download
+const DownloadProgressInterval = 5 * time.Second
+
 type downloadStep struct {
-	container        garden.Container
-	model            models.DownloadAction
-	cachedDownloader cacheddownloader.CachedDownloader
-	streamer         log_streamer.LogStreamer
-	rateLimiter      chan struct{}
-	cancelDownload   chan struct{}
+	container         garden.Container
+	model             models.DownloadAction
+	cachedDownloader  cacheddownloader.CachedDownloader
+	chunkedDownloader chunkeddownloader.ChunkedDownloader
+	verifier          signatureverifier.Verifier
+	streamer          log_streamer.LogStreamer
+	rateLimiter       chan struct{}
+	cancelDownload    chan struct{}
+	clock             clock.Clock
 
 	logger lager.Logger
 }
@@ -32,6 +43,35 @@ func NewDownload(
 	cachedDownloader cacheddownloader.CachedDownloader,
 	rateLimiter chan struct{},
 	streamer log_streamer.LogStreamer,
+	clock clock.Clock,
+	logger lager.Logger,
+) ifrit.Runner {
+	return NewChunkedDownload(
+		container,
+		model,
+		cachedDownloader,
+		nil,
+		nil,
+		rateLimiter,
+		streamer,
+		clock,
+		logger,
+	)
+}
+
+// NewChunkedDownload downloads with chunkedDownloader when the server
+// supports range requests, and with cachedDownloader otherwise. Both cache
+// the artifact by its cache key. When verifier is not nil the signature of
+// the artifact is verified before anything is streamed into the container.
+func NewChunkedDownload(
+	container garden.Container,
+	model models.DownloadAction,
+	cachedDownloader cacheddownloader.CachedDownloader,
+	chunkedDownloader chunkeddownloader.ChunkedDownloader,
+	verifier signatureverifier.Verifier,
+	rateLimiter chan struct{},
+	streamer log_streamer.LogStreamer,
+	clock clock.Clock,
 	logger lager.Logger,
 ) ifrit.Runner {
 	logger = logger.Session("download-step", lager.Data{
@@ -41,13 +81,16 @@ func NewDownload(
 	})
 
 	return &downloadStep{
-		container:        container,
-		model:            model,
-		cachedDownloader: cachedDownloader,
-		streamer:         streamer,
-		rateLimiter:      rateLimiter,
-		logger:           logger,
-		cancelDownload:   make(chan struct{}),
+		container:         container,
+		model:             model,
+		cachedDownloader:  cachedDownloader,
+		chunkedDownloader: chunkedDownloader,
+		verifier:          verifier,
+		streamer:          streamer,
+		rateLimiter:       rateLimiter,
+		clock:             clock,
+		logger:            logger,
+		cancelDownload:    make(chan struct{}),
 	}
 }
 
@@ -95,6 +138,21 @@ func (step *downloadStep) perform() error {
 		return NewEmittableError(err, errString)
 	}
 
+	err = step.verify()
+	if err != nil {
+		downloadedFile.Close()
+
+		var errString string
+		if err == signatureverifier.ErrSignatureNotFound {
+			errString = step.describe("Signature of %s not found", "Signature not found")
+		} else {
+			errString = step.describe("Verifying signature of %s failed", "Verifying signature failed")
+		}
+
+		step.emitError(fmt.Sprintf("%s\n", errString))
+		return NewEmittableError(err, errString)
+	}
+
 	err = step.streamIn(step.model.To, downloadedFile)
 	if err != nil {
 		var errString string
@@ -116,6 +174,36 @@ func (step *downloadStep) perform() error {
 	return nil
 }
 
+// verify checks the signature once the artifact is fetched, against the
+// checksum the fetched content was validated with.
+func (step *downloadStep) verify() error {
+	if step.verifier == nil {
+		return nil
+	}
+
+	url, err := url.ParseRequestURI(step.model.From)
+	if err != nil {
+		step.logger.Error("parse-request-uri-error", err)
+		return err
+	}
+
+	err = step.verifier.Verify(
+		step.logger.Session("verifier"),
+		url,
+		cacheddownloader.ChecksumInfoType{
+			Algorithm: step.model.GetChecksumAlgorithm(),
+			Value:     step.model.GetChecksumValue(),
+		},
+		step.cancelDownload,
+	)
+	if err != nil {
+		step.logger.Error("verify-failed", err)
+		return err
+	}
+
+	return nil
+}
+
 func (step *downloadStep) fetch() (io.ReadCloser, int64, error) {
 	step.logger.Info("fetch-starting")
 	url, err := url.ParseRequestURI(step.model.From)
@@ -124,14 +212,29 @@ func (step *downloadStep) fetch() (io.ReadCloser, int64, error) {
 		return nil, 0, err
 	}
 
+	checksum := cacheddownloader.ChecksumInfoType{
+		Algorithm: step.model.GetChecksumAlgorithm(),
+		Value:     step.model.GetChecksumValue(),
+	}
+
+	cacheKey := step.model.CacheKey
+	if step.verifier != nil {
+		cacheKey = signatureverifier.CacheKey(cacheKey, checksum)
+	}
+
+	if step.chunkedDownloader != nil {
+		tarStream, downloadedSize, err := step.fetchChunked(url, cacheKey, checksum)
+		if err != chunkeddownloader.ErrRangesNotSupported {
+			return tarStream, downloadedSize, err
+		}
+		step.logger.Info("falling-back-to-cached-downloader")
+	}
+
 	tarStream, downloadedSize, err := step.cachedDownloader.Fetch(
 		step.logger.Session("downloader"),
 		url,
-		step.model.CacheKey,
-		cacheddownloader.ChecksumInfoType{
-			Algorithm: step.model.GetChecksumAlgorithm(),
-			Value:     step.model.GetChecksumValue(),
-		},
+		cacheKey,
+		checksum,
 		step.cancelDownload,
 	)
 	if err != nil {
@@ -143,6 +246,45 @@ func (step *downloadStep) fetch() (io.ReadCloser, int64, error) {
 	return tarStream, downloadedSize, nil
 }
 
+func (step *downloadStep) fetchChunked(url *url.URL, cacheKey string, checksum cacheddownloader.ChecksumInfoType) (io.ReadCloser, int64, error) {
+	tarStream, downloadedSize, err := step.chunkedDownloader.Fetch(
+		step.logger.Session("chunked-downloader"),
+		url,
+		cacheKey,
+		checksum,
+		step.progressReporter(),
+		step.cancelDownload,
+	)
+	if err != nil {
+		if err != chunkeddownloader.ErrRangesNotSupported {
+			step.logger.Error("chunked-fetch-failed", err)
+		}
+		return nil, 0, err
+	}
+
+	step.logger.Info("fetch-complete", lager.Data{"size": downloadedSize, "chunked": true})
+	return tarStream, downloadedSize, nil
+}
+
+func (step *downloadStep) progressReporter() chunkeddownloader.ProgressFunc {
+	var lastReport time.Time
+	return func(downloaded, total int64) {
+		now := step.clock.Now()
+		if downloaded < total && now.Sub(lastReport) < DownloadProgressInterval {
+			return
+		}
+		lastReport = now
+
+		step.emit(
+			"Downloading %s: %s of %s (%d%%)\n",
+			step.model.Artifact,
+			bytefmt.ByteSize(uint64(downloaded)),
+			bytefmt.ByteSize(uint64(total)),
+			downloaded*100/total,
+		)
+	}
+}
+
 func (step *downloadStep) streamIn(destination string, reader io.ReadCloser) error {
 	step.logger.Info("stream-in-starting")
 
@@ -161,6 +303,13 @@ func (step *downloadStep) streamIn(destination string, reader io.ReadCloser) err
 	return nil
 }
 
+func (step *downloadStep) describe(withArtifact, withoutArtifact string) string {
+	if step.model.Artifact != "" {
+		return fmt.Sprintf(withArtifact, step.model.Artifact)
+	}
+	return withoutArtifact
+}
+
 func (step *downloadStep) emit(format string, a ...interface{}) {
 	if step.model.Artifact != "" {
 		fmt.Fprintf(step.streamer.Stdout(), format, a...)
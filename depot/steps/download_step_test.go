@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"code.cloudfoundry.org/cacheddownloader"
 	cdfakes "code.cloudfoundry.org/cacheddownloader/cacheddownloaderfakes"
@@ -18,7 +19,12 @@ import (
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/garden"
 
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/executor/depot/chunkeddownloader"
+	"code.cloudfoundry.org/executor/depot/chunkeddownloader/fake_chunkeddownloader"
 	"code.cloudfoundry.org/executor/depot/log_streamer/fake_log_streamer"
+	"code.cloudfoundry.org/executor/depot/signatureverifier"
+	"code.cloudfoundry.org/executor/depot/signatureverifier/fake_signatureverifier"
 	"code.cloudfoundry.org/executor/depot/steps"
 	"code.cloudfoundry.org/executor/fakes"
 	. "github.com/onsi/ginkgo"
@@ -37,6 +43,7 @@ var _ = Describe("DownloadAction", func() {
 		cache          *cdfakes.FakeCachedDownloader
 		gardenClient   *fakes.FakeGardenClient
 		fakeStreamer   *fake_log_streamer.FakeLogStreamer
+		fakeClock      *fakeclock.FakeClock
 		logger         *lagertest.TestLogger
 		rateLimiter    chan struct{}
 	)
@@ -57,6 +64,7 @@ var _ = Describe("DownloadAction", func() {
 		gardenClient = fakes.NewGardenClient()
 
 		fakeStreamer = newFakeStreamer()
+		fakeClock = fakeclock.NewFakeClock(time.Now())
 		logger = lagertest.NewTestLogger("test")
 
 		rateLimiter = make(chan struct{}, 1)
@@ -79,6 +87,7 @@ var _ = Describe("DownloadAction", func() {
 				cache,
 				rateLimiter,
 				fakeStreamer,
+				fakeClock,
 				logger,
 			)
 
@@ -352,6 +361,7 @@ var _ = Describe("DownloadAction", func() {
 				cache,
 				rateLimiter,
 				fakeStreamer,
+				fakeClock,
 				logger,
 			)
 		})
@@ -386,6 +396,7 @@ var _ = Describe("DownloadAction", func() {
 				cache,
 				rateLimiter,
 				fakeStreamer,
+				fakeClock,
 				logger,
 			)
 		})
@@ -506,6 +517,7 @@ var _ = Describe("DownloadAction", func() {
 				cache,
 				rateLimiter,
 				fakeStreamer,
+				fakeClock,
 				logger,
 			)
 
@@ -520,6 +532,7 @@ var _ = Describe("DownloadAction", func() {
 				cache,
 				rateLimiter,
 				fakeStreamer,
+				fakeClock,
 				logger,
 			)
 
@@ -534,6 +547,7 @@ var _ = Describe("DownloadAction", func() {
 				cache,
 				rateLimiter,
 				fakeStreamer,
+				fakeClock,
 				logger,
 			)
 
@@ -563,6 +577,192 @@ var _ = Describe("DownloadAction", func() {
 	})
 })
 
+var _ = Describe("ChunkedDownloadAction", func() {
+	var (
+		downloadAction    models.DownloadAction
+		cache             *cdfakes.FakeCachedDownloader
+		chunkedDownloader *fake_chunkeddownloader.FakeChunkedDownloader
+		verifier          signatureverifier.Verifier
+		gardenClient      *fakes.FakeGardenClient
+		fakeStreamer      *fake_log_streamer.FakeLogStreamer
+		fakeClock         *fakeclock.FakeClock
+		logger            *lagertest.TestLogger
+		streamedIn        *bytes.Buffer
+		stepErr           error
+	)
+
+	BeforeEach(func() {
+		cache = &cdfakes.FakeCachedDownloader{}
+		cache.FetchReturns(ioutil.NopCloser(new(bytes.Buffer)), 42, nil)
+
+		verifier = nil
+		chunkedDownloader = &fake_chunkeddownloader.FakeChunkedDownloader{}
+		chunkedDownloader.FetchStub = func(_ lager.Logger, _ *url.URL, _ string, _ cacheddownloader.ChecksumInfoType, progress chunkeddownloader.ProgressFunc, _ <-chan struct{}) (io.ReadCloser, int64, error) {
+			progress(50, 100)
+			progress(60, 100)
+			fakeClock.Increment(steps.DownloadProgressInterval)
+			progress(80, 100)
+			progress(100, 100)
+
+			return createTempTar(), 100, nil
+		}
+
+		downloadAction = models.DownloadAction{
+			Artifact:          "droplet",
+			From:              "http://mr_jones",
+			To:                "/tmp/Antarctica",
+			CacheKey:          "the-cache-key",
+			User:              "notroot",
+			ChecksumAlgorithm: "sha256",
+			ChecksumValue:     "checksum-value",
+		}
+
+		gardenClient = fakes.NewGardenClient()
+		streamedIn = &bytes.Buffer{}
+		gardenClient.Connection.StreamInStub = func(handle string, spec garden.StreamInSpec) error {
+			_, err := io.Copy(streamedIn, spec.TarStream)
+			return err
+		}
+
+		fakeStreamer = newFakeStreamer()
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		logger = lagertest.NewTestLogger("test")
+	})
+
+	JustBeforeEach(func() {
+		container, err := gardenClient.Create(garden.ContainerSpec{Handle: "some-container-handle"})
+		Expect(err).NotTo(HaveOccurred())
+
+		step := steps.NewChunkedDownload(
+			container,
+			downloadAction,
+			cache,
+			chunkedDownloader,
+			verifier,
+			make(chan struct{}, 1),
+			fakeStreamer,
+			fakeClock,
+			logger,
+		)
+
+		stepErr = <-ifrit.Invoke(step).Wait()
+	})
+
+	It("downloads with the chunked downloader instead of the cached downloader", func() {
+		Expect(stepErr).NotTo(HaveOccurred())
+		Expect(cache.FetchCallCount()).To(BeZero())
+
+		Expect(chunkedDownloader.FetchCallCount()).To(Equal(1))
+		_, url, cacheKey, checksum, _, cancel := chunkedDownloader.FetchArgsForCall(0)
+		Expect(url.Host).To(Equal("mr_jones"))
+		Expect(cacheKey).To(Equal("the-cache-key"))
+		Expect(checksum).To(Equal(cacheddownloader.ChecksumInfoType{Algorithm: "sha256", Value: "checksum-value"}))
+		Expect(cancel).NotTo(BeNil())
+	})
+
+	It("streams the downloaded file into the container", func() {
+		header, err := tar.NewReader(streamedIn).Next()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(header.Name).To(Equal("file1"))
+	})
+
+	It("reports the progress at a throttled cadence", func() {
+		stdout := string(fakeStreamer.Stdout().(*gbytes.Buffer).Contents())
+		Expect(stdout).To(ContainSubstring("Downloading droplet: 50B of 100B (50%)\n"))
+		Expect(stdout).NotTo(ContainSubstring("(60%)"))
+		Expect(stdout).To(ContainSubstring("Downloading droplet: 80B of 100B (80%)\n"))
+		Expect(stdout).To(ContainSubstring("Downloading droplet: 100B of 100B (100%)\n"))
+		Expect(stdout).To(ContainSubstring("Downloaded droplet (100B)\n"))
+	})
+
+	Context("when the server does not support range requests", func() {
+		BeforeEach(func() {
+			chunkedDownloader.FetchStub = nil
+			chunkedDownloader.FetchReturns(nil, 0, chunkeddownloader.ErrRangesNotSupported)
+		})
+
+		It("falls back to the cache", func() {
+			Expect(stepErr).NotTo(HaveOccurred())
+			Expect(cache.FetchCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the chunked download fails", func() {
+		BeforeEach(func() {
+			chunkedDownloader.FetchStub = nil
+			chunkedDownloader.FetchReturns(nil, 0, &chunkeddownloader.ChecksumMismatchError{})
+		})
+
+		It("fails without falling back to the cache", func() {
+			Expect(stepErr).To(MatchError("Downloading droplet failed"))
+			Expect(cache.FetchCallCount()).To(BeZero())
+		})
+	})
+
+	Context("when a signature verifier is configured", func() {
+		var fakeVerifier *fake_signatureverifier.FakeVerifier
+
+		BeforeEach(func() {
+			fakeVerifier = &fake_signatureverifier.FakeVerifier{}
+			verifier = fakeVerifier
+		})
+
+		It("verifies the signature against the checksum the artifact was fetched with", func() {
+			Expect(stepErr).NotTo(HaveOccurred())
+			Expect(chunkedDownloader.FetchCallCount()).To(Equal(1))
+			_, _, _, fetchedChecksum, _, _ := chunkedDownloader.FetchArgsForCall(0)
+
+			Expect(fakeVerifier.VerifyCallCount()).To(Equal(1))
+			_, url, checksum, _ := fakeVerifier.VerifyArgsForCall(0)
+			Expect(url.Host).To(Equal("mr_jones"))
+			Expect(checksum).To(Equal(cacheddownloader.ChecksumInfoType{Algorithm: "sha256", Value: "checksum-value"}))
+			Expect(checksum).To(Equal(fetchedChecksum))
+		})
+
+		It("fetches the artifact under a cache key bound to the checksum", func() {
+			_, _, cacheKey, _, _, _ := chunkedDownloader.FetchArgsForCall(0)
+			Expect(cacheKey).To(Equal(signatureverifier.CacheKey("the-cache-key", cacheddownloader.ChecksumInfoType{Algorithm: "sha256", Value: "checksum-value"})))
+			Expect(cacheKey).NotTo(Equal("the-cache-key"))
+		})
+
+		Context("when the server does not support range requests", func() {
+			BeforeEach(func() {
+				chunkedDownloader.FetchStub = nil
+				chunkedDownloader.FetchReturns(nil, 0, chunkeddownloader.ErrRangesNotSupported)
+			})
+
+			It("fetches from the cached downloader under the bound cache key", func() {
+				Expect(stepErr).NotTo(HaveOccurred())
+				_, _, cacheKey, _, _ := cache.FetchArgsForCall(0)
+				Expect(cacheKey).To(Equal(signatureverifier.CacheKey("the-cache-key", cacheddownloader.ChecksumInfoType{Algorithm: "sha256", Value: "checksum-value"})))
+			})
+		})
+
+		Context("when the signature is invalid", func() {
+			BeforeEach(func() {
+				fakeVerifier.VerifyReturns(signatureverifier.ErrSignatureInvalid)
+			})
+
+			It("fails before anything is streamed in", func() {
+				Expect(stepErr).To(MatchError("Verifying signature of droplet failed"))
+				Expect(gardenClient.Connection.StreamInCallCount()).To(BeZero())
+				Expect(fakeStreamer.Stderr().(*gbytes.Buffer)).To(gbytes.Say("Verifying signature of droplet failed\n"))
+			})
+		})
+
+		Context("when the signature is missing", func() {
+			BeforeEach(func() {
+				fakeVerifier.VerifyReturns(signatureverifier.ErrSignatureNotFound)
+			})
+
+			It("fails with a distinct error", func() {
+				Expect(stepErr).To(MatchError("Signature of droplet not found"))
+				Expect(gardenClient.Connection.StreamInCallCount()).To(BeZero())
+			})
+		})
+	})
+})
+
 var _ = Describe("ReadSizer", func() {
 	Describe("BytesRead", func() {
 		It("returns the number of bytes read", func() {
@@ -2,13 +2,20 @@ package steps
 
 import (
 	"os"
+	"time"
 
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/executor/depot/log_streamer"
 	"github.com/tedsuo/ifrit"
 
 	"code.cloudfoundry.org/lager"
 )
 
+// ProgressFunc is told when the substep of an emit progress step starts and
+// how it ends.
+type ProgressFunc func(step string, phase executor.StepPhase, duration time.Duration, err error)
+
 type emitProgressStep struct {
 	substep        ifrit.Runner
 	logger         lager.Logger
@@ -16,6 +23,9 @@ type emitProgressStep struct {
 	successMessage string
 	failureMessage string
 	streamer       log_streamer.LogStreamer
+	stepName       string
+	onProgress     ProgressFunc
+	clock          clock.Clock
 }
 
 func NewEmitProgress(
@@ -25,6 +35,22 @@ func NewEmitProgress(
 	failureMessage string,
 	streamer log_streamer.LogStreamer,
 	logger lager.Logger,
+) *emitProgressStep {
+	return NewEmitProgressWithEvents(substep, startMessage, successMessage, failureMessage, streamer, "", nil, nil, logger)
+}
+
+// NewEmitProgressWithEvents also reports the progress of substep, under
+// stepName, to onProgress.
+func NewEmitProgressWithEvents(
+	substep ifrit.Runner,
+	startMessage,
+	successMessage,
+	failureMessage string,
+	streamer log_streamer.LogStreamer,
+	stepName string,
+	onProgress ProgressFunc,
+	clock clock.Clock,
+	logger lager.Logger,
 ) *emitProgressStep {
 	logger = logger.Session("emit-progress-step")
 	return &emitProgressStep{
@@ -34,6 +60,9 @@ func NewEmitProgress(
 		successMessage: successMessage,
 		failureMessage: failureMessage,
 		streamer:       streamer,
+		stepName:       stepName,
+		onProgress:     onProgress,
+		clock:          clock,
 	}
 }
 
@@ -42,8 +71,27 @@ func (step *emitProgressStep) Run(signals <-chan os.Signal, ready chan<- struct{
 		step.streamer.Stdout().Write([]byte(step.startMessage + "\n"))
 	}
 
+	var startTime time.Time
+	if step.onProgress != nil {
+		startTime = step.clock.Now()
+		step.onProgress(step.stepName, executor.StepPhaseStarted, 0, nil)
+	}
+
 	err := step.substep.Run(signals, ready)
+	step.emitMessages(err)
+
+	if step.onProgress != nil {
+		phase := executor.StepPhaseSucceeded
+		if err != nil {
+			phase = executor.StepPhaseFailed
+		}
+		step.onProgress(step.stepName, phase, step.clock.Since(startTime), err)
+	}
 
+	return err
+}
+
+func (step *emitProgressStep) emitMessages(err error) {
 	if err != nil {
 		if step.failureMessage != "" {
 			step.streamer.Stderr().Write([]byte(step.failureMessage))
@@ -70,6 +118,4 @@ func (step *emitProgressStep) Run(signals <-chan os.Signal, ready chan<- struct{
 			step.streamer.Stdout().Write([]byte(step.successMessage + "\n"))
 		}
 	}
-
-	return err
 }
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"errors"
 	"os"
+	"time"
 
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/lager/lagertest"
 
 	"code.cloudfoundry.org/executor/depot/log_streamer/fake_log_streamer"
@@ -213,4 +216,56 @@ var _ = Describe("EmitProgressStep", func() {
 			Eventually(finished).Should(BeClosed())
 		})
 	})
+
+	Context("with progress events", func() {
+		type progress struct {
+			step     string
+			phase    executor.StepPhase
+			duration time.Duration
+			err      error
+		}
+
+		var (
+			fakeClock *fakeclock.FakeClock
+			events    []progress
+		)
+
+		BeforeEach(func() {
+			fakeClock = fakeclock.NewFakeClock(time.Now())
+			events = nil
+			subStep.RunStub = func(signals <-chan os.Signal, ready chan<- struct{}) error {
+				fakeClock.Increment(3 * time.Second)
+				return errorToReturn
+			}
+		})
+
+		JustBeforeEach(func() {
+			onProgress := func(step string, phase executor.StepPhase, duration time.Duration, err error) {
+				events = append(events, progress{step, phase, duration, err})
+			}
+			step = steps.NewEmitProgressWithEvents(subStep, startMessage, successMessage, failureMessage, fakeStreamer, "download droplet", onProgress, fakeClock, logger)
+		})
+
+		It("reports the start and the success of the substep", func() {
+			Expect(step.Run(nil, nil)).To(Succeed())
+			Expect(events).To(Equal([]progress{
+				{"download droplet", executor.StepPhaseStarted, 0, nil},
+				{"download droplet", executor.StepPhaseSucceeded, 3 * time.Second, nil},
+			}))
+		})
+
+		Context("when the substep fails", func() {
+			BeforeEach(func() {
+				errorToReturn = errors.New("failed to download")
+			})
+
+			It("reports the failure with its error", func() {
+				Expect(step.Run(nil, nil)).To(MatchError("failed to download"))
+				Expect(events).To(HaveLen(2))
+				Expect(events[1].phase).To(Equal(executor.StepPhaseFailed))
+				Expect(events[1].duration).To(Equal(3 * time.Second))
+				Expect(events[1].err).To(MatchError("failed to download"))
+			})
+		})
+	})
 })
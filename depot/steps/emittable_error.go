@@ -1,10 +1,15 @@
 package steps
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/hashicorp/errwrap"
+)
 
 type EmittableError struct {
-	msg          string
-	wrappedError error
+	msg           string
+	wrappedError  error
+	outputExcerpt string
 }
 
 func NewEmittableError(wrappedError error, message string, args ...interface{}) *EmittableError {
@@ -26,3 +31,36 @@ func (e *EmittableError) Error() string {
 func (e *EmittableError) WrappedError() error {
 	return e.wrappedError
 }
+
+// WithOutputExcerpt attaches the tail of the output of the failed process
+// to the error.
+func (e *EmittableError) WithOutputExcerpt(excerpt string) *EmittableError {
+	e.outputExcerpt = excerpt
+	return e
+}
+
+func (e *EmittableError) OutputExcerpt() string {
+	return e.outputExcerpt
+}
+
+// OutputExcerpt returns the first output excerpt found in err or in the
+// errors it wraps.
+func OutputExcerpt(err error) string {
+	switch e := err.(type) {
+	case nil:
+		return ""
+	case *EmittableError:
+		if e.outputExcerpt != "" {
+			return e.outputExcerpt
+		}
+		return OutputExcerpt(e.wrappedError)
+	case errwrap.Wrapper:
+		for _, wrapped := range e.WrappedErrors() {
+			if excerpt := OutputExcerpt(wrapped); excerpt != "" {
+				return excerpt
+			}
+		}
+	}
+
+	return ""
+}
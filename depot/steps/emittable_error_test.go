@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"code.cloudfoundry.org/executor/depot/steps"
+	"github.com/hashicorp/go-multierror"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -46,4 +47,26 @@ var _ = Describe("EmittableError", func() {
 			})
 		})
 	})
+
+	Describe("OutputExcerpt", func() {
+		It("returns the excerpt attached to the error", func() {
+			err := steps.NewEmittableError(nil, "Fancy").WithOutputExcerpt("[stderr] oops")
+			Expect(err.OutputExcerpt()).To(Equal("[stderr] oops"))
+			Expect(steps.OutputExcerpt(err)).To(Equal("[stderr] oops"))
+		})
+
+		It("finds the excerpt of wrapped errors", func() {
+			inner := steps.NewEmittableError(nil, "Fancy").WithOutputExcerpt("[stderr] oops")
+			outer := steps.NewEmittableError(inner, "Fancier")
+			Expect(steps.OutputExcerpt(outer)).To(Equal("[stderr] oops"))
+
+			aggregate := multierror.Append(errors.New("other"), outer)
+			Expect(steps.OutputExcerpt(aggregate)).To(Equal("[stderr] oops"))
+		})
+
+		It("is empty for errors without an excerpt", func() {
+			Expect(steps.OutputExcerpt(wrappedError)).To(BeEmpty())
+			Expect(steps.OutputExcerpt(steps.NewEmittableError(wrappedError, "Fancy"))).To(BeEmpty())
+		})
+	})
 })
@@ -0,0 +1,34 @@
+package steps
+
+import (
+	"os"
+
+	"github.com/tedsuo/ifrit"
+)
+
+type errorPrefixStep struct {
+	substep ifrit.Runner
+	prefix  string
+}
+
+// NewErrorPrefix prefixes the error of the substep, like
+// NewOutputWrapperWithPrefix does for the output of a process.
+func NewErrorPrefix(substep ifrit.Runner, prefix string) ifrit.Runner {
+	return &errorPrefixStep{
+		substep: substep,
+		prefix:  prefix,
+	}
+}
+
+func (step *errorPrefixStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	err := step.substep.Run(signals, ready)
+	if err == nil {
+		return nil
+	}
+
+	if _, cancelled := err.(*CancelledError); cancelled {
+		return err
+	}
+
+	return NewEmittableError(err, "%s: %s", step.prefix, err.Error())
+}
@@ -0,0 +1,47 @@
+package steps_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/executor/depot/steps"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/fake_runner"
+)
+
+var _ = Describe("ErrorPrefixStep", func() {
+	var (
+		substep *fake_runner.TestRunner
+		process ifrit.Process
+	)
+
+	BeforeEach(func() {
+		substep = fake_runner.NewTestRunner()
+		process = ifrit.Background(steps.NewErrorPrefix(substep, "instance proxy failed to start"))
+	})
+
+	AfterEach(func() {
+		substep.EnsureExit()
+	})
+
+	It("prefixes the error of the substep", func() {
+		substep.TriggerExit(errors.New("connection refused"))
+
+		var err *steps.EmittableError
+		Eventually(process.Wait()).Should(Receive(&err))
+		Expect(err.Error()).To(Equal("instance proxy failed to start: connection refused"))
+		Expect(err.WrappedError()).To(MatchError("connection refused"))
+	})
+
+	It("succeeds when the substep succeeds", func() {
+		substep.TriggerExit(nil)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("does not prefix cancellation", func() {
+		substep.TriggerExit(new(steps.CancelledError))
+		Eventually(process.Wait()).Should(Receive(Equal(new(steps.CancelledError))))
+	})
+})
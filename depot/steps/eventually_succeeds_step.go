@@ -11,26 +11,42 @@ import (
 type eventuallySucceedsStep struct {
 	create             func() ifrit.Runner
 	frequency, timeout time.Duration
+	successThreshold   int
+	schedule           Schedule
 	clock              clock.Clock
 }
 
 // TODO: use a workpool when running the substep
 func NewEventuallySucceedsStep(create func() ifrit.Runner, frequency, timeout time.Duration, clock clock.Clock) ifrit.Runner {
+	return NewEventuallySucceedsStepWithThreshold(create, frequency, timeout, 1, nil, clock)
+}
+
+// NewEventuallySucceedsStepWithThreshold only succeeds once successThreshold
+// substeps in a row have succeeded. A nil schedule runs the substep every
+// frequency.
+func NewEventuallySucceedsStepWithThreshold(create func() ifrit.Runner, frequency, timeout time.Duration, successThreshold int, schedule Schedule, clock clock.Clock) ifrit.Runner {
+	if successThreshold < 1 {
+		successThreshold = 1
+	}
+
 	return &eventuallySucceedsStep{
-		create:    create,
-		frequency: frequency,
-		timeout:   timeout,
-		clock:     clock,
+		create:           create,
+		frequency:        frequency,
+		timeout:          timeout,
+		successThreshold: successThreshold,
+		schedule:         schedule,
+		clock:            clock,
 	}
 }
 
 func (step *eventuallySucceedsStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	var err error
+	successes := 0
 
 	close(ready)
 
 	startTime := step.clock.Now()
-	t := step.clock.NewTimer(step.frequency)
+	t := step.clock.NewTimer(nextInterval(step.schedule, step.frequency))
 
 	for {
 		select {
@@ -45,16 +61,23 @@ func (step *eventuallySucceedsStep) Run(signals <-chan os.Signal, ready chan<- s
 		case s := <-signals:
 			subProcess.Signal(s)
 			return <-subProcess.Wait()
-		case err = <-subProcess.Wait():
-			if err == nil {
-				return nil
+		case subErr := <-subProcess.Wait():
+			if subErr == nil {
+				successes++
+				if successes >= step.successThreshold {
+					return nil
+				}
+			} else {
+				successes = 0
+				err = subErr
 			}
 		}
 
-		if step.timeout > 0 && step.clock.Now().After(startTime.Add(step.timeout)) {
+		// a streak of successes that is in progress is allowed to finish
+		if successes == 0 && step.timeout > 0 && step.clock.Now().After(startTime.Add(step.timeout)) {
 			return err
 		}
 
-		t.Reset(step.frequency)
+		t.Reset(nextInterval(step.schedule, step.frequency))
 	}
 }
@@ -123,3 +123,73 @@ var _ = Describe("EventuallySucceedsStep", func() {
 		})
 	})
 })
+
+var _ = Describe("EventuallySucceedsStepWithThreshold", func() {
+	var (
+		process   ifrit.Process
+		fakeStep  *fake_runner.TestRunner
+		fakeClock *fakeclock.FakeClock
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeStep = fake_runner.NewTestRunner()
+
+		step := steps.NewEventuallySucceedsStepWithThreshold(func() ifrit.Runner { return fakeStep }, time.Second, 10*time.Second, 2, nil, fakeClock)
+		process = ifrit.Background(step)
+	})
+
+	probe := func(err error) {
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		fakeStep.TriggerExit(err)
+	}
+
+	It("succeeds once the threshold of consecutive successes is reached", func() {
+		probe(nil)
+		Consistently(process.Wait()).ShouldNot(Receive())
+		probe(nil)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("starts counting again after a failure", func() {
+		probe(nil)
+		probe(errors.New("BOOOOM"))
+		probe(nil)
+		Consistently(process.Wait()).ShouldNot(Receive())
+		probe(nil)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	It("lets a streak of successes finish after the timeout", func() {
+		probe(errors.New("BOOOOM"))
+		fakeClock.WaitForWatcherAndIncrement(10 * time.Second)
+		fakeStep.TriggerExit(nil)
+		Consistently(process.Wait()).ShouldNot(Receive())
+		probe(nil)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+})
+
+type doublingSchedule struct{}
+
+func (doublingSchedule) Interval(base time.Duration) time.Duration {
+	return 2 * base
+}
+
+var _ = Describe("EventuallySucceedsStepWithThreshold with a schedule", func() {
+	It("waits for the interval of the schedule between substeps", func() {
+		fakeClock := fakeclock.NewFakeClock(time.Now())
+		fakeStep := fake_runner.NewTestRunner()
+
+		step := steps.NewEventuallySucceedsStepWithThreshold(func() ifrit.Runner { return fakeStep }, time.Second, 0, 1, doublingSchedule{}, fakeClock)
+		process := ifrit.Background(step)
+
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Consistently(fakeStep.RunCallCount).Should(BeZero())
+		fakeClock.Increment(time.Second)
+		Eventually(fakeStep.RunCallCount).Should(Equal(1))
+
+		fakeStep.TriggerExit(nil)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+})
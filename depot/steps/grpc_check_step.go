@@ -0,0 +1,86 @@
+package steps
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/tedsuo/ifrit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+type grpcCheckStep struct {
+	address string
+	service string
+	timeout time.Duration
+	logger  lager.Logger
+}
+
+// NewGRPCCheck makes a single call to the grpc.health.v1 Health service at
+// address and fails unless the service reports SERVING within the timeout.
+func NewGRPCCheck(address, service string, timeout time.Duration, logger lager.Logger) ifrit.Runner {
+	return &grpcCheckStep{
+		address: address,
+		service: service,
+		timeout: timeout,
+		logger:  logger.Session("grpc-check", lager.Data{"address": address, "service": service}),
+	}
+}
+
+func (step *grpcCheckStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), step.timeout)
+	defer cancel()
+
+	close(ready)
+
+	resultCh := make(chan error, 1)
+	go func() {
+		resultCh <- step.check(ctx)
+	}()
+
+	select {
+	case err := <-resultCh:
+		return err
+	case <-signals:
+		cancel()
+		<-resultCh
+		return new(CancelledError)
+	}
+}
+
+func (step *grpcCheckStep) check(ctx context.Context) error {
+	conn, err := grpc.DialContext(ctx, step.address, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		step.logger.Info("failed-to-connect", lager.Data{"error": err.Error()})
+		if ctx.Err() == context.DeadlineExceeded {
+			return NewEmittableError(err, "Failed to make gRPC connection to %s: timed out after %s", step.address, step.timeout)
+		}
+		return NewEmittableError(err, "Failed to make gRPC connection to %s", step.address)
+	}
+	defer conn.Close()
+
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{
+		Service: step.service,
+	})
+	if err != nil {
+		step.logger.Info("check-failed", lager.Data{"error": err.Error()})
+		return NewEmittableError(err, "gRPC health check of %s failed: %s", step.target(), status.Convert(err).Message())
+	}
+
+	if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+		step.logger.Info("not-serving", lager.Data{"status": resp.GetStatus().String()})
+		return NewEmittableError(nil, "gRPC health check of %s returned %s", step.target(), resp.GetStatus())
+	}
+
+	return nil
+}
+
+func (step *grpcCheckStep) target() string {
+	if step.service == "" {
+		return step.address
+	}
+	return step.service + " at " + step.address
+}
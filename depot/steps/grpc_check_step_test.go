@@ -0,0 +1,108 @@
+package steps_test
+
+import (
+	"net"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/executor/depot/steps"
+	"code.cloudfoundry.org/lager/lagertest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("GRPCCheckStep", func() {
+	var (
+		logger       *lagertest.TestLogger
+		listener     net.Listener
+		server       *grpc.Server
+		healthServer *health.Server
+		service      string
+		timeout      time.Duration
+		process      ifrit.Process
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		service = ""
+		timeout = time.Second
+
+		var err error
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+
+		healthServer = health.NewServer()
+		server = grpc.NewServer()
+		grpc_health_v1.RegisterHealthServer(server, healthServer)
+		go server.Serve(listener)
+	})
+
+	AfterEach(func() {
+		server.Stop()
+	})
+
+	JustBeforeEach(func() {
+		process = ifrit.Background(steps.NewGRPCCheck(listener.Addr().String(), service, timeout, logger))
+	})
+
+	It("succeeds when the server is serving", func() {
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	Context("when the service is not serving", func() {
+		BeforeEach(func() {
+			service = "app"
+			healthServer.SetServingStatus(service, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+		})
+
+		It("fails with the reported status", func() {
+			var err error
+			Eventually(process.Wait()).Should(Receive(&err))
+			Expect(err).To(MatchError("gRPC health check of app at " + listener.Addr().String() + " returned NOT_SERVING"))
+		})
+	})
+
+	Context("when the service is unknown", func() {
+		BeforeEach(func() {
+			service = "unknown"
+		})
+
+		It("fails with the error returned by the server", func() {
+			var err error
+			Eventually(process.Wait()).Should(Receive(&err))
+			Expect(err).To(MatchError(ContainSubstring("gRPC health check of unknown at")))
+			Expect(err).To(MatchError(ContainSubstring("unknown service")))
+		})
+	})
+
+	Context("when nothing is listening", func() {
+		BeforeEach(func() {
+			timeout = 100 * time.Millisecond
+			server.Stop()
+		})
+
+		It("fails once the timeout elapses", func() {
+			var err error
+			Eventually(process.Wait()).Should(Receive(&err))
+			Expect(err).To(MatchError(ContainSubstring("Failed to make gRPC connection to " + listener.Addr().String())))
+			Expect(err).To(MatchError(ContainSubstring("timed out after 100ms")))
+		})
+	})
+
+	Context("when signalled", func() {
+		BeforeEach(func() {
+			timeout = time.Minute
+			server.Stop()
+		})
+
+		It("returns a CancelledError", func() {
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive(Equal(new(steps.CancelledError))))
+		})
+	})
+})
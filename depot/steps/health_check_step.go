@@ -27,6 +27,7 @@ type healthCheckStep struct {
 	healthCheckStreamer log_streamer.LogStreamer
 
 	startTimeout time.Duration
+	health       *HealthTracker
 }
 
 func NewHealthCheckStep(
@@ -37,6 +38,7 @@ func NewHealthCheckStep(
 	logStreamer log_streamer.LogStreamer,
 	healthcheckStreamer log_streamer.LogStreamer,
 	startTimeout time.Duration,
+	health *HealthTracker,
 ) ifrit.Runner {
 	logger = logger.Session("health-check-step")
 
@@ -48,6 +50,7 @@ func NewHealthCheckStep(
 		logStreamer:         logStreamer,
 		healthCheckStreamer: healthcheckStreamer,
 		startTimeout:        startTimeout,
+		health:              health,
 	}
 }
 
@@ -55,6 +58,7 @@ func (step *healthCheckStep) Run(signals <-chan os.Signal, ready chan<- struct{}
 	//TODO: make this use metron agent directly, don't use log streamer, shouldn't be rate limited.
 	fmt.Fprint(step.logStreamer.Stdout(), "Starting health monitoring of container\n")
 
+	step.health.start()
 	readinessProcess := ifrit.Background(step.readinessCheck)
 
 	healthCheckStartedTime := time.Now()
@@ -62,6 +66,7 @@ func (step *healthCheckStep) Run(signals <-chan os.Signal, ready chan<- struct{}
 	select {
 	case err := <-readinessProcess.Wait():
 		if err != nil {
+			step.health.readinessFailed()
 			healthCheckFailedTime := time.Since(healthCheckStartedTime).Round(time.Millisecond)
 			//TODO: make this use metron agent directly, don't use log streamer, shouldn't be rate limited.
 			fmt.Fprintf(step.healthCheckStreamer.Stderr(), "%s\n", err.Error())
@@ -77,6 +82,7 @@ func (step *healthCheckStep) Run(signals <-chan os.Signal, ready chan<- struct{}
 		return new(CancelledError)
 	}
 
+	step.health.becameHealthy(step.clock.Now())
 	step.logger.Info("transitioned-to-healthy")
 	//TODO: make this use metron agent directly, don't use log streamer, shouldn't be rate limited.
 	fmt.Fprint(step.logStreamer.Stdout(), "Container became healthy\n")
@@ -86,6 +92,7 @@ func (step *healthCheckStep) Run(signals <-chan os.Signal, ready chan<- struct{}
 
 	select {
 	case err := <-livenessProcess.Wait():
+		step.health.livenessFailed()
 		step.logger.Info("transitioned-to-unhealthy")
 		//TODO: make this use metron agent directly, don't use log streamer, shouldn't be rate limited.
 		fmt.Fprintf(step.healthCheckStreamer.Stderr(), "%s\n", err.Error())
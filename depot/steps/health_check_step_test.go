@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/executor/depot/log_streamer/fake_log_streamer"
 	"code.cloudfoundry.org/executor/depot/steps"
 	"code.cloudfoundry.org/lager/lagertest"
@@ -30,6 +31,7 @@ var _ = Describe("NewHealthCheckStep", func() {
 		step    ifrit.Runner
 		process ifrit.Process
 		logger  *lagertest.TestLogger
+		health  *steps.HealthTracker
 	)
 
 	BeforeEach(func() {
@@ -44,19 +46,26 @@ var _ = Describe("NewHealthCheckStep", func() {
 		fakeStreamer = newFakeStreamer()
 
 		logger = lagertest.NewTestLogger("test")
+		health = steps.NewHealthTracker()
 	})
 
 	JustBeforeEach(func() {
 		fakeStreamer.WithSourceReturns(fakeStreamer)
 
+		// like the transformer, the checks record their own probes
+		var liveness ifrit.Runner
+		if livenessCheck != nil {
+			liveness = steps.NewRecordProbe(livenessCheck, health, clock)
+		}
 		step = steps.NewHealthCheckStep(
-			readinessCheck,
-			livenessCheck,
+			steps.NewRecordProbe(readinessCheck, health, clock),
+			liveness,
 			logger,
 			clock,
 			fakeStreamer,
 			fakeHealthCheckStreamer,
 			startTimeout,
+			health,
 		)
 
 		process = ifrit.Background(step)
@@ -78,6 +87,13 @@ var _ = Describe("NewHealthCheckStep", func() {
 			)
 		})
 
+		It("tracks the health as unknown", func() {
+			Eventually(health.Health).Should(Equal(&executor.ContainerHealth{
+				Readiness: executor.CheckStatusUnknown,
+				Liveness:  executor.CheckStatusUnknown,
+			}))
+		})
+
 		Context("when the readiness check fails", func() {
 			JustBeforeEach(func() {
 				readinessCheck.TriggerExit(errors.New("booom!"))
@@ -102,6 +118,13 @@ var _ = Describe("NewHealthCheckStep", func() {
 				)
 			})
 
+			It("tracks the readiness as failing", func() {
+				Eventually(process.Wait()).Should(Receive())
+				Expect(health.Health().Readiness).To(Equal(executor.CheckStatusFailing))
+				Expect(health.Health().LastProbeOutput).To(Equal("booom!"))
+				Expect(health.Health().ConsecutiveFailures).To(Equal(1))
+			})
+
 			It("emits a log message explaining the timeout", func() {
 				Eventually(fakeStreamer.Stderr().(*gbytes.Buffer)).Should(gbytes.Say(
 					"Failed after .*: readiness health check never passed.\n",
@@ -118,6 +141,13 @@ var _ = Describe("NewHealthCheckStep", func() {
 				Eventually(process.Ready()).Should(BeClosed())
 			})
 
+			It("tracks the container as healthy", func() {
+				Eventually(process.Ready()).Should(BeClosed())
+				Expect(health.Health().Readiness).To(Equal(executor.CheckStatusPassing))
+				Expect(health.Health().Liveness).To(Equal(executor.CheckStatusPassing))
+				Expect(health.Health().HealthySince).To(Equal(clock.Now().UnixNano()))
+			})
+
 			It("emits a log message for the success", func() {
 				Eventually(fakeStreamer.Stdout().(*gbytes.Buffer)).Should(
 					gbytes.Say("Container became healthy\n"),
@@ -162,6 +192,13 @@ var _ = Describe("NewHealthCheckStep", func() {
 					Eventually(process.Wait()).Should(Receive(&err))
 					Expect(err.WrappedError()).To(Equal(disaster))
 				})
+
+				It("tracks the liveness as failing", func() {
+					Eventually(process.Wait()).Should(Receive())
+					Expect(health.Health().Liveness).To(Equal(executor.CheckStatusFailing))
+					Expect(health.Health().HealthySince).To(BeZero())
+					Expect(health.Health().LastProbeOutput).To(Equal("oh no!"))
+				})
 			})
 		})
 	})
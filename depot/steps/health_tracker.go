@@ -0,0 +1,116 @@
+package steps
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/executor"
+	"github.com/tedsuo/ifrit"
+)
+
+// HealthTracker records the health of a container as its health checks run.
+// All methods are safe to call on a nil *HealthTracker, which tracks nothing.
+type HealthTracker struct {
+	lock    sync.Mutex
+	started bool
+	health  executor.ContainerHealth
+}
+
+func NewHealthTracker() *HealthTracker {
+	return &HealthTracker{}
+}
+
+// Health returns nil until the health checks of the container have started.
+func (h *HealthTracker) Health() *executor.ContainerHealth {
+	if h == nil {
+		return nil
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if !h.started {
+		return nil
+	}
+	health := h.health
+	return &health
+}
+
+func (h *HealthTracker) start() {
+	h.update(func(health *executor.ContainerHealth) {
+		*health = executor.ContainerHealth{
+			Readiness: executor.CheckStatusUnknown,
+			Liveness:  executor.CheckStatusUnknown,
+		}
+	})
+}
+
+func (h *HealthTracker) becameHealthy(now time.Time) {
+	h.update(func(health *executor.ContainerHealth) {
+		health.Readiness = executor.CheckStatusPassing
+		health.Liveness = executor.CheckStatusPassing
+		health.HealthySince = now.UnixNano()
+	})
+}
+
+// the probes themselves are recorded by the checks, wrapped in NewRecordProbe
+func (h *HealthTracker) readinessFailed() {
+	h.update(func(health *executor.ContainerHealth) {
+		health.Readiness = executor.CheckStatusFailing
+	})
+}
+
+func (h *HealthTracker) livenessFailed() {
+	h.update(func(health *executor.ContainerHealth) {
+		health.Liveness = executor.CheckStatusFailing
+		health.HealthySince = 0
+	})
+}
+
+func (h *HealthTracker) recordProbe(now time.Time, err error) {
+	h.update(func(health *executor.ContainerHealth) {
+		health.RecordProbe(now, err)
+	})
+}
+
+func (h *HealthTracker) update(f func(*executor.ContainerHealth)) {
+	if h == nil {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.started = true
+	f(&h.health)
+}
+
+type recordProbeStep struct {
+	substep ifrit.Runner
+	health  *HealthTracker
+	clock   clock.Clock
+}
+
+// NewRecordProbe records the result of the substep as a probe of health.
+// Cancelled probes are not recorded.
+func NewRecordProbe(substep ifrit.Runner, health *HealthTracker, clock clock.Clock) ifrit.Runner {
+	if health == nil {
+		return substep
+	}
+
+	return &recordProbeStep{
+		substep: substep,
+		health:  health,
+		clock:   clock,
+	}
+}
+
+func (step *recordProbeStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	err := step.substep.Run(signals, ready)
+	if _, cancelled := err.(*CancelledError); !cancelled {
+		step.health.recordProbe(step.clock.Now(), err)
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package steps_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/executor/depot/steps"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/fake_runner"
+)
+
+var _ = Describe("NewRecordProbe", func() {
+	var (
+		clock  *fakeclock.FakeClock
+		health *steps.HealthTracker
+	)
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		health = steps.NewHealthTracker()
+	})
+
+	probe := func(err error) {
+		check := fake_runner.NewTestRunner()
+		process := ifrit.Background(steps.NewRecordProbe(check, health, clock))
+		Eventually(check.RunCallCount).Should(Equal(1))
+		check.TriggerExit(err)
+		Eventually(process.Wait()).Should(Receive())
+	}
+
+	It("records every failed probe", func() {
+		probe(errors.New("first failure"))
+		firstProbeTime := health.Health().LastProbeTime
+
+		clock.Increment(time.Second)
+		probe(errors.New("second failure"))
+
+		Expect(health.Health().ConsecutiveFailures).To(Equal(2))
+		Expect(health.Health().LastProbeOutput).To(Equal("second failure"))
+		Expect(health.Health().LastProbeTime).To(Equal(firstProbeTime + int64(time.Second)))
+	})
+
+	It("resets the failures once a probe succeeds", func() {
+		probe(errors.New("failure"))
+		probe(nil)
+
+		Expect(health.Health().ConsecutiveFailures).To(BeZero())
+		Expect(health.Health().LastProbeOutput).To(BeEmpty())
+	})
+
+	It("does not record cancelled probes", func() {
+		probe(new(steps.CancelledError))
+
+		Expect(health.Health()).To(BeNil())
+	})
+})
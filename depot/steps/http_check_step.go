@@ -0,0 +1,106 @@
+package steps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/tedsuo/ifrit"
+)
+
+// probes are made once per check interval, so connections are not reused
+var httpCheckClient = &http.Client{
+	Transport: &http.Transport{
+		DisableKeepAlives: true,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+type httpCheckStep struct {
+	host    string
+	port    int
+	path    string
+	timeout time.Duration
+	logger  lager.Logger
+}
+
+// NewHTTPCheck makes a single HTTP request to the path on host and port and
+// fails unless it is answered with 200 OK within the timeout. Failures are
+// reported the way the healthcheck sidecar reports them.
+func NewHTTPCheck(host string, port int, path string, timeout time.Duration, logger lager.Logger) ifrit.Runner {
+	return &httpCheckStep{
+		host:    host,
+		port:    port,
+		path:    path,
+		timeout: timeout,
+		logger:  logger.Session("http-check", lager.Data{"port": port, "path": path}),
+	}
+}
+
+func (step *httpCheckStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), step.timeout)
+	defer cancel()
+
+	close(ready)
+
+	resultCh := make(chan error, 1)
+	go func() {
+		resultCh <- step.check(ctx)
+	}()
+
+	select {
+	case err := <-resultCh:
+		return err
+	case <-signals:
+		cancel()
+		<-resultCh
+		return new(CancelledError)
+	}
+}
+
+func (step *httpCheckStep) check(ctx context.Context) error {
+	url := fmt.Sprintf("http://%s%s", net.JoinHostPort(step.host, strconv.Itoa(step.port)), step.path)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return step.failed(err, err.Error())
+	}
+
+	startTime := time.Now()
+	resp, err := httpCheckClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return step.failed(err, probeFailureReason(ctx, err, step.timeout))
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		elapsed := time.Since(startTime) / time.Millisecond
+		return step.failed(nil, fmt.Sprintf("received status code %d in %dms", resp.StatusCode, elapsed))
+	}
+
+	return nil
+}
+
+func (step *httpCheckStep) failed(err error, reason string) error {
+	step.logger.Info("failed", lager.Data{"reason": reason})
+	return NewEmittableError(err, "Failed to make HTTP request to '%s' on port %d: %s", step.path, step.port, reason)
+}
+
+func probeFailureReason(ctx context.Context, err error, timeout time.Duration) string {
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return fmt.Sprintf("timed out after %.2f seconds", timeout.Seconds())
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection refused"
+	default:
+		return err.Error()
+	}
+}
@@ -0,0 +1,116 @@
+package steps_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/executor/depot/steps"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("HTTPCheckStep", func() {
+	var (
+		logger     *lagertest.TestLogger
+		server     *httptest.Server
+		statusCode int
+		block      chan struct{}
+		host       string
+		port       int
+		timeout    time.Duration
+		process    ifrit.Process
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		statusCode = http.StatusOK
+		block = make(chan struct{})
+		timeout = time.Second
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				<-block
+			}
+			w.WriteHeader(statusCode)
+		}))
+
+		var portStr string
+		var err error
+		host, portStr, err = net.SplitHostPort(server.Listener.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		port, err = strconv.Atoi(portStr)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		close(block)
+		server.Close()
+	})
+
+	run := func(path string) {
+		process = ifrit.Background(steps.NewHTTPCheck(host, port, path, timeout, logger))
+	}
+
+	It("succeeds when the endpoint responds with 200", func() {
+		run("/health")
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	Context("when the endpoint responds with another status", func() {
+		BeforeEach(func() {
+			statusCode = http.StatusServiceUnavailable
+		})
+
+		It("fails the way the healthcheck sidecar does", func() {
+			run("/health")
+			var err error
+			Eventually(process.Wait()).Should(Receive(&err))
+			Expect(err).To(MatchError(MatchRegexp(`^Failed to make HTTP request to '/health' on port \d+: received status code 503 in \d+ms$`)))
+		})
+	})
+
+	Context("when the request times out", func() {
+		BeforeEach(func() {
+			timeout = 100 * time.Millisecond
+		})
+
+		It("fails with the timeout", func() {
+			run("/slow")
+			var err error
+			Eventually(process.Wait()).Should(Receive(&err))
+			Expect(err).To(MatchError("Failed to make HTTP request to '/slow' on port " + strconv.Itoa(port) + ": timed out after 0.10 seconds"))
+		})
+	})
+
+	Context("when nothing is listening", func() {
+		BeforeEach(func() {
+			server.Close()
+		})
+
+		It("fails with connection refused", func() {
+			run("/health")
+			var err error
+			Eventually(process.Wait()).Should(Receive(&err))
+			Expect(err).To(MatchError("Failed to make HTTP request to '/health' on port " + strconv.Itoa(port) + ": connection refused"))
+		})
+	})
+
+	Context("when signalled", func() {
+		BeforeEach(func() {
+			timeout = time.Minute
+		})
+
+		It("returns a CancelledError", func() {
+			run("/slow")
+			process.Signal(os.Interrupt)
+			Eventually(process.Wait()).Should(Receive(Equal(new(steps.CancelledError))))
+		})
+	})
+})
@@ -0,0 +1,33 @@
+package steps
+
+import (
+	"fmt"
+	"os"
+
+	"code.cloudfoundry.org/executor/depot/log_streamer"
+	"github.com/tedsuo/ifrit"
+)
+
+type logFailureStep struct {
+	substep  ifrit.Runner
+	streamer log_streamer.LogStreamer
+}
+
+// NewLogFailure writes the error of the substep to the stderr of streamer,
+// so a check can report its failures under its own log source.
+func NewLogFailure(substep ifrit.Runner, streamer log_streamer.LogStreamer) ifrit.Runner {
+	return &logFailureStep{
+		substep:  substep,
+		streamer: streamer,
+	}
+}
+
+func (step *logFailureStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	err := step.substep.Run(signals, ready)
+	if err != nil {
+		if _, cancelled := err.(*CancelledError); !cancelled {
+			fmt.Fprintf(step.streamer.Stderr(), "%s\n", err.Error())
+		}
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package steps_test
+
+import (
+	"errors"
+	"os"
+
+	"code.cloudfoundry.org/executor/depot/log_streamer/fake_log_streamer"
+	"code.cloudfoundry.org/executor/depot/steps"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/fake_runner"
+)
+
+var _ = Describe("LogFailureStep", func() {
+	var (
+		substep  *fake_runner.TestRunner
+		streamer *fake_log_streamer.FakeLogStreamer
+		process  ifrit.Process
+	)
+
+	BeforeEach(func() {
+		substep = fake_runner.NewTestRunner()
+		streamer = newFakeStreamer()
+		process = ifrit.Background(steps.NewLogFailure(substep, streamer))
+	})
+
+	AfterEach(func() {
+		substep.EnsureExit()
+	})
+
+	It("writes the error of the substep to stderr", func() {
+		substep.TriggerExit(errors.New("check failed"))
+		Eventually(process.Wait()).Should(Receive(MatchError("check failed")))
+		Expect(streamer.Stderr().(*gbytes.Buffer)).To(gbytes.Say("check failed\n"))
+	})
+
+	It("writes nothing when the substep succeeds", func() {
+		substep.TriggerExit(nil)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+		Expect(streamer.Stderr().(*gbytes.Buffer).Contents()).To(BeEmpty())
+	})
+
+	It("writes nothing when the substep is cancelled", func() {
+		process.Signal(os.Interrupt)
+		Eventually(substep.WaitForCall()).Should(Receive(Equal(os.Interrupt)))
+		substep.TriggerExit(new(steps.CancelledError))
+		Eventually(process.Wait()).Should(Receive(Equal(new(steps.CancelledError))))
+		Expect(streamer.Stderr().(*gbytes.Buffer).Contents()).To(BeEmpty())
+	})
+})
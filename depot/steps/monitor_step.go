@@ -6,7 +6,6 @@ import (
 	"code.cloudfoundry.org/clock"
 	"code.cloudfoundry.org/executor/depot/log_streamer"
 	"code.cloudfoundry.org/lager"
-	"code.cloudfoundry.org/workpool"
 	"github.com/tedsuo/ifrit"
 )
 
@@ -18,18 +17,26 @@ func NewMonitor(
 	startTimeout time.Duration,
 	healthyInterval time.Duration,
 	unhealthyInterval time.Duration,
-	workPool *workpool.WorkPool,
+	successThreshold int,
+	failureThreshold int,
+	workPool WorkPool,
+	schedule Schedule,
+	health *HealthTracker,
+	probes *ProbeMetrics,
 	proxyReadinessChecks ...ifrit.Runner,
 ) ifrit.Runner {
-	throttledCheckFunc := func() ifrit.Runner {
-		return NewThrottle(checkFunc(), workPool)
+	throttledCheckFunc := func(probe string) func() ifrit.Runner {
+		return func() ifrit.Runner {
+			check := NewReportProbe(checkFunc(), probe, probes, clock)
+			return NewRecordProbe(NewThrottle(check, workPool), health, clock)
+		}
 	}
 
-	readiness := NewEventuallySucceedsStep(throttledCheckFunc, unhealthyInterval, startTimeout, clock)
-	liveness := NewConsistentlySucceedsStep(throttledCheckFunc, healthyInterval, clock)
+	readiness := NewEventuallySucceedsStepWithThreshold(throttledCheckFunc(ReadinessProbe), unhealthyInterval, startTimeout, successThreshold, schedule, clock)
+	liveness := NewConsistentlySucceedsStepWithThreshold(throttledCheckFunc(LivenessProbe), healthyInterval, failureThreshold, schedule, clock)
 
 	// add the proxy readiness checks (if any)
 	readiness = NewParallel(append(proxyReadinessChecks, readiness))
 
-	return NewHealthCheckStep(readiness, liveness, logger, clock, logStreamer, logStreamer, startTimeout)
+	return NewHealthCheckStep(readiness, liveness, logger, clock, logStreamer, logStreamer, startTimeout, health)
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/executor/depot/log_streamer/fake_log_streamer"
 	"code.cloudfoundry.org/executor/depot/steps"
 	"code.cloudfoundry.org/lager/lagertest"
@@ -32,6 +33,10 @@ var _ = Describe("MonitorStep", func() {
 		startTimeout      time.Duration
 		healthyInterval   time.Duration
 		unhealthyInterval time.Duration
+		successThreshold  int
+		failureThreshold  int
+		health            *steps.HealthTracker
+		probes            *steps.ProbeMetrics
 
 		step   ifrit.Runner
 		logger *lagertest.TestLogger
@@ -50,6 +55,10 @@ var _ = Describe("MonitorStep", func() {
 		startTimeout = 0
 		healthyInterval = 1 * time.Second
 		unhealthyInterval = 500 * time.Millisecond
+		successThreshold = 1
+		failureThreshold = 1
+		health = steps.NewHealthTracker()
+		probes = nil
 
 		fakeStep1 = fake_runner.NewTestRunner()
 		fakeStep2 = fake_runner.NewTestRunner()
@@ -83,7 +92,12 @@ var _ = Describe("MonitorStep", func() {
 			startTimeout,
 			healthyInterval,
 			unhealthyInterval,
+			successThreshold,
+			failureThreshold,
 			workPool,
+			nil,
+			health,
+			probes,
 		)
 	})
 
@@ -216,6 +230,62 @@ var _ = Describe("MonitorStep", func() {
 			})
 		})
 
+		Context("when a failure threshold is set", func() {
+			var fakeStep3 *fake_runner.TestRunner
+
+			BeforeEach(func() {
+				failureThreshold = 2
+				fakeStep3 = fake_runner.NewTestRunner()
+				checkSteps = make(chan ifrit.Runner, 3)
+				checkSteps <- fakeStep1
+				checkSteps <- fakeStep2
+				checkSteps <- fakeStep3
+			})
+
+			It("only fails after consecutive failures reach the threshold", func() {
+				go fakeStep1.TriggerExit(nil)
+				expectCheckAfterInterval(fakeStep1, unhealthyInterval)
+				Eventually(process.Ready()).Should(BeClosed())
+
+				go fakeStep2.TriggerExit(errors.New("gc pause"))
+				expectCheckAfterInterval(fakeStep2, healthyInterval)
+				Consistently(process.Wait()).ShouldNot(Receive())
+
+				go fakeStep3.TriggerExit(errors.New("oh no!"))
+				expectCheckAfterInterval(fakeStep3, healthyInterval)
+				var err *steps.EmittableError
+				Eventually(process.Wait()).Should(Receive(&err))
+				Expect(err.WrappedError()).To(MatchError("oh no!"))
+				Expect(health.Health().ConsecutiveFailures).To(Equal(2))
+				Expect(health.Health().LastProbeOutput).To(Equal("oh no!"))
+			})
+		})
+
+		Context("when probe metrics are configured", func() {
+			var fakeMetronClient *mfakes.FakeIngressClient
+
+			BeforeEach(func() {
+				fakeMetronClient = new(mfakes.FakeIngressClient)
+				probes = steps.NewProbeMetrics(fakeMetronClient, nil, nil, logger)
+			})
+
+			It("reports readiness and liveness probes", func() {
+				go fakeStep1.TriggerExit(nil)
+				expectCheckAfterInterval(fakeStep1, unhealthyInterval)
+				Eventually(process.Ready()).Should(BeClosed())
+				Eventually(fakeMetronClient.SendMetricCallCount).Should(Equal(1))
+				_, _, opts := fakeMetronClient.SendMetricArgsForCall(0)
+				Expect(envelopeTags(opts)).To(HaveKeyWithValue("probe", "readiness"))
+
+				go fakeStep2.TriggerExit(errors.New("oh no!"))
+				expectCheckAfterInterval(fakeStep2, healthyInterval)
+				Eventually(fakeMetronClient.SendMetricCallCount).Should(Equal(2))
+				_, value, opts := fakeMetronClient.SendMetricArgsForCall(1)
+				Expect(value).To(Equal(0))
+				Expect(envelopeTags(opts)).To(HaveKeyWithValue("probe", "liveness"))
+			})
+		})
+
 		Context("when the check is failing immediately", func() {
 			var expectedErr error
 			BeforeEach(func() {
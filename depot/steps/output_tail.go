@@ -0,0 +1,144 @@
+package steps
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// OutputTailLines is the number of lines of process output kept for the
+	// failure reason of a run step
+	OutputTailLines = 10
+
+	outputTailMaxLineLength = 256
+)
+
+var ansiEscapeSequence = regexp.MustCompile("\x1b\\[[0-9;?]*[ -/]*[@-~]")
+
+// outputTail keeps the last lines written to its writers. Lines are recorded
+// before they reach the log streamer, so they are not subject to its rate
+// limiting.
+type outputTail struct {
+	lock    sync.Mutex
+	lines   []string
+	next    int
+	writers []*tailWriter
+}
+
+func newOutputTail(maxLines int) *outputTail {
+	return &outputTail{
+		lines: make([]string, 0, maxLines),
+	}
+}
+
+func (t *outputTail) Writer(stream string) io.Writer {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	w := &tailWriter{tail: t, stream: stream}
+	t.writers = append(t.writers, w)
+	return w
+}
+
+// Excerpt returns the sanitized tail of the output, one line per recorded
+// line, oldest first.
+func (t *outputTail) Excerpt() string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, w := range t.writers {
+		w.flush()
+	}
+
+	lines := append([]string{}, t.lines[t.next:]...)
+	lines = append(lines, t.lines[:t.next]...)
+	return strings.Join(lines, "\n")
+}
+
+func (t *outputTail) add(stream string, line []byte) {
+	sanitized := sanitizeOutputLine(line)
+	if sanitized == "" {
+		return
+	}
+	sanitized = "[" + stream + "] " + sanitized
+
+	if len(t.lines) < cap(t.lines) {
+		t.lines = append(t.lines, sanitized)
+		return
+	}
+
+	if len(t.lines) == 0 {
+		return
+	}
+
+	t.lines[t.next] = sanitized
+	t.next = (t.next + 1) % len(t.lines)
+}
+
+type tailWriter struct {
+	tail    *outputTail
+	stream  string
+	partial []byte
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.tail.lock.Lock()
+	defer w.tail.lock.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+
+		chunk := p
+		if i >= 0 {
+			chunk = p[:i]
+		}
+
+		// the rest of overly long lines is dropped
+		if room := outputTailMaxLineLength - len(w.partial); room > 0 {
+			if len(chunk) > room {
+				chunk = chunk[:room]
+			}
+			w.partial = append(w.partial, chunk...)
+		}
+
+		if i < 0 {
+			break
+		}
+
+		w.flush()
+		p = p[i+1:]
+	}
+
+	return n, nil
+}
+
+// flush must be called with the tail lock held
+func (w *tailWriter) flush() {
+	if len(w.partial) == 0 {
+		return
+	}
+
+	w.tail.add(w.stream, w.partial)
+	w.partial = w.partial[:0]
+}
+
+func sanitizeOutputLine(line []byte) string {
+	s := strings.ToValidUTF8(string(line), "�")
+	s = ansiEscapeSequence.ReplaceAllString(s, "")
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return ' '
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+
+	return strings.TrimSpace(s)
+}
@@ -8,18 +8,26 @@ import (
 )
 
 type parallelStep struct {
-	substeps []ifrit.Runner
+	substeps       []ifrit.Runner
+	maxConcurrency int
 }
 
 func NewParallel(substeps []ifrit.Runner) *parallelStep {
+	return NewParallelWithLimit(substeps, 0)
+}
+
+// NewParallelWithLimit runs at most maxConcurrency substeps at a time. A
+// maxConcurrency of 0 runs all substeps at once.
+func NewParallelWithLimit(substeps []ifrit.Runner, maxConcurrency int) *parallelStep {
 	return &parallelStep{
-		substeps: substeps,
+		substeps:       substeps,
+		maxConcurrency: maxConcurrency,
 	}
 }
 
 func (step *parallelStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
 	var subProcesses []ifrit.Process
-	for _, subStep := range step.substeps {
+	for _, subStep := range limitConcurrency(step.substeps, step.maxConcurrency) {
 		subProcesses = append(subProcesses, ifrit.Background(subStep))
 	}
 
@@ -68,3 +76,35 @@ func waitForChildrenToBeReady(done <-chan struct{}, ps []ifrit.Process, ready ch
 	}
 	close(ready)
 }
+
+type limitedStep struct {
+	substep ifrit.Runner
+	slots   chan struct{}
+}
+
+// limitConcurrency makes the substeps wait for one of maxConcurrency slots
+// before they run. A substep that is signalled while waiting is cancelled
+// without running.
+func limitConcurrency(substeps []ifrit.Runner, maxConcurrency int) []ifrit.Runner {
+	if maxConcurrency <= 0 || maxConcurrency >= len(substeps) {
+		return substeps
+	}
+
+	slots := make(chan struct{}, maxConcurrency)
+	limited := make([]ifrit.Runner, len(substeps))
+	for i, substep := range substeps {
+		limited[i] = &limitedStep{substep: substep, slots: slots}
+	}
+	return limited
+}
+
+func (step *limitedStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	select {
+	case step.slots <- struct{}{}:
+	case <-signals:
+		return new(CancelledError)
+	}
+	defer func() { <-step.slots }()
+
+	return step.substep.Run(signals, ready)
+}
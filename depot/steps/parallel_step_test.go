@@ -128,3 +128,85 @@ var _ = Describe("ParallelStep", func() {
 		})
 	})
 })
+
+var _ = Describe("ParallelStepWithLimit", func() {
+	var (
+		process ifrit.Process
+
+		subSteps []*fake_runner.TestRunner
+	)
+
+	BeforeEach(func() {
+		subSteps = []*fake_runner.TestRunner{
+			fake_runner.NewTestRunner(),
+			fake_runner.NewTestRunner(),
+			fake_runner.NewTestRunner(),
+		}
+		process = ifrit.Background(steps.NewParallelWithLimit(
+			[]ifrit.Runner{subSteps[0], subSteps[1], subSteps[2]},
+			2,
+		))
+	})
+
+	AfterEach(func() {
+		for _, subStep := range subSteps {
+			subStep.EnsureExit()
+		}
+	})
+
+	runCount := func() int {
+		count := 0
+		for _, subStep := range subSteps {
+			count += subStep.RunCallCount()
+		}
+		return count
+	}
+
+	It("runs at most the limit of substeps at a time", func() {
+		Eventually(runCount).Should(Equal(2))
+		Consistently(runCount).Should(Equal(2))
+
+		exited := 0
+		for i, subStep := range subSteps {
+			if subStep.RunCallCount() == 1 {
+				subStep.TriggerExit(errors.New("oh no"))
+				exited = i
+				break
+			}
+		}
+		Eventually(runCount).Should(Equal(3))
+
+		for i, subStep := range subSteps {
+			if i != exited {
+				subStep.TriggerExit(errors.New("oh my"))
+			}
+		}
+
+		var err error
+		Eventually(process.Wait()).Should(Receive(&err))
+		Expect(err.(*multierror.Error).WrappedErrors()).To(HaveLen(3))
+	})
+
+	Context("when told to cancel", func() {
+		It("cancels the running substeps and never runs the waiting one", func() {
+			Eventually(runCount).Should(Equal(2))
+			process.Signal(os.Interrupt)
+
+			for _, subStep := range subSteps {
+				if subStep.RunCallCount() == 1 {
+					Eventually(subStep.WaitForCall()).Should(Receive(Equal(os.Interrupt)))
+					subStep.TriggerExit(new(steps.CancelledError))
+				}
+			}
+
+			var err error
+			Eventually(process.Wait()).Should(Receive(&err))
+			Expect(err.(*multierror.Error).WrappedErrors()).To(ConsistOf(
+				new(steps.CancelledError),
+				new(steps.CancelledError),
+				new(steps.CancelledError),
+			))
+			Expect(runCount()).To(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,68 @@
+package steps
+
+import (
+	"os"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/tedsuo/ifrit"
+)
+
+type preStopStep struct {
+	substep ifrit.Runner
+	hook    ifrit.Runner
+	logger  lager.Logger
+}
+
+// This step runs hook when it is first signalled and forwards the signal to
+// the substep once the hook has exited. The hook is expected to bound its own
+// duration.
+func NewPreStop(substep, hook ifrit.Runner, logger lager.Logger) ifrit.Runner {
+	return &preStopStep{
+		substep: substep,
+		hook:    hook,
+		logger:  logger.Session("pre-stop-step"),
+	}
+}
+
+func (step *preStopStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	process := ifrit.Background(step.substep)
+	subStepReady := process.Ready()
+	hookRan := false
+
+	for {
+		select {
+		case <-subStepReady:
+			close(ready)
+			subStepReady = nil
+
+		case err := <-process.Wait():
+			return err
+
+		case sig := <-signals:
+			if !hookRan {
+				hookRan = true
+				step.runHook(process)
+			}
+			process.Signal(sig)
+		}
+	}
+}
+
+func (step *preStopStep) runHook(process ifrit.Process) {
+	step.logger.Info("running-hook")
+
+	hookProcess := ifrit.Background(step.hook)
+	select {
+	case err := <-hookProcess.Wait():
+		if err != nil {
+			step.logger.Error("hook-failed", err)
+			return
+		}
+		step.logger.Info("hook-succeeded")
+
+	case <-process.Wait():
+		step.logger.Info("substep-exited-during-hook")
+		hookProcess.Signal(os.Interrupt)
+		<-hookProcess.Wait()
+	}
+}
@@ -0,0 +1,99 @@
+package steps_test
+
+import (
+	"errors"
+	"os"
+
+	"code.cloudfoundry.org/executor/depot/steps"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/fake_runner"
+)
+
+var _ = Describe("PreStopStep", func() {
+	var (
+		substep *fake_runner.TestRunner
+		hook    *fake_runner.TestRunner
+		logger  *lagertest.TestLogger
+		process ifrit.Process
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		substep = fake_runner.NewTestRunner()
+		hook = fake_runner.NewTestRunner()
+	})
+
+	JustBeforeEach(func() {
+		process = ifrit.Background(steps.NewPreStop(substep, hook, logger))
+		Eventually(substep.RunCallCount).Should(Equal(1))
+	})
+
+	AfterEach(func() {
+		substep.EnsureExit()
+		hook.EnsureExit()
+	})
+
+	It("becomes ready when the substep is ready", func() {
+		Consistently(process.Ready()).ShouldNot(BeClosed())
+		substep.TriggerReady()
+		Eventually(process.Ready()).Should(BeClosed())
+	})
+
+	It("returns the result of the substep without running the hook", func() {
+		substep.TriggerExit(errors.New("boom"))
+		Eventually(process.Wait()).Should(Receive(MatchError("boom")))
+		Expect(hook.RunCallCount()).To(BeZero())
+	})
+
+	Context("when signalled", func() {
+		var substepSignals <-chan os.Signal
+
+		JustBeforeEach(func() {
+			substepSignals = substep.WaitForCall()
+			process.Signal(os.Interrupt)
+			Eventually(hook.RunCallCount).Should(Equal(1))
+		})
+
+		It("runs the hook before signalling the substep", func() {
+			Consistently(substepSignals).ShouldNot(Receive())
+
+			hook.TriggerExit(nil)
+			Eventually(substepSignals).Should(Receive(Equal(os.Interrupt)))
+
+			substep.TriggerExit(nil)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+		})
+
+		Context("when the hook fails", func() {
+			It("still signals the substep", func() {
+				hook.TriggerExit(errors.New("hook failed"))
+				Eventually(substepSignals).Should(Receive(Equal(os.Interrupt)))
+			})
+		})
+
+		Context("when the substep exits while the hook is running", func() {
+			It("stops the hook and returns the result of the substep", func() {
+				hookSignals := hook.WaitForCall()
+				substep.TriggerExit(errors.New("exited"))
+
+				Eventually(hookSignals).Should(Receive(Equal(os.Interrupt)))
+				hook.TriggerExit(nil)
+
+				Eventually(process.Wait()).Should(Receive(MatchError("exited")))
+			})
+		})
+
+		It("only runs the hook once", func() {
+			hook.TriggerExit(nil)
+			Eventually(substepSignals).Should(Receive())
+
+			process.Signal(os.Kill)
+			Eventually(substepSignals).Should(Receive(Equal(os.Kill)))
+			Expect(hook.RunCallCount()).To(Equal(1))
+		})
+	})
+})
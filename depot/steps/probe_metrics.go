@@ -0,0 +1,102 @@
+package steps
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/executor/depot/metrics"
+	loggregator "code.cloudfoundry.org/go-loggregator/v8"
+	"code.cloudfoundry.org/lager"
+	"github.com/tedsuo/ifrit"
+)
+
+const (
+	HealthCheckProbeLatency   = "HealthCheckProbeLatency"
+	HealthCheckProbeSucceeded = "HealthCheckProbeSucceeded"
+
+	ReadinessProbe = "readiness"
+	LivenessProbe  = "liveness"
+)
+
+// ProbeMetrics emits the latency and outcome of the health check probes of a
+// container, tagged with the metric tags of the container, and feeds the
+// latency into the histogram of the cell. A nil *ProbeMetrics emits nothing.
+type ProbeMetrics struct {
+	metronClient loggingclient.IngressClient
+	tags         map[string]string
+	histogram    *metrics.ProbeLatencyReporter
+	logger       lager.Logger
+}
+
+func NewProbeMetrics(
+	metronClient loggingclient.IngressClient,
+	tags map[string]string,
+	histogram *metrics.ProbeLatencyReporter,
+	logger lager.Logger,
+) *ProbeMetrics {
+	if metronClient == nil {
+		return nil
+	}
+
+	return &ProbeMetrics{
+		metronClient: metronClient,
+		tags:         tags,
+		histogram:    histogram,
+		logger:       logger.Session("probe-metrics"),
+	}
+}
+
+func (m *ProbeMetrics) report(probe string, latency time.Duration, err error) {
+	tags := map[string]string{"probe": probe}
+	for k, v := range m.tags {
+		tags[k] = v
+	}
+	tagOption := loggregator.WithEnvelopeTags(tags)
+
+	succeeded := 1
+	if err != nil {
+		succeeded = 0
+	}
+
+	if sendErr := m.metronClient.SendDuration(HealthCheckProbeLatency, latency, tagOption); sendErr != nil {
+		m.logger.Error("failed-to-send-probe-latency-metric", sendErr, lager.Data{"probe": probe})
+	}
+	if sendErr := m.metronClient.SendMetric(HealthCheckProbeSucceeded, succeeded, tagOption); sendErr != nil {
+		m.logger.Error("failed-to-send-probe-outcome-metric", sendErr, lager.Data{"probe": probe})
+	}
+
+	m.histogram.Observe(probe, latency)
+}
+
+type reportProbeStep struct {
+	substep ifrit.Runner
+	probe   string
+	metrics *ProbeMetrics
+	clock   clock.Clock
+}
+
+// NewReportProbe reports the latency and outcome of every run of substep that
+// is not cancelled, as a probe of the given kind.
+func NewReportProbe(substep ifrit.Runner, probe string, metrics *ProbeMetrics, clock clock.Clock) ifrit.Runner {
+	if metrics == nil {
+		return substep
+	}
+
+	return &reportProbeStep{
+		substep: substep,
+		probe:   probe,
+		metrics: metrics,
+		clock:   clock,
+	}
+}
+
+func (step *reportProbeStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	start := step.clock.Now()
+	err := step.substep.Run(signals, ready)
+	if _, cancelled := err.(*CancelledError); !cancelled {
+		step.metrics.report(step.probe, step.clock.Since(start), err)
+	}
+	return err
+}
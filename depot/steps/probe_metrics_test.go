@@ -0,0 +1,83 @@
+package steps_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/executor/depot/steps"
+	loggregator "code.cloudfoundry.org/go-loggregator/v8"
+	"code.cloudfoundry.org/go-loggregator/v8/rpc/loggregator_v2"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/fake_runner"
+)
+
+func envelopeTags(opts []loggregator.EmitGaugeOption) map[string]string {
+	e := &loggregator_v2.Envelope{Tags: map[string]string{}}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e.Tags
+}
+
+var _ = Describe("ReportProbeStep", func() {
+	var (
+		fakeStep         *fake_runner.TestRunner
+		fakeClock        *fakeclock.FakeClock
+		fakeMetronClient *mfakes.FakeIngressClient
+		process          ifrit.Process
+	)
+
+	BeforeEach(func() {
+		fakeStep = fake_runner.NewTestRunner()
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeMetronClient = new(mfakes.FakeIngressClient)
+
+		probeMetrics := steps.NewProbeMetrics(
+			fakeMetronClient,
+			map[string]string{"source_id": "some-app"},
+			nil,
+			lagertest.NewTestLogger("test"),
+		)
+		process = ifrit.Background(steps.NewReportProbe(fakeStep, steps.LivenessProbe, probeMetrics, fakeClock))
+		Eventually(fakeStep.RunCallCount).Should(Equal(1))
+	})
+
+	It("reports the latency of the probe with the tags of the container", func() {
+		fakeClock.Increment(250 * time.Millisecond)
+		fakeStep.TriggerExit(nil)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+
+		Expect(fakeMetronClient.SendDurationCallCount()).To(Equal(1))
+		name, latency, opts := fakeMetronClient.SendDurationArgsForCall(0)
+		Expect(name).To(Equal(steps.HealthCheckProbeLatency))
+		Expect(latency).To(Equal(250 * time.Millisecond))
+		Expect(envelopeTags(opts)).To(Equal(map[string]string{"source_id": "some-app", "probe": "liveness"}))
+
+		Expect(fakeMetronClient.SendMetricCallCount()).To(Equal(1))
+		name, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+		Expect(name).To(Equal(steps.HealthCheckProbeSucceeded))
+		Expect(value).To(Equal(1))
+	})
+
+	It("reports a failed probe", func() {
+		fakeStep.TriggerExit(errors.New("BOOOOM"))
+		Eventually(process.Wait()).Should(Receive(MatchError("BOOOOM")))
+
+		Expect(fakeMetronClient.SendMetricCallCount()).To(Equal(1))
+		_, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+		Expect(value).To(Equal(0))
+	})
+
+	It("does not report a cancelled probe", func() {
+		fakeStep.TriggerExit(new(steps.CancelledError))
+		Eventually(process.Wait()).Should(Receive())
+
+		Expect(fakeMetronClient.SendDurationCallCount()).To(BeZero())
+		Expect(fakeMetronClient.SendMetricCallCount()).To(BeZero())
+	})
+})
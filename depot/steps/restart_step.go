@@ -0,0 +1,105 @@
+package steps
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+	"github.com/tedsuo/ifrit"
+)
+
+const (
+	DefaultRestartInitialBackoff = 1 * time.Second
+	DefaultRestartMaxBackoff     = 5 * time.Minute
+)
+
+type restartStep struct {
+	create    func() ifrit.Runner
+	policy    executor.RestartPolicy
+	onRestart func(restartCount int, err error)
+	clock     clock.Clock
+	logger    lager.Logger
+}
+
+// This step re-runs the substep returned by create whenever it exits and the
+// restart policy allows it. onRestart is invoked before every restart with the
+// number of restarts so far and the error the previous run exited with.
+func NewRestart(
+	create func() ifrit.Runner,
+	policy executor.RestartPolicy,
+	onRestart func(restartCount int, err error),
+	clock clock.Clock,
+	logger lager.Logger,
+) ifrit.Runner {
+	return &restartStep{
+		create:    create,
+		policy:    policy,
+		onRestart: onRestart,
+		clock:     clock,
+		logger:    logger.Session("restart-step"),
+	}
+}
+
+func (step *restartStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	loop := newBackoffLoop(signals, ready, step.clock, step.logger)
+	restartCount := 0
+
+	for {
+		signalled, err := loop.run(step.create())
+		if signalled {
+			return err
+		}
+
+		if !step.shouldRestart(restartCount, err) {
+			return err
+		}
+
+		restartCount++
+		backoff := step.backoff(restartCount)
+
+		logData := lager.Data{"restart-count": restartCount, "backoff": backoff.String()}
+		if err != nil {
+			logData["error"] = err.Error()
+		}
+		step.logger.Info("restarting", logData)
+
+		if !loop.wait(backoff) {
+			return new(CancelledError)
+		}
+
+		if step.onRestart != nil {
+			step.onRestart(restartCount, err)
+		}
+	}
+}
+
+func (step *restartStep) shouldRestart(restartCount int, err error) bool {
+	if step.policy.MaxRestarts > 0 && restartCount >= step.policy.MaxRestarts {
+		return false
+	}
+
+	switch step.policy.Policy {
+	case executor.RestartPolicyAlways:
+		return true
+	case executor.RestartPolicyOnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+func (step *restartStep) backoff(restartCount int) time.Duration {
+	initial := DefaultRestartInitialBackoff
+	if step.policy.InitialBackoffMs > 0 {
+		initial = time.Duration(step.policy.InitialBackoffMs) * time.Millisecond
+	}
+
+	max := DefaultRestartMaxBackoff
+	if step.policy.MaxBackoffMs > 0 {
+		max = time.Duration(step.policy.MaxBackoffMs) * time.Millisecond
+	}
+
+	return exponentialBackoff(initial, max, restartCount)
+}
@@ -0,0 +1,183 @@
+package steps_test
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/depot/steps"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/fake_runner"
+)
+
+var _ = Describe("RestartStep", func() {
+	var (
+		step    ifrit.Runner
+		process ifrit.Process
+
+		policy     executor.RestartPolicy
+		fakeRunner *fake_runner.TestRunner
+		fakeClock  *fakeclock.FakeClock
+		logger     *lagertest.TestLogger
+
+		restartsLock sync.Mutex
+		restarts     []int
+	)
+
+	restartCounts := func() []int {
+		restartsLock.Lock()
+		defer restartsLock.Unlock()
+		return append([]int{}, restarts...)
+	}
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeRunner = fake_runner.NewTestRunner()
+		logger = lagertest.NewTestLogger("test")
+		restarts = nil
+		policy = executor.RestartPolicy{
+			Policy:           executor.RestartPolicyOnFailure,
+			InitialBackoffMs: 1000,
+			MaxBackoffMs:     3000,
+		}
+	})
+
+	JustBeforeEach(func() {
+		step = steps.NewRestart(
+			func() ifrit.Runner { return fakeRunner },
+			policy,
+			func(restartCount int, err error) {
+				restartsLock.Lock()
+				defer restartsLock.Unlock()
+				restarts = append(restarts, restartCount)
+			},
+			fakeClock,
+			logger,
+		)
+		process = ifrit.Background(step)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Kill)
+		fakeRunner.EnsureExit()
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	It("runs the substep", func() {
+		Eventually(fakeRunner.RunCallCount).Should(Equal(1))
+	})
+
+	It("becomes ready when the substep is ready", func() {
+		Eventually(fakeRunner.RunCallCount).Should(Equal(1))
+		Consistently(process.Ready()).ShouldNot(BeClosed())
+		fakeRunner.TriggerReady()
+		Eventually(process.Ready()).Should(BeClosed())
+	})
+
+	Context("when the substep fails", func() {
+		JustBeforeEach(func() {
+			Eventually(fakeRunner.RunCallCount).Should(Equal(1))
+			fakeRunner.TriggerExit(errors.New("boom"))
+		})
+
+		It("restarts the substep after the initial backoff", func() {
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+			Eventually(fakeRunner.RunCallCount).Should(Equal(2))
+			Expect(restartCounts()).To(Equal([]int{1}))
+		})
+
+		It("doubles the backoff on every restart up to the maximum", func() {
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+			Eventually(fakeRunner.RunCallCount).Should(Equal(2))
+			fakeRunner.TriggerExit(errors.New("boom"))
+
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+			Consistently(fakeRunner.RunCallCount).Should(Equal(2))
+			fakeClock.Increment(time.Second)
+			Eventually(fakeRunner.RunCallCount).Should(Equal(3))
+			fakeRunner.TriggerExit(errors.New("boom"))
+
+			fakeClock.WaitForWatcherAndIncrement(2 * time.Second)
+			Consistently(fakeRunner.RunCallCount).Should(Equal(3))
+			fakeClock.Increment(time.Second)
+			Eventually(fakeRunner.RunCallCount).Should(Equal(4))
+			Expect(restartCounts()).To(Equal([]int{1, 2, 3}))
+		})
+
+		Context("when signalled during the backoff", func() {
+			It("exits with a cancelled error", func() {
+				fakeClock.WaitForWatcher()
+				process.Signal(os.Interrupt)
+				Eventually(process.Wait()).Should(Receive(MatchError(new(steps.CancelledError))))
+				Expect(restartCounts()).To(BeEmpty())
+			})
+		})
+
+		Context("when the max restarts is reached", func() {
+			BeforeEach(func() {
+				policy.MaxRestarts = 1
+			})
+
+			It("exits with the substep error", func() {
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(fakeRunner.RunCallCount).Should(Equal(2))
+				fakeRunner.TriggerExit(errors.New("boom again"))
+				Eventually(process.Wait()).Should(Receive(MatchError("boom again")))
+			})
+		})
+	})
+
+	Context("when the substep exits successfully", func() {
+		JustBeforeEach(func() {
+			Eventually(fakeRunner.RunCallCount).Should(Equal(1))
+			fakeRunner.TriggerExit(nil)
+		})
+
+		Context("and the policy is on-failure", func() {
+			It("exits without restarting", func() {
+				Eventually(process.Wait()).Should(Receive(BeNil()))
+				Expect(restartCounts()).To(BeEmpty())
+			})
+		})
+
+		Context("and the policy is always", func() {
+			BeforeEach(func() {
+				policy.Policy = executor.RestartPolicyAlways
+			})
+
+			It("restarts the substep", func() {
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(fakeRunner.RunCallCount).Should(Equal(2))
+			})
+		})
+	})
+
+	Context("when the policy is never", func() {
+		BeforeEach(func() {
+			policy.Policy = executor.RestartPolicyNever
+		})
+
+		It("exits with the substep error", func() {
+			Eventually(fakeRunner.RunCallCount).Should(Equal(1))
+			fakeRunner.TriggerExit(errors.New("boom"))
+			Eventually(process.Wait()).Should(Receive(MatchError("boom")))
+		})
+	})
+
+	Context("when signalled while the substep is running", func() {
+		It("forwards the signal and returns the substep result", func() {
+			signals := fakeRunner.WaitForCall()
+			process.Signal(os.Interrupt)
+			Eventually(signals).Should(Receive(Equal(os.Interrupt)))
+			fakeRunner.TriggerExit(errors.New("interrupted"))
+			Eventually(process.Wait()).Should(Receive(MatchError("interrupted")))
+		})
+	})
+})
@@ -0,0 +1,141 @@
+package steps
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/depot/log_streamer"
+	"code.cloudfoundry.org/lager"
+	"github.com/hashicorp/errwrap"
+	"github.com/tedsuo/ifrit"
+)
+
+const (
+	DefaultRetryInitialBackoff = 1 * time.Second
+	DefaultRetryMaxBackoff     = 1 * time.Minute
+)
+
+type retryStep struct {
+	create      func() ifrit.Runner
+	policy      executor.RetryPolicy
+	isRetryable func(error) bool
+	streamer    log_streamer.LogStreamer
+	clock       clock.Clock
+	logger      lager.Logger
+}
+
+// This step re-runs the substep returned by create until it succeeds, the
+// error it fails with is not retryable or MaxAttempts attempts have been made.
+// A nil isRetryable retries every error accepted by IsRetryable.
+func NewRetry(
+	create func() ifrit.Runner,
+	policy executor.RetryPolicy,
+	isRetryable func(error) bool,
+	streamer log_streamer.LogStreamer,
+	clock clock.Clock,
+	logger lager.Logger,
+) ifrit.Runner {
+	if isRetryable == nil {
+		isRetryable = IsRetryable
+	}
+
+	return &retryStep{
+		create:      create,
+		policy:      policy,
+		isRetryable: isRetryable,
+		streamer:    streamer,
+		clock:       clock,
+		logger:      logger.Session("retry-step"),
+	}
+}
+
+type IsRetryableError interface {
+	IsRetryable() bool
+}
+
+// Cancellations are never retried; any other error is retried unless it, or
+// one of the errors it wraps, reports otherwise through IsRetryableError.
+func IsRetryable(err error) bool {
+	switch e := err.(type) {
+	case *CancelledError:
+		return false
+	case IsRetryableError:
+		return e.IsRetryable()
+	case *EmittableError:
+		return IsRetryable(e.WrappedError())
+	case errwrap.Wrapper:
+		for _, wrapped := range e.WrappedErrors() {
+			if !IsRetryable(wrapped) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (step *retryStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	maxAttempts := step.policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	loop := newBackoffLoop(signals, ready, step.clock, step.logger)
+
+	for attempt := 1; ; attempt++ {
+		step.logger.Info("starting-attempt", lager.Data{"attempt": attempt, "max-attempts": maxAttempts})
+		if attempt > 1 {
+			fmt.Fprintf(step.streamer.Stdout(), "Attempt %d of %d\n", attempt, maxAttempts)
+		}
+
+		signalled, err := loop.run(step.create())
+		if signalled || err == nil {
+			return err
+		}
+
+		if attempt >= maxAttempts || !step.isRetryable(err) {
+			step.logger.Info("giving-up", lager.Data{"attempt": attempt, "error": err.Error()})
+			return err
+		}
+
+		backoff := step.backoff(attempt)
+		step.logger.Info("attempt-failed", lager.Data{
+			"attempt": attempt,
+			"backoff": backoff.String(),
+			"error":   err.Error(),
+		})
+		fmt.Fprintf(step.streamer.Stderr(), "Attempt %d of %d failed, retrying in %s: %s\n", attempt, maxAttempts, backoff, err)
+
+		if !loop.wait(backoff) {
+			return new(CancelledError)
+		}
+	}
+}
+
+func (step *retryStep) backoff(attempt int) time.Duration {
+	initial := DefaultRetryInitialBackoff
+	if step.policy.InitialBackoffMs > 0 {
+		initial = time.Duration(step.policy.InitialBackoffMs) * time.Millisecond
+	}
+
+	max := DefaultRetryMaxBackoff
+	if step.policy.MaxBackoffMs > 0 {
+		max = time.Duration(step.policy.MaxBackoffMs) * time.Millisecond
+	}
+
+	backoff := exponentialBackoff(initial, max, attempt)
+
+	jitter := step.policy.Jitter
+	if jitter > 1 {
+		jitter = 1
+	}
+	if jitter > 0 {
+		backoff -= time.Duration(jitter * rand.Float64() * float64(backoff))
+	}
+
+	return backoff
+}
@@ -0,0 +1,231 @@
+package steps_test
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/depot/chunkeddownloader"
+	"code.cloudfoundry.org/executor/depot/log_streamer/fake_log_streamer"
+	"code.cloudfoundry.org/executor/depot/signatureverifier"
+	"code.cloudfoundry.org/executor/depot/steps"
+	"code.cloudfoundry.org/executor/depot/uploader"
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/hashicorp/go-multierror"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/fake_runner"
+)
+
+type nonRetryableError struct{}
+
+func (nonRetryableError) Error() string     { return "permanent" }
+func (nonRetryableError) IsRetryable() bool { return false }
+
+var _ = Describe("RetryStep", func() {
+	var (
+		step    ifrit.Runner
+		process ifrit.Process
+
+		policy       executor.RetryPolicy
+		fakeRunner   *fake_runner.TestRunner
+		fakeClock    *fakeclock.FakeClock
+		fakeStreamer *fake_log_streamer.FakeLogStreamer
+		stdout       *gbytes.Buffer
+		stderr       *gbytes.Buffer
+		logger       *lagertest.TestLogger
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		fakeRunner = fake_runner.NewTestRunner()
+		logger = lagertest.NewTestLogger("test")
+
+		stdout = gbytes.NewBuffer()
+		stderr = gbytes.NewBuffer()
+		fakeStreamer = new(fake_log_streamer.FakeLogStreamer)
+		fakeStreamer.StdoutReturns(stdout)
+		fakeStreamer.StderrReturns(stderr)
+
+		policy = executor.RetryPolicy{
+			MaxAttempts:      3,
+			InitialBackoffMs: 1000,
+			MaxBackoffMs:     1500,
+		}
+	})
+
+	JustBeforeEach(func() {
+		step = steps.NewRetry(
+			func() ifrit.Runner { return fakeRunner },
+			policy,
+			nil,
+			fakeStreamer,
+			fakeClock,
+			logger,
+		)
+		process = ifrit.Background(step)
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Kill)
+		fakeRunner.EnsureExit()
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	It("becomes ready when the substep is ready", func() {
+		Eventually(fakeRunner.RunCallCount).Should(Equal(1))
+		fakeRunner.TriggerReady()
+		Eventually(process.Ready()).Should(BeClosed())
+	})
+
+	Context("when the substep succeeds", func() {
+		It("exits without retrying", func() {
+			Eventually(fakeRunner.RunCallCount).Should(Equal(1))
+			fakeRunner.TriggerExit(nil)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+			Expect(fakeRunner.RunCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the substep fails", func() {
+		JustBeforeEach(func() {
+			Eventually(fakeRunner.RunCallCount).Should(Equal(1))
+			fakeRunner.TriggerExit(errors.New("boom"))
+		})
+
+		It("logs the failed attempt", func() {
+			Eventually(stderr).Should(gbytes.Say("Attempt 1 of 3 failed, retrying in 1s: boom"))
+		})
+
+		It("retries after the backoff and logs the attempt", func() {
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+			Eventually(fakeRunner.RunCallCount).Should(Equal(2))
+			Expect(stdout).To(gbytes.Say("Attempt 2 of 3"))
+		})
+
+		It("caps the backoff at the maximum", func() {
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+			Eventually(fakeRunner.RunCallCount).Should(Equal(2))
+			fakeRunner.TriggerExit(errors.New("boom"))
+
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+			Consistently(fakeRunner.RunCallCount).Should(Equal(2))
+			fakeClock.Increment(500 * time.Millisecond)
+			Eventually(fakeRunner.RunCallCount).Should(Equal(3))
+		})
+
+		It("exits with the last error once the max attempts are reached", func() {
+			fakeClock.WaitForWatcherAndIncrement(time.Second)
+			Eventually(fakeRunner.RunCallCount).Should(Equal(2))
+			fakeRunner.TriggerExit(errors.New("boom"))
+
+			fakeClock.WaitForWatcherAndIncrement(1500 * time.Millisecond)
+			Eventually(fakeRunner.RunCallCount).Should(Equal(3))
+			fakeRunner.TriggerExit(errors.New("last boom"))
+
+			Eventually(process.Wait()).Should(Receive(MatchError("last boom")))
+		})
+
+		Context("when signalled during the backoff", func() {
+			It("exits with a cancelled error", func() {
+				fakeClock.WaitForWatcher()
+				process.Signal(os.Interrupt)
+				Eventually(process.Wait()).Should(Receive(MatchError(new(steps.CancelledError))))
+			})
+		})
+
+		Context("when jitter is configured", func() {
+			BeforeEach(func() {
+				policy.Jitter = 0.5
+			})
+
+			It("retries no later than the un-jittered backoff", func() {
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(fakeRunner.RunCallCount).Should(Equal(2))
+			})
+		})
+	})
+
+	Context("when the substep fails with a non retryable error", func() {
+		It("exits without retrying", func() {
+			Eventually(fakeRunner.RunCallCount).Should(Equal(1))
+			fakeRunner.TriggerExit(nonRetryableError{})
+			Eventually(process.Wait()).Should(Receive(Equal(nonRetryableError{})))
+			Expect(fakeRunner.RunCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when signalled while the substep is running", func() {
+		It("forwards the signal and returns the substep result", func() {
+			signals := fakeRunner.WaitForCall()
+			process.Signal(os.Interrupt)
+			Eventually(signals).Should(Receive(Equal(os.Interrupt)))
+			fakeRunner.TriggerExit(errors.New("interrupted"))
+			Eventually(process.Wait()).Should(Receive(MatchError("interrupted")))
+		})
+	})
+})
+
+var _ = Describe("IsRetryable", func() {
+	It("retries errors that do not classify themselves", func() {
+		Expect(steps.IsRetryable(errors.New("boom"))).To(BeTrue())
+	})
+
+	It("does not retry cancellations", func() {
+		Expect(steps.IsRetryable(new(steps.CancelledError))).To(BeFalse())
+	})
+
+	It("does not retry checksum mismatches", func() {
+		err := &chunkeddownloader.ChecksumMismatchError{Algorithm: "sha256", Expected: "a", Actual: "b"}
+		Expect(steps.IsRetryable(err)).To(BeFalse())
+	})
+
+	It("does not retry failed signature verifications", func() {
+		Expect(steps.IsRetryable(signatureverifier.ErrSignatureInvalid)).To(BeFalse())
+		Expect(steps.IsRetryable(signatureverifier.ErrSignatureNotFound)).To(BeFalse())
+		Expect(steps.IsRetryable(signatureverifier.ErrChecksumRequired)).To(BeFalse())
+	})
+
+	It("does not retry invalid steps", func() {
+		Expect(steps.IsRetryable(executor.NewStepsInvalidError([]string{"action: is required"}))).To(BeFalse())
+	})
+
+	Describe("upload errors", func() {
+		It("does not retry client errors", func() {
+			Expect(steps.IsRetryable(&uploader.UploadError{Class: uploader.FailureClientError, StatusCode: 400})).To(BeFalse())
+			Expect(steps.IsRetryable(&uploader.UploadError{Class: uploader.FailureAuth, StatusCode: 403})).To(BeFalse())
+		})
+
+		It("retries timeouts and rate limiting", func() {
+			Expect(steps.IsRetryable(&uploader.UploadError{Class: uploader.FailureClientError, StatusCode: 408})).To(BeTrue())
+			Expect(steps.IsRetryable(&uploader.UploadError{Class: uploader.FailureClientError, StatusCode: 429})).To(BeTrue())
+		})
+
+		It("retries server and network errors", func() {
+			Expect(steps.IsRetryable(&uploader.UploadError{Class: uploader.FailureServerError, StatusCode: 503})).To(BeTrue())
+			Expect(steps.IsRetryable(&uploader.UploadError{Class: uploader.FailureNetwork, Err: errors.New("reset")})).To(BeTrue())
+		})
+	})
+
+	It("classifies the error wrapped by an emittable error", func() {
+		err := steps.NewEmittableError(signatureverifier.ErrSignatureInvalid, "Verifying signature failed")
+		Expect(steps.IsRetryable(err)).To(BeFalse())
+	})
+
+	Describe("aggregated errors", func() {
+		It("does not retry when one of the errors is not retryable", func() {
+			err := multierror.Append(errors.New("boom"), new(steps.CancelledError))
+			Expect(steps.IsRetryable(err)).To(BeFalse())
+		})
+
+		It("retries when all of the errors are retryable", func() {
+			err := multierror.Append(errors.New("boom"), errors.New("bang"))
+			Expect(steps.IsRetryable(err)).To(BeTrue())
+		})
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"time"
@@ -26,8 +27,10 @@ type runStep struct {
 	externalIP               string
 	internalIP               string
 	portMappings             []executor.PortMapping
+	resourceLimits           *executor.ResourceLimits
 	clock                    clock.Clock
 	gracefulShutdownInterval time.Duration
+	stopSignal               garden.Signal
 	suppressExitStatusCode   bool
 	sidecar                  Sidecar
 }
@@ -47,8 +50,10 @@ func NewRun(
 	externalIP string,
 	internalIP string,
 	portMappings []executor.PortMapping,
+	resourceLimits *executor.ResourceLimits,
 	clock clock.Clock,
 	gracefulShutdownInterval time.Duration,
+	stopSignal garden.Signal,
 	suppressExitStatusCode bool,
 ) *runStep {
 	return NewRunWithSidecar(
@@ -59,8 +64,10 @@ func NewRun(
 		externalIP,
 		internalIP,
 		portMappings,
+		resourceLimits,
 		clock,
 		gracefulShutdownInterval,
+		stopSignal,
 		suppressExitStatusCode,
 		Sidecar{},
 		false,
@@ -75,8 +82,10 @@ func NewRunWithSidecar(
 	externalIP string,
 	internalIP string,
 	portMappings []executor.PortMapping,
+	resourceLimits *executor.ResourceLimits,
 	clock clock.Clock,
 	gracefulShutdownInterval time.Duration,
+	stopSignal garden.Signal,
 	suppressExitStatusCode bool,
 	sidecar Sidecar,
 	privileged bool,
@@ -90,8 +99,10 @@ func NewRunWithSidecar(
 		externalIP:               externalIP,
 		internalIP:               internalIP,
 		portMappings:             portMappings,
+		resourceLimits:           resourceLimits,
 		clock:                    clock,
 		gracefulShutdownInterval: gracefulShutdownInterval,
+		stopSignal:               stopSignal,
 		suppressExitStatusCode:   suppressExitStatusCode,
 		sidecar:                  sidecar,
 	}
@@ -116,10 +127,9 @@ func (step *runStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error
 
 	step.logger.Debug("creating-process")
 
-	var nofile *uint64
-	if step.model.ResourceLimits != nil {
-		nofile = step.model.ResourceLimits.GetNofilePtr()
-	}
+	limits := step.processLimits()
+
+	tail := newOutputTail(OutputTailLines)
 
 	var processIO garden.ProcessIO
 	if step.model.SuppressLogOutput {
@@ -129,8 +139,8 @@ func (step *runStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error
 		}
 	} else {
 		processIO = garden.ProcessIO{
-			Stdout: step.streamer.Stdout(),
-			Stderr: step.streamer.Stderr(),
+			Stdout: io.MultiWriter(tail.Writer("stdout"), step.streamer.Stdout()),
+			Stderr: io.MultiWriter(tail.Writer("stderr"), step.streamer.Stderr()),
 		}
 	}
 
@@ -145,9 +155,7 @@ func (step *runStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error
 			Env:  envVars,
 			User: step.model.User,
 
-			Limits: garden.ResourceLimits{
-				Nofile: nofile,
-			},
+			Limits: limits,
 
 			Image:                   step.sidecar.Image,
 			BindMounts:              step.sidecar.BindMounts,
@@ -242,7 +250,7 @@ func (step *runStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error
 
 			if exitStatus != 0 {
 				logger.Error("run-step-failed-with-nonzero-status-code", errors.New(exitErrorMessage), lager.Data{"status-code": exitStatus})
-				return NewEmittableError(nil, emittableExitErrorMessage)
+				return NewEmittableError(nil, emittableExitErrorMessage).WithOutputExcerpt(tail.Excerpt())
 			}
 
 			return nil
@@ -252,8 +260,8 @@ func (step *runStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error
 			return err
 
 		case <-signals:
-			logger.Debug("signalling-terminate")
-			err := process.Signal(garden.SignalTerminate)
+			logger.Debug("signalling-terminate", lager.Data{"signal": step.stopSignal})
+			err := process.Signal(step.stopSignal)
 			if err != nil {
 				logger.Error("signalling-terminate-failed", err)
 			}
@@ -293,6 +301,37 @@ func (step *runStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error
 	}
 }
 
+// processLimits combines the container's resource limits with the nofile
+// limit of the action, which takes precedence.
+func (step *runStep) processLimits() garden.ResourceLimits {
+	var limits garden.ResourceLimits
+	if rl := step.resourceLimits; rl != nil {
+		limits = garden.ResourceLimits{
+			As:         rl.As,
+			Core:       rl.Core,
+			Cpu:        rl.Cpu,
+			Data:       rl.Data,
+			Fsize:      rl.Fsize,
+			Memlock:    rl.Memlock,
+			Msgqueue:   rl.Msgqueue,
+			Nice:       rl.Nice,
+			Nofile:     rl.Nofile,
+			Nproc:      rl.Nproc,
+			Rtprio:     rl.Rtprio,
+			Sigpending: rl.Sigpending,
+			Stack:      rl.Stack,
+		}
+	}
+
+	if step.model.ResourceLimits != nil {
+		if nofile := step.model.ResourceLimits.GetNofilePtr(); nofile != nil {
+			limits.Nofile = nofile
+		}
+	}
+
+	return limits
+}
+
 func convertEnvironmentVariables(environmentVariables []*models.EnvironmentVariable) []string {
 	converted := []string{}
 
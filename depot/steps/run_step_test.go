@@ -34,6 +34,7 @@ var _ = Describe("RunAction", func() {
 		fileDescriptorLimit, processesLimit uint64
 		externalIP, internalIP              string
 		portMappings                        []executor.PortMapping
+		resourceLimits                      *executor.ResourceLimits
 		fakeClock                           *fakeclock.FakeClock
 		suppressExitStatusCode              bool
 
@@ -43,12 +44,14 @@ var _ = Describe("RunAction", func() {
 		sidecar                  steps.Sidecar
 		privileged               bool
 		gracefulShutdownInterval time.Duration = 5 * time.Second
+		stopSignal               garden.Signal
 	)
 
 	BeforeEach(func() {
 		fileDescriptorLimit = 17
 		processesLimit = 1024
 		suppressExitStatusCode = false
+		stopSignal = garden.SignalTerminate
 		testLogSource = "testlogsource"
 		sidecar = steps.Sidecar{}
 
@@ -86,6 +89,7 @@ var _ = Describe("RunAction", func() {
 		externalIP = "external-ip"
 		internalIP = "internal-ip"
 		portMappings = nil
+		resourceLimits = nil
 		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 456))
 	})
 
@@ -105,8 +109,10 @@ var _ = Describe("RunAction", func() {
 			externalIP,
 			internalIP,
 			portMappings,
+			resourceLimits,
 			fakeClock,
 			gracefulShutdownInterval,
+			stopSignal,
 			suppressExitStatusCode,
 			sidecar,
 			privileged,
@@ -425,6 +431,45 @@ var _ = Describe("RunAction", func() {
 			})
 		})
 
+		Context("when container resource limits are configured", func() {
+			var core, stack, nproc, nofile uint64
+
+			BeforeEach(func() {
+				core, stack, nproc, nofile = 0, 8388608, 512, 4096
+				resourceLimits = &executor.ResourceLimits{
+					Core:   &core,
+					Stack:  &stack,
+					Nproc:  &nproc,
+					Nofile: &nofile,
+				}
+				spawnedProcess.WaitReturns(0, nil)
+			})
+
+			It("enforces them on the process", func() {
+				_, spec, _ := gardenClient.Connection.RunArgsForCall(0)
+				Expect(*spec.Limits.Core).To(BeZero())
+				Expect(*spec.Limits.Stack).To(BeEquivalentTo(8388608))
+				Expect(*spec.Limits.Nproc).To(BeEquivalentTo(512))
+				Expect(spec.Limits.As).To(BeNil())
+			})
+
+			It("prefers the file descriptor limit of the action", func() {
+				_, spec, _ := gardenClient.Connection.RunArgsForCall(0)
+				Expect(*spec.Limits.Nofile).To(Equal(fileDescriptorLimit))
+			})
+
+			Context("when the action has no resource limits", func() {
+				BeforeEach(func() {
+					runAction.ResourceLimits = nil
+				})
+
+				It("uses the container's file descriptor limit", func() {
+					_, spec, _ := gardenClient.Connection.RunArgsForCall(0)
+					Expect(*spec.Limits.Nofile).To(BeEquivalentTo(4096))
+				})
+			})
+		})
+
 		Context("when the Garden process has a non-zero exit code", func() {
 			BeforeEach(func() {
 				spawnedProcess.WaitReturns(19, nil)
@@ -451,6 +496,38 @@ var _ = Describe("RunAction", func() {
 					Eventually(process.Wait()).Should(Receive(MatchError(steps.NewEmittableError(nil, errMsg))))
 				})
 			})
+
+			Context("when the process writes output", func() {
+				BeforeEach(func() {
+					gardenClient.Connection.RunStub = func(_ string, _ garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+						for i := 1; i <= steps.OutputTailLines; i++ {
+							fmt.Fprintf(processIO.Stdout, "line %d\n", i)
+						}
+						fmt.Fprint(processIO.Stderr, "\x1b[31mfatal:\x1b[0m something\tbroke\n")
+						fmt.Fprint(processIO.Stderr, "no trailing newline")
+						return spawnedProcess, nil
+					}
+				})
+
+				It("includes the sanitized tail of the output in the error", func() {
+					var err error
+					Eventually(process.Wait()).Should(Receive(&err))
+
+					emittableErr, ok := err.(*steps.EmittableError)
+					Expect(ok).To(BeTrue())
+
+					lines := strings.Split(emittableErr.OutputExcerpt(), "\n")
+					Expect(lines).To(HaveLen(steps.OutputTailLines))
+					Expect(lines[0]).To(Equal("[stdout] line 3"))
+					Expect(lines[len(lines)-2]).To(Equal("[stderr] fatal: something broke"))
+					Expect(lines[len(lines)-1]).To(Equal("[stderr] no trailing newline"))
+				})
+
+				It("still streams the output", func() {
+					Eventually(process.Wait()).Should(Receive())
+					Expect(fakeStreamer.Stdout().(*gbytes.Buffer).Contents()).To(ContainSubstring("line 1\n"))
+				})
+			})
 		})
 
 		Context("readiness", func() {
@@ -709,6 +786,17 @@ var _ = Describe("RunAction", func() {
 				Expect(spawnedProcess.SignalArgsForCall(0)).To(Equal(garden.SignalTerminate))
 			})
 
+			Context("when the stop signal is kill", func() {
+				BeforeEach(func() {
+					stopSignal = garden.SignalKill
+				})
+
+				It("sends a kill to the process", func() {
+					Eventually(spawnedProcess.SignalCallCount).Should(Equal(1))
+					Expect(spawnedProcess.SignalArgsForCall(0)).To(Equal(garden.SignalKill))
+				})
+			})
+
 			Context("when the process exits", func() {
 				It("completes the run without having sent kill", func() {
 					Eventually(spawnedProcess.SignalCallCount).Should(Equal(1))
@@ -0,0 +1,16 @@
+package steps
+
+import "time"
+
+// Schedule decides how long to wait before the next run of a periodic check,
+// given the configured interval.
+type Schedule interface {
+	Interval(base time.Duration) time.Duration
+}
+
+func nextInterval(schedule Schedule, base time.Duration) time.Duration {
+	if schedule == nil {
+		return base
+	}
+	return schedule.Interval(base)
+}
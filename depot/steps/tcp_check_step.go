@@ -0,0 +1,65 @@
+package steps
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/tedsuo/ifrit"
+)
+
+type tcpCheckStep struct {
+	host    string
+	port    int
+	timeout time.Duration
+	logger  lager.Logger
+}
+
+// NewTCPCheck makes a single TCP connection to host and port and fails
+// unless it is established within the timeout. Failures are reported the way
+// the healthcheck sidecar reports them.
+func NewTCPCheck(host string, port int, timeout time.Duration, logger lager.Logger) ifrit.Runner {
+	return &tcpCheckStep{
+		host:    host,
+		port:    port,
+		timeout: timeout,
+		logger:  logger.Session("tcp-check", lager.Data{"port": port}),
+	}
+}
+
+func (step *tcpCheckStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), step.timeout)
+	defer cancel()
+
+	close(ready)
+
+	resultCh := make(chan error, 1)
+	go func() {
+		resultCh <- step.check(ctx)
+	}()
+
+	select {
+	case err := <-resultCh:
+		return err
+	case <-signals:
+		cancel()
+		<-resultCh
+		return new(CancelledError)
+	}
+}
+
+func (step *tcpCheckStep) check(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(step.host, strconv.Itoa(step.port)))
+	if err != nil {
+		reason := probeFailureReason(ctx, err, step.timeout)
+		step.logger.Info("failed", lager.Data{"reason": reason})
+		return NewEmittableError(err, "Failed to make TCP connection to port %d: %s", step.port, reason)
+	}
+
+	conn.Close()
+	return nil
+}
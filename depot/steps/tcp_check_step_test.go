@@ -0,0 +1,59 @@
+package steps_test
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/executor/depot/steps"
+	"code.cloudfoundry.org/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+)
+
+var _ = Describe("TCPCheckStep", func() {
+	var (
+		logger   *lagertest.TestLogger
+		listener net.Listener
+		host     string
+		port     int
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+
+		var err error
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+
+		var portStr string
+		host, portStr, err = net.SplitHostPort(listener.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		port, err = strconv.Atoi(portStr)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		listener.Close()
+	})
+
+	It("succeeds when a connection can be made", func() {
+		process := ifrit.Background(steps.NewTCPCheck(host, port, time.Second, logger))
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+	})
+
+	Context("when nothing is listening", func() {
+		BeforeEach(func() {
+			listener.Close()
+		})
+
+		It("fails the way the healthcheck sidecar does", func() {
+			process := ifrit.Background(steps.NewTCPCheck(host, port, time.Second, logger))
+			var err error
+			Eventually(process.Wait()).Should(Receive(&err))
+			Expect(err).To(MatchError("Failed to make TCP connection to port " + strconv.Itoa(port) + ": connection refused"))
+		})
+	})
+})
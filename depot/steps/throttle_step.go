@@ -3,16 +3,20 @@ package steps
 import (
 	"os"
 
-	"code.cloudfoundry.org/workpool"
 	"github.com/tedsuo/ifrit"
 )
 
+// WorkPool is satisfied by *workpool.WorkPool.
+type WorkPool interface {
+	Submit(work func())
+}
+
 type throttleStep struct {
 	substep  ifrit.Runner
-	workPool *workpool.WorkPool
+	workPool WorkPool
 }
 
-func NewThrottle(substep ifrit.Runner, workPool *workpool.WorkPool) *throttleStep {
+func NewThrottle(substep ifrit.Runner, workPool WorkPool) *throttleStep {
 	return &throttleStep{
 		substep:  substep,
 		workPool: workPool,
@@ -0,0 +1,50 @@
+package steps
+
+import (
+	"context"
+	"os"
+
+	"github.com/tedsuo/ifrit"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type traceStep struct {
+	substep ifrit.Runner
+	ctx     context.Context
+	tracer  trace.Tracer
+	name    string
+	attrs   []attribute.KeyValue
+}
+
+// This step records a span named name, parented by the span in ctx, for the
+// duration of the substep.
+func NewTrace(
+	substep ifrit.Runner,
+	ctx context.Context,
+	tracer trace.Tracer,
+	name string,
+	attrs ...attribute.KeyValue,
+) ifrit.Runner {
+	return &traceStep{
+		substep: substep,
+		ctx:     ctx,
+		tracer:  tracer,
+		name:    name,
+		attrs:   attrs,
+	}
+}
+
+func (step *traceStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	_, span := step.tracer.Start(step.ctx, step.name, trace.WithAttributes(step.attrs...))
+	defer span.End()
+
+	err := step.substep.Run(signals, ready)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
@@ -0,0 +1,80 @@
+package steps_test
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/executor/depot/steps"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/fake_runner"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ = Describe("TraceStep", func() {
+	var (
+		step       ifrit.Runner
+		subStep    *fake_runner.TestRunner
+		exporter   *tracetest.InMemoryExporter
+		parent     context.Context
+		parentSpan trace.Span
+		process    ifrit.Process
+	)
+
+	BeforeEach(func() {
+		subStep = fake_runner.NewTestRunner()
+		exporter = tracetest.NewInMemoryExporter()
+		tracer := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)).Tracer("test")
+
+		parent, parentSpan = tracer.Start(context.Background(), "parent")
+
+		step = steps.NewTrace(subStep, parent, tracer, "some-step", attribute.String("key", "value"))
+	})
+
+	JustBeforeEach(func() {
+		process = ifrit.Background(step)
+	})
+
+	AfterEach(func() {
+		subStep.EnsureExit()
+	})
+
+	It("becomes ready when the substep is ready", func() {
+		Consistently(process.Ready()).ShouldNot(BeClosed())
+		subStep.TriggerReady()
+		Eventually(process.Ready()).Should(BeClosed())
+	})
+
+	It("records a span for the substep as a child of the given context", func() {
+		Eventually(subStep.RunCallCount).Should(Equal(1))
+		Expect(exporter.GetSpans()).To(BeEmpty())
+
+		subStep.TriggerExit(nil)
+		Eventually(process.Wait()).Should(Receive(BeNil()))
+
+		spans := exporter.GetSpans()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Name).To(Equal("some-step"))
+		Expect(spans[0].Parent.SpanID()).To(Equal(parentSpan.SpanContext().SpanID()))
+		Expect(spans[0].Attributes).To(ContainElement(attribute.String("key", "value")))
+	})
+
+	Context("when the substep fails", func() {
+		It("records the error on the span", func() {
+			Eventually(subStep.RunCallCount).Should(Equal(1))
+			subStep.TriggerExit(errors.New("boom"))
+			Eventually(process.Wait()).Should(Receive(MatchError("boom")))
+
+			spans := exporter.GetSpans()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Status.Code).To(Equal(codes.Error))
+			Expect(spans[0].Status.Description).To(Equal("boom"))
+		})
+	})
+})
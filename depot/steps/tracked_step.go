@@ -0,0 +1,145 @@
+package steps
+
+import (
+	"os"
+	"sync"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/executor"
+	"github.com/tedsuo/ifrit"
+)
+
+// StepNode records the status of a step and of its substeps. All methods
+// are safe to call on a nil *StepNode, which tracks nothing.
+type StepNode struct {
+	lock     sync.Mutex
+	status   executor.StepStatus
+	children []*StepNode
+}
+
+func NewStepNode(name string) *StepNode {
+	return &StepNode{
+		status: executor.StepStatus{Name: name, State: executor.StepPending},
+	}
+}
+
+func (n *StepNode) AddChild(name string) *StepNode {
+	if n == nil {
+		return nil
+	}
+
+	child := NewStepNode(name)
+
+	n.lock.Lock()
+	n.children = append(n.children, child)
+	n.lock.Unlock()
+
+	return child
+}
+
+// ClearChildren drops the substeps of the node, for steps that rebuild their
+// substeps on every run.
+func (n *StepNode) ClearChildren() {
+	if n == nil {
+		return
+	}
+
+	n.lock.Lock()
+	n.children = nil
+	n.lock.Unlock()
+}
+
+func (n *StepNode) Status() executor.StepStatus {
+	if n == nil {
+		return executor.StepStatus{}
+	}
+
+	n.lock.Lock()
+	status := n.status
+	children := append([]*StepNode{}, n.children...)
+	n.lock.Unlock()
+
+	status.Children = nil
+	for _, child := range children {
+		status.Children = append(status.Children, child.Status())
+	}
+
+	return status
+}
+
+func (n *StepNode) start(now int64) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.status.State = executor.StepRunning
+	n.status.StartTime = now
+	n.status.EndTime = 0
+	n.status.Error = ""
+}
+
+func (n *StepNode) finish(now int64, err error, signalled bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.status.EndTime = now
+	if err != nil {
+		n.status.Error = err.Error()
+	}
+
+	_, cancelled := err.(*CancelledError)
+	switch {
+	case cancelled || (signalled && err != nil):
+		n.status.State = executor.StepCancelled
+	case err != nil:
+		n.status.State = executor.StepFailed
+	default:
+		n.status.State = executor.StepSucceeded
+	}
+}
+
+type trackedStep struct {
+	node    *StepNode
+	substep ifrit.Runner
+	clock   clock.Clock
+}
+
+// This step reports the progress of the substep to node. The substep is
+// returned unwrapped when node is nil.
+func NewTracked(node *StepNode, substep ifrit.Runner, clock clock.Clock) ifrit.Runner {
+	if node == nil {
+		return substep
+	}
+
+	return &trackedStep{
+		node:    node,
+		substep: substep,
+		clock:   clock,
+	}
+}
+
+func (step *trackedStep) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	step.node.start(step.clock.Now().UnixNano())
+
+	subStepSignals := make(chan os.Signal)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- step.substep.Run(subStepSignals, ready)
+	}()
+
+	signalled := false
+	for {
+		select {
+		case s := <-signals:
+			signalled = true
+			select {
+			case subStepSignals <- s:
+			case err := <-errCh:
+				step.node.finish(step.clock.Now().UnixNano(), err, signalled)
+				return err
+			}
+		case err := <-errCh:
+			step.node.finish(step.clock.Now().UnixNano(), err, signalled)
+			return err
+		}
+	}
+}
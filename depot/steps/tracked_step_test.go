@@ -0,0 +1,121 @@
+package steps_test
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/depot/steps"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/fake_runner"
+)
+
+var _ = Describe("TrackedStep", func() {
+	var (
+		step      ifrit.Runner
+		subStep   *fake_runner.TestRunner
+		node      *steps.StepNode
+		fakeClock *fakeclock.FakeClock
+		process   ifrit.Process
+	)
+
+	BeforeEach(func() {
+		subStep = fake_runner.NewTestRunner()
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		node = steps.NewStepNode("some-step")
+		step = steps.NewTracked(node, subStep, fakeClock)
+	})
+
+	AfterEach(func() {
+		subStep.EnsureExit()
+	})
+
+	It("is pending until it runs", func() {
+		Expect(node.Status()).To(Equal(executor.StepStatus{
+			Name:  "some-step",
+			State: executor.StepPending,
+		}))
+	})
+
+	Context("when running", func() {
+		var startTime time.Time
+
+		JustBeforeEach(func() {
+			startTime = fakeClock.Now()
+			process = ifrit.Background(step)
+			Eventually(subStep.RunCallCount).Should(Equal(1))
+		})
+
+		It("reports the step as running", func() {
+			status := node.Status()
+			Expect(status.State).To(Equal(executor.StepRunning))
+			Expect(status.StartTime).To(Equal(startTime.UnixNano()))
+			Expect(status.EndTime).To(BeZero())
+		})
+
+		It("becomes ready when the substep is ready", func() {
+			subStep.TriggerReady()
+			Eventually(process.Ready()).Should(BeClosed())
+		})
+
+		It("reports the step as succeeded when the substep succeeds", func() {
+			fakeClock.Increment(time.Second)
+			subStep.TriggerExit(nil)
+			Eventually(process.Wait()).Should(Receive(BeNil()))
+
+			status := node.Status()
+			Expect(status.State).To(Equal(executor.StepSucceeded))
+			Expect(status.EndTime).To(Equal(startTime.Add(time.Second).UnixNano()))
+		})
+
+		It("reports the step as failed with the error when the substep fails", func() {
+			subStep.TriggerExit(errors.New("boom"))
+			Eventually(process.Wait()).Should(Receive(MatchError("boom")))
+
+			status := node.Status()
+			Expect(status.State).To(Equal(executor.StepFailed))
+			Expect(status.Error).To(Equal("boom"))
+		})
+
+		It("forwards signals and reports the step as cancelled", func() {
+			process.Signal(os.Interrupt)
+			Eventually(subStep.WaitForCall()).Should(Receive(Equal(os.Interrupt)))
+			subStep.TriggerExit(errors.New("interrupted"))
+			Eventually(process.Wait()).Should(Receive(MatchError("interrupted")))
+
+			Expect(node.Status().State).To(Equal(executor.StepCancelled))
+		})
+	})
+
+	Describe("StepNode", func() {
+		It("includes the status of its children", func() {
+			child := node.AddChild("child")
+			child.AddChild("grandchild")
+
+			status := node.Status()
+			Expect(status.Children).To(HaveLen(1))
+			Expect(status.Children[0].Name).To(Equal("child"))
+			Expect(status.Children[0].Children).To(ConsistOf(executor.StepStatus{
+				Name:  "grandchild",
+				State: executor.StepPending,
+			}))
+		})
+
+		It("can clear its children", func() {
+			node.AddChild("child")
+			node.ClearChildren()
+			Expect(node.Status().Children).To(BeEmpty())
+		})
+
+		It("tracks nothing when nil", func() {
+			var nilNode *steps.StepNode
+			Expect(nilNode.AddChild("child")).To(BeNil())
+			Expect(steps.NewTracked(nilNode, subStep, fakeClock)).To(Equal(subStep))
+		})
+	})
+})
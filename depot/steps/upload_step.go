@@ -4,11 +4,9 @@ import (
 	"archive/tar"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/url"
 	"os"
 
-	"code.cloudfoundry.org/archiver/compressor"
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/bytefmt"
 	"code.cloudfoundry.org/executor/depot/log_streamer"
@@ -22,7 +20,6 @@ type uploadStep struct {
 	container   garden.Container
 	model       models.UploadAction
 	uploader    uploader.Uploader
-	compressor  compressor.Compressor
 	tempDir     string
 	streamer    log_streamer.LogStreamer
 	rateLimiter chan struct{}
@@ -35,7 +32,6 @@ func NewUpload(
 	container garden.Container,
 	model models.UploadAction,
 	uploader uploader.Uploader,
-	compressor compressor.Compressor,
 	tempDir string,
 	streamer log_streamer.LogStreamer,
 	rateLimiter chan struct{},
@@ -49,7 +45,6 @@ func NewUpload(
 		container:   container,
 		model:       model,
 		uploader:    uploader,
-		compressor:  compressor,
 		tempDir:     tempDir,
 		streamer:    streamer,
 		rateLimiter: rateLimiter,
@@ -60,11 +55,8 @@ func NewUpload(
 }
 
 const (
-	ErrCreateTmpDir    = "Failed to create temp dir"
 	ErrEstablishStream = "Failed to establish stream from container"
 	ErrReadTar         = "Failed to find first item in tar stream"
-	ErrCreateTmpFile   = "Failed to create temp file"
-	ErrCopyStreamToTmp = "Failed to copy stream contents into temp file"
 	ErrParsingURL      = "Failed to parse URL"
 )
 
@@ -87,61 +79,32 @@ func (step *uploadStep) Run(signals <-chan os.Signal, ready chan<- struct{}) (er
 		return err
 	}
 
-	tempDir, err := ioutil.TempDir(step.tempDir, "upload")
+	reader, errString, err := step.streamOut()
 	if err != nil {
-		step.logger.Error("failed-to-create-tmp-dir", err)
-		errString := step.artifactErrString(ErrCreateTmpDir)
+		errString = step.artifactErrString(errString)
 		step.emitError(errString)
 		return NewEmittableError(err, errString)
 	}
+	defer reader.Close()
+
+	// the first attempt streams straight out of the container, a retry
+	// streams out again to spool the content into the temp dir
+	streamedOut := false
+	source := func() (io.ReadCloser, error) {
+		if !streamedOut {
+			streamedOut = true
+			return reader, nil
+		}
 
-	defer os.RemoveAll(tempDir)
-
-	outStream, err := step.container.StreamOut(garden.StreamOutSpec{Path: step.model.From, User: step.model.User})
-	if err != nil {
-		step.logger.Error("failed-to-stream-out", err)
-		errString := step.artifactErrString(ErrEstablishStream)
-		step.emitError(errString)
-		return NewEmittableError(err, errString)
-	}
-	defer outStream.Close()
-
-	tarStream := tar.NewReader(outStream)
-	_, err = tarStream.Next()
-
-	if err != nil {
-		step.logger.Error("failed-to-read-stream", err)
-		errString := step.artifactErrString(ErrReadTar)
-		step.emitError(errString)
-		return NewEmittableError(err, errString)
-	}
-
-	tempFile, err := ioutil.TempFile(step.tempDir, "compressed")
-	if err != nil {
-		step.logger.Error("failed-to-create-tmp-dir", err)
-		errString := step.artifactErrString(ErrCreateTmpFile)
-		step.emitError(errString)
-		return NewEmittableError(err, errString)
-	}
-	finalFileLocation := tempFile.Name()
-	defer func() {
-		tempFile.Close()
-		os.Remove(finalFileLocation)
-	}()
-
-	_, err = io.Copy(tempFile, tarStream)
-	if err != nil {
-		step.logger.Error("failed-to-copy-stream", err)
-		errString := step.artifactErrString(ErrCopyStreamToTmp)
-		step.emitError(errString)
-		return NewEmittableError(err, errString)
+		reader, _, err := step.streamOut()
+		return reader, err
 	}
 
 	finished := make(chan struct{})
 	defer close(finished)
 	go step.cancelUploadOnSignal(finished, signals)
 
-	uploadedBytes, err := step.uploader.Upload(finalFileLocation, url, step.cancelUpload)
+	uploadedBytes, err := step.uploader.UploadStream(source, step.tempDir, url, step.cancelUpload)
 	if err != nil {
 		select {
 		case <-step.cancelUpload:
@@ -151,8 +114,14 @@ func (step *uploadStep) Run(signals <-chan os.Signal, ready chan<- struct{}) (er
 			step.logger.Error("failed-to-upload", err)
 
 			// Do not emit error in case it leaks sensitive data in URL
-			step.emitError(step.artifactErrString("Failed to upload payload"))
-
+			errString := step.artifactErrString("Failed to upload payload")
+			if uploadErr, ok := err.(*uploader.UploadError); ok {
+				errString = fmt.Sprintf("%s: %s", errString, describeUploadFailure(uploadErr))
+				step.emitError(errString)
+				return NewEmittableError(err, errString)
+			}
+
+			step.emitError(errString)
 			return err
 		}
 	}
@@ -163,6 +132,26 @@ func (step *uploadStep) Run(signals <-chan os.Signal, ready chan<- struct{}) (er
 	return nil
 }
 
+// streamOut returns a reader of the first file in the streamed out tar, and
+// the message to emit if that fails
+func (step *uploadStep) streamOut() (io.ReadCloser, string, error) {
+	outStream, err := step.container.StreamOut(garden.StreamOutSpec{Path: step.model.From, User: step.model.User})
+	if err != nil {
+		step.logger.Error("failed-to-stream-out", err)
+		return nil, ErrEstablishStream, err
+	}
+
+	tarStream := tar.NewReader(outStream)
+	_, err = tarStream.Next()
+	if err != nil {
+		step.logger.Error("failed-to-read-stream", err)
+		outStream.Close()
+		return nil, ErrReadTar, err
+	}
+
+	return &tarEntryReader{Reader: tarStream, stream: outStream}, "", nil
+}
+
 func (step *uploadStep) cancelUploadOnSignal(finished chan struct{}, signals <-chan os.Signal) {
 	select {
 	case <-signals:
@@ -171,6 +160,21 @@ func (step *uploadStep) cancelUploadOnSignal(finished chan struct{}, signals <-c
 	}
 }
 
+func describeUploadFailure(err *uploader.UploadError) string {
+	switch err.Class {
+	case uploader.FailureAuth:
+		return fmt.Sprintf("not authorized (status code %d)", err.StatusCode)
+	case uploader.FailureClientError:
+		return fmt.Sprintf("rejected by the server (status code %d)", err.StatusCode)
+	case uploader.FailureServerError:
+		return fmt.Sprintf("server error (status code %d)", err.StatusCode)
+	case uploader.FailureTimeout:
+		return "timed out"
+	default:
+		return "network error"
+	}
+}
+
 func (step *uploadStep) emit(format string, a ...interface{}) {
 	if step.model.Artifact != "" {
 		fmt.Fprintf(step.streamer.Stdout(), format, a...)
@@ -189,3 +193,17 @@ func (step *uploadStep) emitError(errString string) {
 		fmt.Fprintln(step.streamer.Stderr(), errString)
 	}
 }
+
+type tarEntryReader struct {
+	io.Reader
+	stream io.Closer
+	closed bool
+}
+
+func (r *tarEntryReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.stream.Close()
+}
@@ -21,7 +21,6 @@ import (
 	"github.com/onsi/gomega/gbytes"
 	"github.com/tedsuo/ifrit"
 
-	Compressor "code.cloudfoundry.org/archiver/compressor"
 	"code.cloudfoundry.org/executor/depot/log_streamer/fake_log_streamer"
 	"code.cloudfoundry.org/executor/depot/steps"
 	Uploader "code.cloudfoundry.org/executor/depot/uploader"
@@ -41,6 +40,12 @@ func (u *fakeUploader) Upload(fileLocation string, destinationUrl *url.URL, canc
 	return 0, nil
 }
 
+func (u *fakeUploader) UploadStream(source Uploader.StreamSource, spoolDir string, destinationUrl *url.URL, cancel <-chan struct{}) (int64, error) {
+	u.ready <- struct{}{}
+	<-u.barrier
+	return 0, nil
+}
+
 func newFakeStreamer() *fake_log_streamer.FakeLogStreamer {
 	fakeStreamer := new(fake_log_streamer.FakeLogStreamer)
 
@@ -61,10 +66,11 @@ var _ = Describe("UploadStep", func() {
 		tempDir         string
 		gardenClient    *fakes.FakeGardenClient
 		logger          *lagertest.TestLogger
-		compressor      Compressor.Compressor
 		fakeStreamer    *fake_log_streamer.FakeLogStreamer
 		uploadTarget    *httptest.Server
 		uploadedPayload []byte
+		uploadedHeaders http.Header
+		tempFiles       []os.FileInfo
 	)
 
 	BeforeEach(func() {
@@ -73,8 +79,12 @@ var _ = Describe("UploadStep", func() {
 		uploadTarget = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 			var err error
 
+			tempFiles, err = ioutil.ReadDir(tempDir)
+			Expect(err).NotTo(HaveOccurred())
+
 			uploadedPayload, err = ioutil.ReadAll(req.Body)
 			Expect(err).NotTo(HaveOccurred())
+			uploadedHeaders = req.Trailer
 
 			w.WriteHeader(http.StatusOK)
 		}))
@@ -92,7 +102,6 @@ var _ = Describe("UploadStep", func() {
 
 		logger = lagertest.NewTestLogger("test")
 
-		compressor = Compressor.NewTgz()
 		uploader = Uploader.New(logger, 5*time.Second, nil)
 
 		fakeStreamer = newFakeStreamer()
@@ -118,7 +127,6 @@ var _ = Describe("UploadStep", func() {
 			container,
 			*uploadAction,
 			uploader,
-			compressor,
 			tempDir,
 			fakeStreamer,
 			make(chan struct{}, 1),
@@ -187,6 +195,14 @@ var _ = Describe("UploadStep", func() {
 				Expect(string(uploadedPayload)).To(Equal("expected-contents"))
 			})
 
+			It("streams the file without writing temp files", func() {
+				err := <-ifrit.Invoke(step).Wait()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(tempFiles).To(BeEmpty())
+				Expect(uploadedHeaders.Get("Digest")).To(HavePrefix("SHA-256="))
+			})
+
 			It("logs the step", func() {
 				err := <-ifrit.Invoke(step).Wait()
 				Expect(err).NotTo(HaveOccurred())
@@ -211,7 +227,7 @@ var _ = Describe("UploadStep", func() {
 
 					cancelled = make(chan struct{})
 
-					fakeUploader.UploadStub = func(from string, dest *url.URL, cancel <-chan struct{}) (int64, error) {
+					fakeUploader.UploadStreamStub = func(source Uploader.StreamSource, spoolDir string, dest *url.URL, cancel <-chan struct{}) (int64, error) {
 						<-cancel
 						close(cancelled)
 						return 0, cancelledErr
@@ -223,7 +239,7 @@ var _ = Describe("UploadStep", func() {
 				It("cancels any in-flight upload", func() {
 					p := ifrit.Background(step)
 
-					Eventually(fakeUploader.UploadCallCount).Should(Equal(1))
+					Eventually(fakeUploader.UploadStreamCallCount).Should(Equal(1))
 
 					Consistently(p.Wait()).ShouldNot(Receive())
 
@@ -235,37 +251,50 @@ var _ = Describe("UploadStep", func() {
 				})
 			})
 
-			Context("when creating a TmpDir fails", func() {
-				var stderr *gbytes.Buffer
+			Context("when the upload has to be retried", func() {
+				var fakeUploader *fake_uploader.FakeUploader
+
 				BeforeEach(func() {
-					tempDir = "doesnotexist"
-					stderr = fakeStreamer.Stderr().(*gbytes.Buffer)
+					fakeUploader = new(fake_uploader.FakeUploader)
+					fakeUploader.UploadStreamStub = func(source Uploader.StreamSource, spoolDir string, dest *url.URL, cancel <-chan struct{}) (int64, error) {
+						for i := 0; i < 2; i++ {
+							reader, err := source()
+							Expect(err).NotTo(HaveOccurred())
+
+							contents, err := ioutil.ReadAll(reader)
+							Expect(err).NotTo(HaveOccurred())
+							Expect(string(contents)).To(Equal("expected-contents"))
+							reader.Close()
+						}
+						return 17, nil
+					}
+					uploader = fakeUploader
+
+					gardenClient.Connection.StreamOutStub = func(handle string, spec garden.StreamOutSpec) (io.ReadCloser, error) {
+						buffer := gbytes.NewBuffer()
+						writeTarContents(buffer)
+						return buffer, nil
+					}
 				})
 
-				It("logs the step", func() {
+				It("streams the file out of the container again", func() {
 					err := <-ifrit.Invoke(step).Wait()
-					Expect(err).To(HaveOccurred())
-					Expect(logger.TestSink.LogMessages()).To(ContainElement("test.upload-step.failed-to-create-tmp-dir"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(gardenClient.Connection.StreamOutCallCount()).To(Equal(2))
 				})
 
-				Context("when there is a named artifact", func() {
-					BeforeEach(func() {
-						uploadAction.Artifact = "artifact"
-					})
-
-					It("should emit an error with the name", func() {
-						err := <-ifrit.Invoke(step).Wait()
-						Expect(err).To(HaveOccurred())
-						Expect(err.Error()).To(ContainSubstring("Failed to create temp dir for artifact"))
-						Expect(stderr).To(gbytes.Say("Failed to create temp dir for artifact\n"))
-					})
+				It("spools into the temp dir", func() {
+					err := <-ifrit.Invoke(step).Wait()
+					Expect(err).NotTo(HaveOccurred())
+					_, spoolDir, _, _ := fakeUploader.UploadStreamArgsForCall(0)
+					Expect(spoolDir).To(Equal(tempDir))
 				})
 			})
 
 			Describe("streaming logs for uploads", func() {
 				BeforeEach(func() {
 					fakeUploader := new(fake_uploader.FakeUploader)
-					fakeUploader.UploadReturns(1024, nil)
+					fakeUploader.UploadStreamReturns(1024, nil)
 					uploader = fakeUploader
 				})
 
@@ -302,15 +331,14 @@ var _ = Describe("UploadStep", func() {
 				})
 			})
 
-			Context("when copying to compressed file fails", func() {
+			Context("when reading the stream fails during the upload", func() {
 				var stderr *gbytes.Buffer
 				BeforeEach(func() {
-					buffer = gbytes.NewBuffer()
-					writeTarContents(buffer)
-
-					brokenStream := ioutil.NopCloser(iotest.TimeoutReader(buffer))
-
-					gardenClient.Connection.StreamOutReturns(brokenStream, nil)
+					gardenClient.Connection.StreamOutStub = func(handle string, spec garden.StreamOutSpec) (io.ReadCloser, error) {
+						buffer := gbytes.NewBuffer()
+						writeTarContents(buffer)
+						return ioutil.NopCloser(iotest.TimeoutReader(buffer)), nil
+					}
 
 					stderr = fakeStreamer.Stderr().(*gbytes.Buffer)
 				})
@@ -318,7 +346,7 @@ var _ = Describe("UploadStep", func() {
 				It("logs the step", func() {
 					err := <-ifrit.Invoke(step).Wait()
 					Expect(err).To(HaveOccurred())
-					Expect(logger.TestSink.LogMessages()).To(ContainElement("test.upload-step.failed-to-copy-stream"))
+					Expect(logger.TestSink.LogMessages()).To(ContainElement("test.upload-step.failed-to-upload"))
 				})
 
 				Context("when there is a named artifact", func() {
@@ -326,22 +354,19 @@ var _ = Describe("UploadStep", func() {
 						uploadAction.Artifact = "artifact"
 					})
 
-					It("logs the error", func() {
+					It("emits the error", func() {
 						err := <-ifrit.Invoke(step).Wait()
 						Expect(err).To(HaveOccurred())
-						Expect(err.Error()).To(ContainSubstring("Failed to copy stream contents into temp file for artifact"))
-						Expect(stderr).To(gbytes.Say("Failed to copy stream contents into temp file for artifact\n"))
+						Expect(stderr).To(gbytes.Say("Failed to upload payload for artifact\n"))
 					})
 				})
 
-				It("clears out the temporary file", func() {
+				It("clears out the spooled file", func() {
 					err := <-ifrit.Invoke(step).Wait()
 					Expect(err).To(HaveOccurred())
 					files, err := ioutil.ReadDir(tempDir)
 					Expect(err).NotTo(HaveOccurred())
-					for _, f := range files {
-						Expect(f.Name()).NotTo(HavePrefix("compressed"))
-					}
+					Expect(files).To(BeEmpty())
 				})
 			})
 
@@ -354,7 +379,7 @@ var _ = Describe("UploadStep", func() {
 
 				BeforeEach(func() {
 					fakeUploader := new(fake_uploader.FakeUploader)
-					fakeUploader.UploadReturns(0, errUploadFailed)
+					fakeUploader.UploadStreamReturns(0, errUploadFailed)
 					uploader = fakeUploader
 				})
 
@@ -387,6 +412,37 @@ var _ = Describe("UploadStep", func() {
 			})
 		})
 
+		Context("when the upload fails with a classified error", func() {
+			var stderr *gbytes.Buffer
+
+			BeforeEach(func() {
+				gardenClient.Connection.StreamOutStub = func(handle string, spec garden.StreamOutSpec) (io.ReadCloser, error) {
+					buffer := gbytes.NewBuffer()
+					tarWriter := tar.NewWriter(buffer)
+					err := tarWriter.WriteHeader(&tar.Header{Name: "./expected-src.txt"})
+					Expect(err).NotTo(HaveOccurred())
+					return buffer, nil
+				}
+
+				fakeUploader := new(fake_uploader.FakeUploader)
+				fakeUploader.UploadStreamReturns(0, &Uploader.UploadError{
+					Class:      Uploader.FailureAuth,
+					StatusCode: http.StatusForbidden,
+					Err:        errors.New("Upload failed: Status code 403"),
+				})
+				uploader = fakeUploader
+
+				uploadAction.Artifact = "artifact"
+				stderr = fakeStreamer.Stderr().(*gbytes.Buffer)
+			})
+
+			It("surfaces the class of the failure", func() {
+				err := <-ifrit.Invoke(step).Wait()
+				Expect(err).To(MatchError("Failed to upload payload for artifact: not authorized (status code 403)"))
+				Expect(stderr).To(gbytes.Say("Failed to upload payload for artifact: not authorized \\(status code 403\\)\n"))
+			})
+		})
+
 		Context("when there is an error parsing the upload url", func() {
 			var stderr *gbytes.Buffer
 			BeforeEach(func() {
@@ -561,7 +617,6 @@ var _ = Describe("UploadStep", func() {
 				container,
 				uploadAction1,
 				uploader,
-				compressor,
 				tempDir,
 				newFakeStreamer(),
 				rateLimiter,
@@ -577,7 +632,6 @@ var _ = Describe("UploadStep", func() {
 				container,
 				uploadAction2,
 				uploader,
-				compressor,
 				tempDir,
 				newFakeStreamer(),
 				rateLimiter,
@@ -593,7 +647,6 @@ var _ = Describe("UploadStep", func() {
 				container,
 				uploadAction3,
 				uploader,
-				compressor,
 				tempDir,
 				newFakeStreamer(),
 				rateLimiter,
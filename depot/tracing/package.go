@@ -0,0 +1 @@
+package tracing // import "code.cloudfoundry.org/executor/depot/tracing"
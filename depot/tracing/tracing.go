@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"code.cloudfoundry.org/executor"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TracerName = "code.cloudfoundry.org/executor"
+
+	// run requests carrying these tags have their container spans recorded
+	// as part of the given trace
+	TraceIDTag = "trace-id"
+	SpanIDTag  = "span-id"
+
+	ExporterNone   = ""
+	ExporterStdout = "stdout"
+)
+
+func NewTracerProvider(exporter string, w io.Writer) (trace.TracerProvider, error) {
+	switch exporter {
+	case ExporterNone:
+		return trace.NewNoopTracerProvider(), nil
+	case ExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		if err != nil {
+			return nil, err
+		}
+		return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp)), nil
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter: %q", exporter)
+	}
+}
+
+func NewTracer(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		provider = trace.NewNoopTracerProvider()
+	}
+	return provider.Tracer(TracerName)
+}
+
+// ContextWithTags returns ctx with a remote parent span taken from the
+// TraceIDTag and SpanIDTag tags. Without a valid trace id ctx is returned
+// unchanged.
+func ContextWithTags(ctx context.Context, tags executor.Tags) context.Context {
+	traceID, err := trace.TraceIDFromHex(tags[TraceIDTag])
+	if err != nil {
+		return ctx
+	}
+
+	spanID, err := trace.SpanIDFromHex(tags[SpanIDTag])
+	if err != nil {
+		_, err = rand.Read(spanID[:])
+		if err != nil {
+			return ctx
+		}
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+}
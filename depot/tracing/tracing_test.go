@@ -0,0 +1,77 @@
+package tracing_test
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/depot/tracing"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ = Describe("Tracing", func() {
+	Describe("NewTracerProvider", func() {
+		It("returns a no-op provider when no exporter is configured", func() {
+			provider, err := tracing.NewTracerProvider(tracing.ExporterNone, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, span := tracing.NewTracer(provider).Start(context.Background(), "span")
+			Expect(span.SpanContext().IsValid()).To(BeFalse())
+		})
+
+		It("writes spans to the writer with the stdout exporter", func() {
+			buffer := gbytes.NewBuffer()
+			provider, err := tracing.NewTracerProvider(tracing.ExporterStdout, buffer)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, span := tracing.NewTracer(provider).Start(context.Background(), "some-span")
+			span.End()
+
+			Eventually(buffer).Should(gbytes.Say("some-span"))
+		})
+
+		It("fails for an unknown exporter", func() {
+			_, err := tracing.NewTracerProvider("carrier-pigeon", nil)
+			Expect(err).To(MatchError(ContainSubstring("carrier-pigeon")))
+		})
+	})
+
+	Describe("ContextWithTags", func() {
+		const (
+			traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+			spanID  = "00f067aa0ba902b7"
+		)
+
+		It("uses the trace and span id from the tags as the remote parent", func() {
+			ctx := tracing.ContextWithTags(context.Background(), executor.Tags{
+				tracing.TraceIDTag: traceID,
+				tracing.SpanIDTag:  spanID,
+			})
+
+			spanContext := trace.SpanContextFromContext(ctx)
+			Expect(spanContext.IsRemote()).To(BeTrue())
+			Expect(spanContext.TraceID().String()).To(Equal(traceID))
+			Expect(spanContext.SpanID().String()).To(Equal(spanID))
+		})
+
+		It("generates a parent span id when only the trace id is given", func() {
+			ctx := tracing.ContextWithTags(context.Background(), executor.Tags{
+				tracing.TraceIDTag: traceID,
+			})
+
+			spanContext := trace.SpanContextFromContext(ctx)
+			Expect(spanContext.IsValid()).To(BeTrue())
+			Expect(spanContext.TraceID().String()).To(Equal(traceID))
+		})
+
+		It("leaves the context untouched without a valid trace id", func() {
+			ctx := tracing.ContextWithTags(context.Background(), executor.Tags{
+				tracing.TraceIDTag: "not-a-trace-id",
+			})
+
+			Expect(trace.SpanContextFromContext(ctx).IsValid()).To(BeFalse())
+		})
+	})
+})
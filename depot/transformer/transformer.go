@@ -2,40 +2,51 @@ package transformer
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"time"
 
-	"code.cloudfoundry.org/archiver/compressor"
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/cacheddownloader"
 	"code.cloudfoundry.org/clock"
 	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/depot/chunkeddownloader"
+	"code.cloudfoundry.org/executor/depot/healthcheckscheduler"
 	"code.cloudfoundry.org/executor/depot/log_streamer"
+	"code.cloudfoundry.org/executor/depot/metrics"
+	"code.cloudfoundry.org/executor/depot/signatureverifier"
 	"code.cloudfoundry.org/executor/depot/steps"
 	"code.cloudfoundry.org/executor/depot/uploader"
 	"code.cloudfoundry.org/garden"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/workpool"
 	"github.com/tedsuo/ifrit"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	healthCheckNofiles                          uint64 = 1024
 	DefaultDeclarativeHealthcheckRequestTimeout        = int(1 * time.Second / time.Millisecond)
 	HealthLogSource                                    = "HEALTH"
+	DefaultPreStopTimeout                              = 30 * time.Second
 )
 
 var ErrNoCheck = errors.New("no check configured")
+var ErrNoAction = errors.New("no action configured")
 var HealthCheckDstPath string = filepath.Join(string(os.PathSeparator), "etc", "cf-assets", "healthcheck")
 
 //go:generate counterfeiter -o faketransformer/fake_transformer.go . Transformer
 
 type Transformer interface {
+	Validate(lager.Logger, executor.RunInfo, executor.Tags) error
 	StepsRunner(lager.Logger, executor.Container, garden.Container, log_streamer.LogStreamer, Config) (ifrit.Runner, error)
 }
 
@@ -44,29 +55,47 @@ type Config struct {
 	BindMounts        []garden.BindMount
 	CreationStartTime time.Time
 	MetronClient      loggingclient.IngressClient
+	OnRestart         func(restartCount int, err error)
+	OnSidecarRestart  func(sidecar int, restartCount int, err error)
+	TraceContext      context.Context
+	StepTree          *steps.StepNode
+	HealthTracker     *steps.HealthTracker
+	OnProgress        steps.ProgressFunc
 }
 
 type transformer struct {
-	cachedDownloader cacheddownloader.CachedDownloader
-	uploader         uploader.Uploader
-	compressor       compressor.Compressor
-	downloadLimiter  chan struct{}
-	uploadLimiter    chan struct{}
-	tempDir          string
-	clock            clock.Clock
+	cachedDownloader  cacheddownloader.CachedDownloader
+	chunkedDownloader chunkeddownloader.ChunkedDownloader
+	signatureVerifier signatureverifier.Verifier
+	uploader          uploader.Uploader
+	downloadLimiter   chan struct{}
+	uploadLimiter     chan struct{}
+	tempDir           string
+	clock             clock.Clock
 
 	sidecarRootFS               string
 	useDeclarativeHealthCheck   bool
+	useInProcessHealthChecks    bool
 	healthyMonitoringInterval   time.Duration
 	unhealthyMonitoringInterval time.Duration
 	gracefulShutdownInterval    time.Duration
 	healthCheckWorkPool         *workpool.WorkPool
+	healthCheckScheduler        *healthcheckscheduler.Scheduler
+	probeLatencyReporter        *metrics.ProbeLatencyReporter
 
 	useContainerProxy bool
 	drainWait         time.Duration
 
 	postSetupHook []string
 	postSetupUser string
+
+	tracer trace.Tracer
+
+	maxResourceLimits executor.ResourceLimits
+
+	maxParallelConcurrency int
+
+	maxGracefulShutdownInterval time.Duration
 }
 
 type Option func(*transformer)
@@ -85,6 +114,32 @@ func WithDeclarativeHealthchecks() Option {
 	}
 }
 
+// WithInProcessHealthchecks makes the executor probe HTTP and TCP declarative
+// checks itself, on the health check work pool, instead of running the
+// healthcheck binary in a sidecar process for every check.
+func WithInProcessHealthchecks() Option {
+	return func(t *transformer) {
+		t.useInProcessHealthChecks = true
+	}
+}
+
+// WithHealthCheckScheduler spreads health checks over time and backs off
+// while the health check work pool is saturated. The scheduler must wrap the
+// health check work pool of the transformer.
+func WithHealthCheckScheduler(scheduler *healthcheckscheduler.Scheduler) Option {
+	return func(t *transformer) {
+		t.healthCheckScheduler = scheduler
+	}
+}
+
+// WithProbeLatencyReporter aggregates the latency of the health check probes
+// of all containers into the histograms of the reporter.
+func WithProbeLatencyReporter(reporter *metrics.ProbeLatencyReporter) Option {
+	return func(t *transformer) {
+		t.probeLatencyReporter = reporter
+	}
+}
+
 func WithContainerProxy(drainWait time.Duration) Option {
 	return func(t *transformer) {
 		t.useContainerProxy = true
@@ -99,11 +154,49 @@ func WithPostSetupHook(user string, hook []string) Option {
 	}
 }
 
+func WithTracer(tracer trace.Tracer) Option {
+	return func(t *transformer) {
+		t.tracer = tracer
+	}
+}
+
+func WithMaxResourceLimits(limits executor.ResourceLimits) Option {
+	return func(t *transformer) {
+		t.maxResourceLimits = limits
+	}
+}
+
+// WithMaxParallelConcurrency limits how many substeps of a parallel action run
+// at a time. 0 runs all of them at once. Codependent actions are not limited,
+// as their substeps usually run for the lifetime of the container.
+func WithMaxParallelConcurrency(maxConcurrency int) Option {
+	return func(t *transformer) {
+		t.maxParallelConcurrency = maxConcurrency
+	}
+}
+
+func WithMaxGracefulShutdownInterval(interval time.Duration) Option {
+	return func(t *transformer) {
+		t.maxGracefulShutdownInterval = interval
+	}
+}
+
+func WithChunkedDownloader(chunkedDownloader chunkeddownloader.ChunkedDownloader) Option {
+	return func(t *transformer) {
+		t.chunkedDownloader = chunkedDownloader
+	}
+}
+
+func WithSignatureVerifier(verifier signatureverifier.Verifier) Option {
+	return func(t *transformer) {
+		t.signatureVerifier = verifier
+	}
+}
+
 func NewTransformer(
 	clock clock.Clock,
 	cachedDownloader cacheddownloader.CachedDownloader,
 	uploader uploader.Uploader,
-	compressor compressor.Compressor,
 	downloadLimiter chan struct{},
 	uploadLimiter chan struct{},
 	tempDir string,
@@ -116,7 +209,6 @@ func NewTransformer(
 	t := &transformer{
 		cachedDownloader:            cachedDownloader,
 		uploader:                    uploader,
-		compressor:                  compressor,
 		downloadLimiter:             downloadLimiter,
 		uploadLimiter:               uploadLimiter,
 		tempDir:                     tempDir,
@@ -125,6 +217,7 @@ func NewTransformer(
 		gracefulShutdownInterval:    gracefulShutdownInterval,
 		healthCheckWorkPool:         healthCheckWorkPool,
 		clock:                       clock,
+		tracer:                      trace.NewNoopTracerProvider().Tracer(""),
 	}
 
 	for _, o := range opts {
@@ -134,203 +227,346 @@ func NewTransformer(
 	return t
 }
 
+// initContainersStep runs the init containers one after the other, each in
+// its own image. They share the bind mounts of the container, which include
+// its volume mounts.
+func (t *transformer) initContainersStep(
+	ctx context.Context,
+	node *steps.StepNode,
+	logStreamer log_streamer.LogStreamer,
+	container *executor.Container,
+	gardenContainer garden.Container,
+	bindMounts []garden.BindMount,
+	logger lager.Logger,
+) ifrit.Runner {
+	initSteps := make([]ifrit.Runner, len(container.InitContainers))
+	for i, initContainer := range container.InitContainers {
+		name := initContainer.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+
+		runAction := *initContainer.Action
+		sidecar := steps.Sidecar{
+			Name: fmt.Sprintf("%s-init-%s", gardenContainer.Handle(), name),
+			Image: garden.ImageRef{
+				URI:      initContainer.Image,
+				Username: initContainer.ImageUsername,
+				Password: initContainer.ImagePassword,
+			},
+			BindMounts: bindMounts,
+		}
+
+		initLogger := logger.Session("init-container", lager.Data{"name": name})
+		runStep := steps.NewRunWithSidecar(gardenContainer,
+			runAction,
+			logStreamer.WithSource(runAction.LogSource),
+			initLogger,
+			container.ExternalIP,
+			container.InternalIP,
+			container.Ports,
+			nil,
+			t.clock,
+			t.gracefulShutdownInterval,
+			garden.SignalTerminate,
+			false,
+			sidecar,
+			container.Privileged,
+		)
+		initStep := steps.NewTrace(runStep, ctx, t.tracer, "init-container", attribute.String("name", name))
+		initSteps[i] = steps.NewTracked(node.AddChild(name), initStep, t.clock)
+	}
+	return steps.NewSerial(initSteps)
+}
+
+// sidecarStep restarts the sidecar in place as its restart policy allows. A
+// non-critical sidecar that exits for good is left stopped instead of failing
+// the instance.
+func (t *transformer) sidecarStep(
+	env stepEnv,
+	node *steps.StepNode,
+	logStreamer log_streamer.LogStreamer,
+	index int,
+	sidecar executor.Sidecar,
+	onRestart func(sidecar int, restartCount int, err error),
+	logger lager.Logger,
+) (ifrit.Runner, error) {
+	logger = logger.Session("sidecar", lager.Data{"sidecar": index})
+
+	newSidecar := func() (ifrit.Runner, error) {
+		node.ClearChildren()
+		return t.stepFor(env, node, logStreamer, sidecar.Action, logger)
+	}
+
+	step, err := newSidecar()
+	if err != nil {
+		return nil, err
+	}
+
+	policy := sidecar.RestartPolicy
+	if policy != nil && policy.Policy != "" && policy.Policy != executor.RestartPolicyNever {
+		var onSidecarRestart func(restartCount int, err error)
+		if onRestart != nil {
+			onSidecarRestart = func(restartCount int, err error) {
+				onRestart(index, restartCount, err)
+			}
+		}
+		step = steps.NewRestart(stepFactory(step, newSidecar), *policy, onSidecarRestart, t.clock, logger)
+	}
+
+	if !sidecar.IsCritical() {
+		step = steps.NewTry(step, logger)
+	}
+	return step, nil
+}
+
+// stepFactory hands out the already built first step before building new
+// ones, for the steps that recreate their substep. A step that can no longer
+// be built fails when it runs.
+func stepFactory(first ifrit.Runner, build func() (ifrit.Runner, error)) func() ifrit.Runner {
+	var lock sync.Mutex
+	return func() ifrit.Runner {
+		lock.Lock()
+		step := first
+		first = nil
+		lock.Unlock()
+		if step != nil {
+			return step
+		}
+
+		step, err := build()
+		if err != nil {
+			return ifrit.RunFunc(func(<-chan os.Signal, chan<- struct{}) error {
+				return err
+			})
+		}
+		return step
+	}
+}
+
+// stepEnv holds what every step built for one action tree shares.
+type stepEnv struct {
+	ctx                    context.Context
+	onProgress             steps.ProgressFunc
+	container              garden.Container
+	externalIP             string
+	internalIP             string
+	ports                  []executor.PortMapping
+	resourceLimits         *executor.ResourceLimits
+	stopPolicy             *executor.StopPolicy
+	suppressExitStatusCode bool
+	monitorOutputWrapper   bool
+}
+
 func (t *transformer) stepFor(
+	env stepEnv,
+	parent *steps.StepNode,
 	logStreamer log_streamer.LogStreamer,
 	action *models.Action,
-	container garden.Container,
-	externalIP string,
-	internalIP string,
-	ports []executor.PortMapping,
-	suppressExitStatusCode bool,
-	monitorOutputWrapper bool,
 	logger lager.Logger,
-) ifrit.Runner {
+) (ifrit.Runner, error) {
+	// the run info is validated when the container is initialized
+	if action == nil {
+		return nil, ErrNoAction
+	}
+
+	node := parent.AddChild(stepName(action))
+	step, err := t.buildStep(env, node, logStreamer, action, logger)
+	if err != nil {
+		return nil, err
+	}
+	return steps.NewTracked(node, step, t.clock), nil
+}
+
+func (t *transformer) buildStep(
+	env stepEnv,
+	node *steps.StepNode,
+	logStreamer log_streamer.LogStreamer,
+	action *models.Action,
+	logger lager.Logger,
+) (ifrit.Runner, error) {
 	a := action.GetValue()
 	switch actionModel := a.(type) {
 	case *models.RunAction:
-		return steps.NewRun(
-			container,
-			*actionModel,
-			logStreamer.WithSource(actionModel.LogSource),
-			logger,
-			externalIP,
-			internalIP,
-			ports,
-			t.clock,
-			t.gracefulShutdownInterval,
-			suppressExitStatusCode,
-		)
+		gracefulShutdownInterval, stopSignal := t.stopSettings(env.stopPolicy)
+		return steps.NewTrace(
+			steps.NewRun(
+				env.container,
+				*actionModel,
+				logStreamer.WithSource(actionModel.LogSource),
+				logger,
+				env.externalIP,
+				env.internalIP,
+				env.ports,
+				env.resourceLimits,
+				t.clock,
+				gracefulShutdownInterval,
+				stopSignal,
+				env.suppressExitStatusCode,
+			),
+			env.ctx,
+			t.tracer,
+			"run",
+			attribute.String("path", actionModel.Path),
+		), nil
 
 	case *models.DownloadAction:
-		return steps.NewDownload(
-			container,
-			*actionModel,
-			t.cachedDownloader,
-			t.downloadLimiter,
-			logStreamer.WithSource(actionModel.LogSource),
-			logger,
-		)
+		return steps.NewTrace(
+			steps.NewChunkedDownload(
+				env.container,
+				*actionModel,
+				t.cachedDownloader,
+				t.chunkedDownloader,
+				t.signatureVerifier,
+				t.downloadLimiter,
+				logStreamer.WithSource(actionModel.LogSource),
+				t.clock,
+				logger,
+			),
+			env.ctx,
+			t.tracer,
+			"download",
+			attribute.String("artifact", actionModel.Artifact),
+		), nil
 
 	case *models.UploadAction:
-		return steps.NewUpload(
-			container,
-			*actionModel,
-			t.uploader,
-			t.compressor,
-			t.tempDir,
-			logStreamer.WithSource(actionModel.LogSource),
-			t.uploadLimiter,
-			logger,
-		)
-
-	case *models.EmitProgressAction:
-		return steps.NewEmitProgress(
-			t.stepFor(
-				logStreamer,
-				actionModel.Action,
-				container,
-				externalIP,
-				internalIP,
-				ports,
-				suppressExitStatusCode,
-				monitorOutputWrapper,
+		return steps.NewTrace(
+			steps.NewUpload(
+				env.container,
+				*actionModel,
+				t.uploader,
+				t.tempDir,
+				logStreamer.WithSource(actionModel.LogSource),
+				t.uploadLimiter,
 				logger,
 			),
+			env.ctx,
+			t.tracer,
+			"upload",
+			attribute.String("artifact", actionModel.Artifact),
+		), nil
+
+	case *models.EmitProgressAction:
+		subStep, err := t.stepFor(env, node, logStreamer, actionModel.Action, logger)
+		if err != nil {
+			return nil, err
+		}
+		return steps.NewEmitProgressWithEvents(
+			subStep,
 			actionModel.StartMessage,
 			actionModel.SuccessMessage,
 			actionModel.FailureMessagePrefix,
 			logStreamer.WithSource(actionModel.LogSource),
+			stepName(actionModel.Action),
+			env.onProgress,
+			t.clock,
 			logger,
-		)
+		), nil
 
 	case *models.TimeoutAction:
+		subStep, err := t.stepFor(env, node, logStreamer.WithSource(actionModel.LogSource), actionModel.Action, logger)
+		if err != nil {
+			return nil, err
+		}
 		return steps.NewTimeout(
-			t.stepFor(
-				logStreamer.WithSource(actionModel.LogSource),
-				actionModel.Action,
-				container,
-				externalIP,
-				internalIP,
-				ports,
-				suppressExitStatusCode,
-				monitorOutputWrapper,
-				logger,
-			),
+			subStep,
 			time.Duration(actionModel.TimeoutMs)*time.Millisecond,
 			t.clock,
 			logger,
-		)
+		), nil
 
 	case *models.TryAction:
-		return steps.NewTry(
-			t.stepFor(
-				logStreamer.WithSource(actionModel.LogSource),
-				actionModel.Action,
-				container,
-				externalIP,
-				internalIP,
-				ports,
-				suppressExitStatusCode,
-				monitorOutputWrapper,
-				logger,
-			),
-			logger,
-		)
+		subStep, err := t.stepFor(env, node, logStreamer.WithSource(actionModel.LogSource), actionModel.Action, logger)
+		if err != nil {
+			return nil, err
+		}
+		return steps.NewTry(subStep, logger), nil
 
 	case *models.ParallelAction:
+		subSteps, err := t.concurrentSubSteps(env, node, logStreamer.WithSource(actionModel.LogSource), actionModel.Actions, logger)
+		if err != nil {
+			return nil, err
+		}
+		return steps.NewParallelWithLimit(subSteps, t.maxParallelConcurrency), nil
+
+	case *models.CodependentAction:
+		subSteps, err := t.concurrentSubSteps(env, node, logStreamer.WithSource(actionModel.LogSource), actionModel.Actions, logger)
+		if err != nil {
+			return nil, err
+		}
+		errorOnExit := true
+		return steps.NewCodependent(subSteps, errorOnExit, false), nil
+
+	case *models.SerialAction:
 		subSteps := make([]ifrit.Runner, len(actionModel.Actions))
 		for i, action := range actionModel.Actions {
-			var subStep ifrit.Runner
-			if monitorOutputWrapper {
-				buffer := log_streamer.NewConcurrentBuffer(bytes.NewBuffer(nil))
-				bufferedLogStreamer := log_streamer.NewBufferStreamer(buffer, buffer)
-				subStep = steps.NewOutputWrapper(t.stepFor(
-					bufferedLogStreamer,
-					action,
-					container,
-					externalIP,
-					internalIP,
-					ports,
-					suppressExitStatusCode,
-					monitorOutputWrapper,
-					logger,
-				),
-					buffer,
-				)
-			} else {
-				subStep = t.stepFor(
-					logStreamer.WithSource(actionModel.LogSource),
-					action,
-					container,
-					externalIP,
-					internalIP,
-					ports,
-					suppressExitStatusCode,
-					monitorOutputWrapper,
-					logger,
-				)
+			subStep, err := t.stepFor(env, node, logStreamer, action, logger)
+			if err != nil {
+				return nil, err
 			}
 			subSteps[i] = subStep
 		}
-		return steps.NewParallel(subSteps)
+		return steps.NewSerial(subSteps), nil
+	}
 
-	case *models.CodependentAction:
-		subSteps := make([]ifrit.Runner, len(actionModel.Actions))
-		for i, action := range actionModel.Actions {
-			var subStep ifrit.Runner
-			if monitorOutputWrapper {
-				buffer := log_streamer.NewConcurrentBuffer(bytes.NewBuffer(nil))
-				bufferedLogStreamer := log_streamer.NewBufferStreamer(buffer, buffer)
-				subStep = steps.NewOutputWrapper(t.stepFor(
-					bufferedLogStreamer,
-					action,
-					container,
-					externalIP,
-					internalIP,
-					ports,
-					suppressExitStatusCode,
-					monitorOutputWrapper,
-					logger,
-				),
-					buffer,
-				)
-			} else {
-				subStep = t.stepFor(
-					logStreamer.WithSource(actionModel.LogSource),
-					action,
-					container,
-					externalIP,
-					internalIP,
-					ports,
-					suppressExitStatusCode,
-					monitorOutputWrapper,
-					logger,
-				)
+	return nil, fmt.Errorf("unknown action: %T", a)
+}
+
+// concurrentSubSteps builds the substeps of a parallel or codependent action.
+// Monitor substeps buffer their output so it can be reported with the
+// failure.
+func (t *transformer) concurrentSubSteps(
+	env stepEnv,
+	node *steps.StepNode,
+	logStreamer log_streamer.LogStreamer,
+	actions []*models.Action,
+	logger lager.Logger,
+) ([]ifrit.Runner, error) {
+	subSteps := make([]ifrit.Runner, len(actions))
+	for i, action := range actions {
+		if !env.monitorOutputWrapper {
+			subStep, err := t.stepFor(env, node, logStreamer, action, logger)
+			if err != nil {
+				return nil, err
 			}
 			subSteps[i] = subStep
+			continue
 		}
-		errorOnExit := true
-		return steps.NewCodependent(subSteps, errorOnExit, false)
 
-	case *models.SerialAction:
-		subSteps := make([]ifrit.Runner, len(actionModel.Actions))
-		for i, action := range actionModel.Actions {
-			subSteps[i] = t.stepFor(
-				logStreamer,
-				action,
-				container,
-				externalIP,
-				internalIP,
-				ports,
-				suppressExitStatusCode,
-				monitorOutputWrapper,
-				logger,
-			)
+		buffer := log_streamer.NewConcurrentBuffer(bytes.NewBuffer(nil))
+		bufferedLogStreamer := log_streamer.NewBufferStreamer(buffer, buffer)
+		subStep, err := t.stepFor(env, node, bufferedLogStreamer, action, logger)
+		if err != nil {
+			return nil, err
 		}
-		return steps.NewSerial(subSteps)
+		subSteps[i] = steps.NewOutputWrapper(subStep, buffer)
 	}
+	return subSteps, nil
+}
 
-	panic(fmt.Sprintf("unknown action: %T", action))
+func stepName(action *models.Action) string {
+	switch actionModel := action.GetValue().(type) {
+	case *models.RunAction:
+		return "run " + actionModel.Path
+	case *models.DownloadAction:
+		return "download " + actionModel.Artifact
+	case *models.UploadAction:
+		return "upload " + actionModel.Artifact
+	case *models.EmitProgressAction:
+		return "emit-progress"
+	case *models.TimeoutAction:
+		return "timeout"
+	case *models.TryAction:
+		return "try"
+	case *models.ParallelAction:
+		return "parallel"
+	case *models.CodependentAction:
+		return "codependent"
+	case *models.SerialAction:
+		return "serial"
+	default:
+		return fmt.Sprintf("%T", actionModel)
+	}
 }
 
 func overrideSuppressLogOutput(monitorAction *models.Action) {
@@ -363,22 +599,60 @@ func (t *transformer) StepsRunner(
 	logStreamer log_streamer.LogStreamer,
 	config Config,
 ) (ifrit.Runner, error) {
-	var setup, action, postSetup, monitor, longLivedAction ifrit.Runner
-	var substeps []ifrit.Runner
+	ctx := config.TraceContext
+	if ctx == nil {
+		ctx = context.Background()
+	}
 
-	if container.Setup != nil {
-		setup = t.stepFor(
+	env := stepEnv{
+		ctx:            ctx,
+		onProgress:     config.OnProgress,
+		container:      gardenContainer,
+		externalIP:     container.ExternalIP,
+		internalIP:     container.InternalIP,
+		ports:          container.Ports,
+		resourceLimits: container.ResourceLimits,
+		stopPolicy:     container.StopPolicy,
+	}
+
+	var initContainers, setup, postSetup, longLivedAction ifrit.Runner
+
+	if len(container.InitContainers) > 0 {
+		initContainers = t.initContainersStep(ctx,
+			config.StepTree.AddChild("init-containers"),
 			logStreamer,
-			container.Setup,
+			&container,
 			gardenContainer,
-			container.ExternalIP,
-			container.InternalIP,
-			container.Ports,
-			false,
-			false,
-			logger.Session("setup"),
+			config.BindMounts,
+			logger,
 		)
 	}
+
+	if container.Setup != nil {
+		setupNode := config.StepTree.AddChild("setup")
+		newSetup := func() (ifrit.Runner, error) {
+			setupNode.ClearChildren()
+			return t.stepFor(env, setupNode, logStreamer, container.Setup, logger.Session("setup"))
+		}
+
+		var err error
+		setup, err = newSetup()
+		if err != nil {
+			return nil, err
+		}
+		if container.SetupRetryPolicy != nil && container.SetupRetryPolicy.MaxAttempts > 1 {
+			setup = steps.NewRetry(stepFactory(setup, newSetup), *container.SetupRetryPolicy, nil, logStreamer, t.clock, logger.Session("setup"))
+		}
+		setup = steps.NewTracked(setupNode, setup, t.clock)
+	}
+
+	if initContainers != nil {
+		if setup == nil {
+			setup = initContainers
+		} else {
+			setup = steps.NewSerial([]ifrit.Runner{initContainers, setup})
+		}
+	}
 	setup = steps.NewTimedStep(logger, setup, config.MetronClient, t.clock, config.CreationStartTime)
 
 	if len(t.postSetupHook) > 0 {
@@ -396,43 +670,57 @@ func (t *transformer) StepsRunner(
 			container.ExternalIP,
 			container.InternalIP,
 			container.Ports,
+			nil,
 			t.clock,
 			t.gracefulShutdownInterval,
+			garden.SignalTerminate,
 			suppressExitStatusCode,
 		)
+		postSetup = steps.NewTracked(config.StepTree.AddChild("post-setup"), postSetup, t.clock)
 	}
 
-	if container.Action == nil {
-		err := errors.New("container cannot have empty action")
-		logger.Error("steps-runner-empty-action", err)
-		return nil, err
+	probeMetrics := steps.NewProbeMetrics(config.MetronClient, probeMetricTags(container.MetricsConfig), t.probeLatencyReporter, logger)
+
+	actionNode := config.StepTree.AddChild("action")
+	restartPolicy := container.RestartPolicy
+	restarts := restartPolicy != nil && restartPolicy.Policy != "" && restartPolicy.Policy != executor.RestartPolicyNever
+
+	// only the action is restarted, under a node of its own so that the
+	// sidecars and health checks keep their status across restarts
+	mainNode := actionNode
+	if restarts {
+		mainNode = actionNode.AddChild("restart")
+	}
+	newAction := func() (ifrit.Runner, error) {
+		mainNode.ClearChildren()
+		return t.stepFor(env, mainNode, logStreamer, container.Action, logger.Session("action"))
 	}
 
-	action = t.stepFor(
-		logStreamer,
-		container.Action,
-		gardenContainer,
-		container.ExternalIP,
-		container.InternalIP,
-		container.Ports,
-		false,
-		false,
-		logger.Session("action"),
-	)
+	action, err := newAction()
+	if err != nil {
+		return nil, err
+	}
+	if restarts {
+		action = steps.NewTracked(
+			mainNode,
+			steps.NewRestart(stepFactory(action, newAction), *restartPolicy, config.OnRestart, t.clock, logger),
+			t.clock,
+		)
+	}
 
-	substeps = append(substeps, action)
+	substeps := []ifrit.Runner{action}
 
-	for _, sidecar := range container.Sidecars {
-		substeps = append(substeps, t.stepFor(logStreamer,
-			sidecar.Action,
-			gardenContainer,
-			container.ExternalIP,
-			container.InternalIP,
-			container.Ports,
-			false,
-			false,
-			logger.Session("sidecar"),
-		))
+	for i, sidecar := range container.Sidecars {
+		sidecarStep, err := t.sidecarStep(env, actionNode.AddChild("sidecar"), logStreamer,
+			i,
+			sidecar,
+			config.OnSidecarRestart,
+			logger,
+		)
+		if err != nil {
+			return nil, err
+		}
+		substeps = append(substeps, sidecarStep)
 	}
 
 	var proxyReadinessChecks []ifrit.Runner
@@ -442,9 +730,24 @@ func (t *transformer) StepsRunner(
 
 		for idx, p := range config.ProxyTLSPorts {
 			// add envoy readiness checks
+			if t.useInProcessHealthChecks {
+				probe := t.inProcessCheck(&container, "", int(p), DefaultDeclarativeHealthcheckRequestTimeout, false, "", nil, envoyReadinessLogger)
+				proxyReadinessChecks = append(proxyReadinessChecks, steps.NewErrorPrefix(
+					steps.NewEventuallySucceedsStep(
+						probe,
+						t.unhealthyMonitoringInterval,
+						time.Duration(container.StartTimeoutMs)*time.Millisecond,
+						t.clock,
+					),
+					"instance proxy failed to start",
+				))
+				continue
+			}
+
 			readinessSidecarName := fmt.Sprintf("%s-envoy-readiness-healthcheck-%d", gardenContainer.Handle(), idx)
 
 			step := t.createCheck(
+				ctx,
 				&container,
 				gardenContainer,
 				config.BindMounts,
@@ -455,6 +758,7 @@ func (t *transformer) StepsRunner(
 				false,
 				true,
 				t.unhealthyMonitoringInterval,
+				time.Duration(container.StartTimeoutMs)*time.Millisecond,
 				envoyReadinessLogger,
 				"instance proxy failed to start",
 			)
@@ -462,48 +766,54 @@ func (t *transformer) StepsRunner(
 		}
 	}
 
-	if container.CheckDefinition != nil && t.useDeclarativeHealthCheck {
-		monitor = t.transformCheckDefinition(logger,
+	if (container.CheckDefinition != nil || len(container.HealthChecks) > 0) && t.useDeclarativeHealthCheck {
+		monitor := t.transformCheckDefinition(ctx, logger,
 			&container,
 			gardenContainer,
 			logStreamer,
 			config.BindMounts,
 			proxyReadinessChecks,
+			config.HealthTracker,
+			probeMetrics,
 		)
-		substeps = append(substeps, monitor)
+		substeps = append(substeps, steps.NewTracked(actionNode.AddChild("health-check"), monitor, t.clock))
 	} else if container.Monitor != nil {
 		overrideSuppressLogOutput(container.Monitor)
-		monitor = steps.NewMonitor(
-			func() ifrit.Runner {
-				return t.stepFor(
-					logStreamer,
-					container.Monitor,
-					gardenContainer,
-					container.ExternalIP,
-					container.InternalIP,
-					container.Ports,
-					true,
-					true,
-					logger.Session("monitor-run"),
-				)
-			},
+		successThreshold, failureThreshold := healthCheckThresholds(&container)
+		monitorEnv := env
+		monitorEnv.suppressExitStatusCode = true
+		monitorEnv.monitorOutputWrapper = true
+		newMonitorCheck := func() (ifrit.Runner, error) {
+			return t.stepFor(monitorEnv, nil, logStreamer, container.Monitor, logger.Session("monitor-run"))
+		}
+		monitorCheck, err := newMonitorCheck()
+		if err != nil {
+			return nil, err
+		}
+		monitor := steps.NewMonitor(
+			stepFactory(monitorCheck, newMonitorCheck),
 			logger.Session("monitor"),
 			t.clock,
 			logStreamer,
 			time.Duration(container.StartTimeoutMs)*time.Millisecond,
 			t.healthyMonitoringInterval,
 			t.unhealthyMonitoringInterval,
-			t.healthCheckWorkPool,
+			successThreshold,
+			failureThreshold,
+			t.healthCheckPool(),
+			t.healthCheckSchedule(),
+			config.HealthTracker,
+			probeMetrics,
 			proxyReadinessChecks...,
 		)
-		substeps = append(substeps, monitor)
+		substeps = append(substeps, steps.NewTracked(actionNode.AddChild("monitor"), monitor, t.clock))
 	}
 
+	longLivedAction = action
 	if len(substeps) > 1 {
 		longLivedAction = steps.NewCodependent(substeps, false, false)
-	} else {
-		longLivedAction = action
 	}
+	longLivedAction = steps.NewTracked(actionNode, longLivedAction, t.clock)
 
 	if t.useContainerProxy && container.EnableContainerProxy {
 		containerProxyStep := t.transformContainerProxyStep(
@@ -516,6 +826,14 @@ func (t *transformer) StepsRunner(
 		longLivedAction = steps.NewCodependent([]ifrit.Runner{longLivedAction, containerProxyStep}, false, true)
 	}
 
+	if container.StopPolicy != nil && container.StopPolicy.PreStop != nil {
+		longLivedAction = steps.NewPreStop(
+			longLivedAction,
+			t.preStopStep(container, gardenContainer, logStreamer, logger),
+			logger,
+		)
+	}
+
 	var cumulativeStep ifrit.Runner
 	if setup == nil {
 		cumulativeStep = longLivedAction
@@ -527,10 +845,83 @@ func (t *transformer) StepsRunner(
 		}
 	}
 
-	return cumulativeStep, nil
+	return steps.NewTracked(config.StepTree, cumulativeStep, t.clock), nil
+}
+
+// stopSettings returns the graceful shutdown interval and stop signal for the
+// processes of a container. Without a configured maximum, containers may only
+// shorten the cell's graceful shutdown interval.
+func (t *transformer) stopSettings(policy *executor.StopPolicy) (time.Duration, garden.Signal) {
+	interval := t.gracefulShutdownInterval
+	signal := garden.SignalTerminate
+	if policy == nil {
+		return interval, signal
+	}
+
+	if policy.GracefulShutdownIntervalMs > 0 {
+		max := t.maxGracefulShutdownInterval
+		if max == 0 {
+			max = t.gracefulShutdownInterval
+		}
+
+		interval = time.Duration(policy.GracefulShutdownIntervalMs) * time.Millisecond
+		if interval > max {
+			interval = max
+		}
+	}
+
+	if policy.Signal == executor.StopSignalKill {
+		signal = garden.SignalKill
+	}
+
+	return interval, signal
+}
+
+func (t *transformer) preStopStep(
+	container executor.Container,
+	gardenContainer garden.Container,
+	logStreamer log_streamer.LogStreamer,
+	logger lager.Logger,
+) ifrit.Runner {
+	hook := container.StopPolicy.PreStop
+	logger = logger.Session("pre-stop")
+
+	timeout := DefaultPreStopTimeout
+	if hook.TimeoutMs > 0 {
+		timeout = time.Duration(hook.TimeoutMs) * time.Millisecond
+	}
+
+	runAction := models.RunAction{
+		Path: hook.Path,
+		Args: hook.Args,
+		Dir:  hook.Dir,
+		User: hook.User,
+	}
+	gracefulShutdownInterval, stopSignal := t.stopSettings(container.StopPolicy)
+
+	return steps.NewTimeout(
+		steps.NewRun(
+			gardenContainer,
+			runAction,
+			logStreamer,
+			logger,
+			container.ExternalIP,
+			container.InternalIP,
+			container.Ports,
+			container.ResourceLimits,
+			t.clock,
+			gracefulShutdownInterval,
+			stopSignal,
+			false,
+		),
+		timeout,
+		t.clock,
+		logger,
+	)
 }
 
 func (t *transformer) createCheck(
+	ctx context.Context,
 	container *executor.Container,
 	gardenContainer garden.Container,
 	bindMounts []garden.BindMount,
@@ -541,6 +932,7 @@ func (t *transformer) createCheck(
 	http,
 	readiness bool,
 	interval time.Duration,
+	readinessTimeout time.Duration,
 	logger lager.Logger,
 	prefix string,
 ) ifrit.Runner {
@@ -561,11 +953,14 @@ func (t *transformer) createCheck(
 		args = append(args, fmt.Sprintf("-uri=%s", path))
 	}
 
-	if readiness {
-		args = append(args, fmt.Sprintf("-readiness-interval=%s", interval))
-		args = append(args, fmt.Sprintf("-readiness-timeout=%s", time.Duration(container.StartTimeoutMs)*time.Millisecond))
-	} else {
-		args = append(args, fmt.Sprintf("-liveness-interval=%s", interval))
+	// without an interval the healthcheck probes once and exits
+	if interval > 0 {
+		if readiness {
+			args = append(args, fmt.Sprintf("-readiness-interval=%s", interval))
+			args = append(args, fmt.Sprintf("-readiness-timeout=%s", readinessTimeout))
+		} else {
+			args = append(args, fmt.Sprintf("-liveness-interval=%s", interval))
+		}
 	}
 
 	rl := models.ResourceLimits{}
@@ -592,31 +987,40 @@ func (t *transformer) createCheck(
 		container.ExternalIP,
 		container.InternalIP,
 		container.Ports,
+		nil,
 		t.clock,
 		t.gracefulShutdownInterval,
+		garden.SignalTerminate,
 		true,
 		sidecar,
 		container.Privileged,
 	)
+	checkStep := steps.NewTrace(runStep, ctx, t.tracer, "health-check",
+		attribute.Int("port", port),
+		attribute.Bool("readiness", readiness),
+	)
 	if prefix != "" {
-		return steps.NewOutputWrapperWithPrefix(runStep, buffer, prefix)
+		return steps.NewOutputWrapperWithPrefix(checkStep, buffer, prefix)
 	}
-	return steps.NewOutputWrapper(runStep, buffer)
+	return steps.NewOutputWrapper(checkStep, buffer)
 }
 
 func (t *transformer) transformCheckDefinition(
+	ctx context.Context,
 	logger lager.Logger,
 	container *executor.Container,
 	gardenContainer garden.Container,
 	logstreamer log_streamer.LogStreamer,
 	bindMounts []garden.BindMount,
 	proxyReadinessChecks []ifrit.Runner,
+	health *steps.HealthTracker,
+	probes *steps.ProbeMetrics,
 ) ifrit.Runner {
 	var readinessChecks []ifrit.Runner
 	var livenessChecks []ifrit.Runner
 
 	sourceName := HealthLogSource
-	if container.CheckDefinition.LogSource != "" {
+	if container.CheckDefinition != nil && container.CheckDefinition.LogSource != "" {
 		sourceName = container.CheckDefinition.LogSource
 	}
 
@@ -628,87 +1032,173 @@ func (t *transformer) transformCheckDefinition(
 	readinessLogger := logger.Session("readiness-check")
 	livenessLogger := logger.Session("liveness-check")
 
-	for index, check := range container.CheckDefinition.Checks {
+	var checks []*models.Check
+	if container.CheckDefinition != nil {
+		checks = container.CheckDefinition.Checks
+	}
+
+	startTimeout := time.Duration(container.StartTimeoutMs) * time.Millisecond
+	successThreshold, failureThreshold := healthCheckThresholds(container)
+
+	for index, check := range checks {
 
 		readinessSidecarName := fmt.Sprintf("%s-readiness-healthcheck-%d", gardenContainer.Handle(), index)
 		livenessSidecarName := fmt.Sprintf("%s-liveness-healthcheck-%d", gardenContainer.Handle(), index)
 
-		if err := check.Validate(); err != nil {
-			logger.Error("invalid-check", err, lager.Data{"check": check})
-		} else if check.HttpCheck != nil {
-			timeout := int(check.HttpCheck.RequestTimeoutMs)
-			if timeout == 0 {
-				timeout = DefaultDeclarativeHealthcheckRequestTimeout
-			}
-			path := check.HttpCheck.Path
+		var (
+			path    string
+			port    int
+			timeout int
+			http    bool
+		)
+
+		if check.HttpCheck != nil {
+			timeout = int(check.HttpCheck.RequestTimeoutMs)
+			path = check.HttpCheck.Path
 			if path == "" {
 				path = "/"
 			}
+			port = int(check.HttpCheck.Port)
+			http = true
+		} else if check.TcpCheck != nil {
+			timeout = int(check.TcpCheck.ConnectTimeoutMs)
+			port = int(check.TcpCheck.Port)
+		} else {
+			continue
+		}
 
-			readinessChecks = append(readinessChecks, t.createCheck(
-				container,
-				gardenContainer,
-				bindMounts,
-				path,
-				readinessSidecarName,
-				int(check.HttpCheck.Port),
-				timeout,
-				true,
-				true,
+		if timeout == 0 {
+			timeout = DefaultDeclarativeHealthcheckRequestTimeout
+		}
+
+		if t.useInProcessHealthChecks {
+			readinessProbe := t.inProcessCheck(container, path, port, timeout, http, steps.ReadinessProbe, probes, readinessLogger)
+			livenessProbe := t.inProcessCheck(container, path, port, timeout, http, steps.LivenessProbe, probes, livenessLogger)
+			readinessChecks = append(readinessChecks, steps.NewEventuallySucceedsStepWithThreshold(
+				func() ifrit.Runner { return steps.NewRecordProbe(readinessProbe(), health, t.clock) },
 				t.unhealthyMonitoringInterval,
-				readinessLogger,
-				"",
+				startTimeout,
+				successThreshold,
+				t.healthCheckSchedule(),
+				t.clock,
 			))
-			livenessChecks = append(livenessChecks, t.createCheck(
-				container,
-				gardenContainer,
-				bindMounts,
-				path,
-				livenessSidecarName,
-				int(check.HttpCheck.Port),
-				timeout,
-				true,
-				false,
+			livenessChecks = append(livenessChecks, steps.NewConsistentlySucceedsStepWithThreshold(
+				func() ifrit.Runner { return steps.NewRecordProbe(livenessProbe(), health, t.clock) },
 				t.healthyMonitoringInterval,
-				livenessLogger,
-				"",
+				failureThreshold,
+				t.healthCheckSchedule(),
+				t.clock,
 			))
+			continue
+		}
 
-		} else if check.TcpCheck != nil {
-			timeout := int(check.TcpCheck.ConnectTimeoutMs)
-			if timeout == 0 {
-				timeout = DefaultDeclarativeHealthcheckRequestTimeout
+		newCheck := func(readiness bool, interval, readinessTimeout time.Duration) ifrit.Runner {
+			sidecarName, checkLogger, probe := livenessSidecarName, livenessLogger, steps.LivenessProbe
+			if readiness {
+				sidecarName, checkLogger, probe = readinessSidecarName, readinessLogger, steps.ReadinessProbe
 			}
-
-			readinessChecks = append(readinessChecks, t.createCheck(
+			check := t.createCheck(
+				ctx,
 				container,
 				gardenContainer,
 				bindMounts,
-				"",
-				readinessSidecarName,
-				int(check.TcpCheck.Port),
+				path,
+				sidecarName,
+				port,
 				timeout,
-				false,
-				true,
-				t.unhealthyMonitoringInterval,
-				readinessLogger,
-				"",
-			))
-			livenessChecks = append(livenessChecks, t.createCheck(
-				container,
-				gardenContainer,
-				bindMounts,
+				http,
+				readiness,
+				interval,
+				readinessTimeout,
+				checkLogger,
 				"",
-				livenessSidecarName,
-				int(check.TcpCheck.Port),
-				timeout,
-				false,
-				false,
+			)
+			return steps.NewRecordProbe(steps.NewReportProbe(check, probe, probes, t.clock), health, t.clock)
+		}
+
+		// the healthcheck binary only exits on the first result it is waiting
+		// for, so thresholds are counted over single probes of the binary
+		readiness := newCheck(true, t.unhealthyMonitoringInterval, startTimeout)
+		if successThreshold > 1 {
+			readiness = steps.NewEventuallySucceedsStepWithThreshold(
+				func() ifrit.Runner { return newCheck(true, 0, 0) },
+				t.unhealthyMonitoringInterval,
+				startTimeout,
+				successThreshold,
+				t.healthCheckSchedule(),
+				t.clock,
+			)
+		}
+
+		liveness := newCheck(false, t.healthyMonitoringInterval, 0)
+		if failureThreshold > 1 {
+			liveness = steps.NewConsistentlySucceedsStepWithThreshold(
+				func() ifrit.Runner { return newCheck(false, 0, 0) },
 				t.healthyMonitoringInterval,
-				livenessLogger,
-				"",
-			))
+				failureThreshold,
+				t.healthCheckSchedule(),
+				t.clock,
+			)
+		}
+
+		readinessChecks = append(readinessChecks, readiness)
+		livenessChecks = append(livenessChecks, liveness)
+	}
+
+	for index, check := range container.HealthChecks {
+		checkSourceName := sourceName
+		var createCheck func(lager.Logger) ifrit.Runner
+
+		if check.Exec != nil {
+			exec := check.Exec
+			if exec.LogSource != "" {
+				checkSourceName = exec.LogSource
+			}
+			createCheck = func(logger lager.Logger) ifrit.Runner {
+				return t.createExecCheck(container, gardenContainer, exec, logger)
+			}
+		} else if check.GRPC != nil {
+			grpcCheck := check.GRPC
+			if grpcCheck.LogSource != "" {
+				checkSourceName = grpcCheck.LogSource
+			}
+			address := net.JoinHostPort(container.InternalIP, strconv.Itoa(int(grpcCheck.Port)))
+			createCheck = func(logger lager.Logger) ifrit.Runner {
+				return steps.NewGRPCCheck(address, grpcCheck.Service, healthCheckTimeout(grpcCheck.TimeoutMs), logger)
+			}
+		} else {
+			logger.Error("invalid-health-check", nil, lager.Data{"index": index})
+			continue
 		}
+
+		checkStreamer := logstreamer.WithSource(checkSourceName)
+		readinessChecks = append(readinessChecks, steps.NewLogFailure(
+			steps.NewEventuallySucceedsStepWithThreshold(
+				func() ifrit.Runner {
+					probe := steps.NewReportProbe(createCheck(readinessLogger), steps.ReadinessProbe, probes, t.clock)
+					return steps.NewRecordProbe(probe, health, t.clock)
+				},
+				t.unhealthyMonitoringInterval,
+				startTimeout,
+				successThreshold,
+				t.healthCheckSchedule(),
+				t.clock,
+			),
+			checkStreamer,
+		))
+		livenessChecks = append(livenessChecks, steps.NewLogFailure(
+			steps.NewConsistentlySucceedsStepWithThreshold(
+				func() ifrit.Runner {
+					probe := steps.NewReportProbe(createCheck(livenessLogger), steps.LivenessProbe, probes, t.clock)
+					return steps.NewRecordProbe(probe, health, t.clock)
+				},
+				t.healthyMonitoringInterval,
+				failureThreshold,
+				t.healthCheckSchedule(),
+				t.clock,
+			),
+			checkStreamer,
+		))
 	}
 
 	readinessCheck := steps.NewParallel(append(proxyReadinessChecks, readinessChecks...))
@@ -721,10 +1211,129 @@ func (t *transformer) transformCheckDefinition(
 		t.clock,
 		logstreamer,
 		logstreamer.WithSource(sourceName),
-		time.Duration(container.StartTimeoutMs)*time.Millisecond,
+		startTimeout,
+		health,
 	)
 }
 
+// probeMetricTags identifies the container the same way as its container
+// metrics.
+func probeMetricTags(metricsConfig executor.MetricsConfig) map[string]string {
+	tags := map[string]string{}
+	for k, v := range metricsConfig.Tags {
+		tags[k] = v
+	}
+	if _, ok := tags["source_id"]; !ok && metricsConfig.Guid != "" {
+		tags["source_id"] = metricsConfig.Guid
+	}
+	if _, ok := tags["instance_id"]; !ok {
+		tags["instance_id"] = strconv.Itoa(metricsConfig.Index)
+	}
+	return tags
+}
+
+// inProcessCheck probes the container from the executor, with the same
+// failure output as the healthcheck binary. The probe is reported to probes
+// without the time it spent waiting for the work pool.
+func (t *transformer) inProcessCheck(
+	container *executor.Container,
+	path string,
+	port,
+	timeout int,
+	http bool,
+	probe string,
+	probes *steps.ProbeMetrics,
+	logger lager.Logger,
+) func() ifrit.Runner {
+	requestTimeout := time.Duration(timeout) * time.Millisecond
+	return func() ifrit.Runner {
+		var check ifrit.Runner
+		if http {
+			check = steps.NewHTTPCheck(container.InternalIP, port, path, requestTimeout, logger)
+		} else {
+			check = steps.NewTCPCheck(container.InternalIP, port, requestTimeout, logger)
+		}
+		return steps.NewThrottle(steps.NewReportProbe(check, probe, probes, t.clock), t.healthCheckPool())
+	}
+}
+
+func (t *transformer) healthCheckPool() steps.WorkPool {
+	if t.healthCheckScheduler != nil {
+		return t.healthCheckScheduler
+	}
+	return t.healthCheckWorkPool
+}
+
+// healthCheckSchedule returns a nil interface rather than a nil scheduler so
+// that the steps fall back to fixed intervals.
+func (t *transformer) healthCheckSchedule() steps.Schedule {
+	if t.healthCheckScheduler != nil {
+		return t.healthCheckScheduler
+	}
+	return nil
+}
+
+// createExecCheck runs the command of an exec check inside the container. The
+// output of the command is only reported when the check fails.
+func (t *transformer) createExecCheck(
+	container *executor.Container,
+	gardenContainer garden.Container,
+	check *executor.ExecCheck,
+	logger lager.Logger,
+) ifrit.Runner {
+	buffer := log_streamer.NewConcurrentBuffer(bytes.NewBuffer(nil))
+	runAction := models.RunAction{
+		Path: check.Path,
+		Args: check.Args,
+		Dir:  check.Dir,
+		User: check.User,
+	}
+
+	runStep := steps.NewRun(
+		gardenContainer,
+		runAction,
+		log_streamer.NewBufferStreamer(buffer, buffer),
+		logger.Session("exec-check"),
+		container.ExternalIP,
+		container.InternalIP,
+		container.Ports,
+		nil,
+		t.clock,
+		t.gracefulShutdownInterval,
+		garden.SignalTerminate,
+		true,
+	)
+
+	timeout := healthCheckTimeout(check.TimeoutMs)
+	return steps.NewOutputWrapperWithPrefix(
+		steps.NewTimeout(runStep, timeout, t.clock, logger),
+		buffer,
+		fmt.Sprintf("Exec health check %s failed", check.Path),
+	)
+}
+
+// healthCheckThresholds returns how many probes in a row have to pass before
+// the container is healthy and fail before it is unhealthy.
+func healthCheckThresholds(container *executor.Container) (int, int) {
+	successThreshold, failureThreshold := 1, 1
+	if thresholds := container.HealthCheckThresholds; thresholds != nil {
+		if thresholds.SuccessThreshold > 1 {
+			successThreshold = int(thresholds.SuccessThreshold)
+		}
+		if thresholds.FailureThreshold > 1 {
+			failureThreshold = int(thresholds.FailureThreshold)
+		}
+	}
+	return successThreshold, failureThreshold
+}
+
+func healthCheckTimeout(timeoutMs uint) time.Duration {
+	if timeoutMs == 0 {
+		return time.Duration(DefaultDeclarativeHealthcheckRequestTimeout) * time.Millisecond
+	}
+	return time.Duration(timeoutMs) * time.Millisecond
+}
+
 func (t *transformer) transformContainerProxyStep(
 	container garden.Container,
 	execContainer executor.Container,
@@ -757,8 +1366,10 @@ func (t *transformer) transformContainerProxyStep(
 		execContainer.ExternalIP,
 		execContainer.InternalIP,
 		execContainer.Ports,
+		nil,
 		t.clock,
 		t.gracefulShutdownInterval,
+		garden.SignalTerminate,
 		false,
 		sidecar,
 		execContainer.Privileged,
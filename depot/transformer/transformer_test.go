@@ -4,9 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -16,10 +20,13 @@ import (
 	"code.cloudfoundry.org/clock/fakeclock"
 	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/depot/healthcheckscheduler"
 	"code.cloudfoundry.org/executor/depot/log_streamer"
+	"code.cloudfoundry.org/executor/depot/steps"
 	"code.cloudfoundry.org/executor/depot/transformer"
 	"code.cloudfoundry.org/garden"
 	"code.cloudfoundry.org/garden/gardenfakes"
+	"code.cloudfoundry.org/go-loggregator/v8/rpc/loggregator_v2"
 	"code.cloudfoundry.org/lager"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/workpool"
@@ -108,7 +115,7 @@ var _ = Describe("Transformer", func() {
 		JustBeforeEach(func() {
 			optimusPrime = transformer.NewTransformer(
 				clock,
-				nil, nil, nil, nil, nil,
+				nil, nil, nil, nil,
 				os.TempDir(),
 				healthyMonitoringInterval,
 				unhealthyMonitoringInterval,
@@ -125,6 +132,23 @@ var _ = Describe("Transformer", func() {
 
 			It("returns an error", func() {
 				_, err := optimusPrime.StepsRunner(logger, container, gardenContainer, logStreamer, cfg)
+				Expect(err).To(Equal(transformer.ErrNoAction))
+			})
+		})
+
+		Context("when a nested action is empty", func() {
+			BeforeEach(func() {
+				container.Action = models.WrapAction(models.Serial(
+					&models.RunAction{Path: "/action/path", User: "user"},
+				))
+				container.Action.SerialAction.Actions = append(container.Action.SerialAction.Actions, &models.Action{})
+			})
+
+			It("returns an error instead of panicking", func() {
+				var err error
+				Expect(func() {
+					_, err = optimusPrime.StepsRunner(logger, container, gardenContainer, logStreamer, cfg)
+				}).NotTo(Panic())
 				Expect(err).To(HaveOccurred())
 			})
 		})
@@ -229,6 +253,300 @@ var _ = Describe("Transformer", func() {
 			Eventually(logger).Should(gbytes.Say("container-setup.*duration.*1000000000"))
 		})
 
+		Context("when the parallel concurrency is limited", func() {
+			BeforeEach(func() {
+				options = append(options, transformer.WithMaxParallelConcurrency(1))
+				container.Setup = &models.Action{
+					ParallelAction: &models.ParallelAction{
+						Actions: []*models.Action{
+							{RunAction: &models.RunAction{Path: "/download/one"}},
+							{RunAction: &models.RunAction{Path: "/download/two"}},
+						},
+					},
+				}
+			})
+
+			It("runs one substep of a parallel action at a time", func() {
+				waitCh := make(chan int)
+				defer close(waitCh)
+				gardenContainer.RunStub = func(processSpec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+					if strings.HasPrefix(processSpec.Path, "/download/") {
+						return &gardenfakes.FakeProcess{WaitStub: func() (int, error) {
+							return <-waitCh, nil
+						}}, nil
+					}
+					return &gardenfakes.FakeProcess{}, nil
+				}
+
+				runner, err := optimusPrime.StepsRunner(logger, container, gardenContainer, logStreamer, cfg)
+				Expect(err).NotTo(HaveOccurred())
+				ifrit.Background(runner)
+
+				Eventually(gardenContainer.RunCallCount).Should(Equal(1))
+				Consistently(gardenContainer.RunCallCount).Should(Equal(1))
+
+				waitCh <- 0
+				Eventually(gardenContainer.RunCallCount).Should(Equal(2))
+				waitCh <- 0
+			})
+
+			Context("and the action is a codependent action wider than the limit", func() {
+				BeforeEach(func() {
+					container.Setup = nil
+					container.Monitor = nil
+					container.Action = &models.Action{
+						CodependentAction: &models.CodependentAction{
+							Actions: []*models.Action{
+								{RunAction: &models.RunAction{Path: "/process/one"}},
+								{RunAction: &models.RunAction{Path: "/process/two"}},
+								{RunAction: &models.RunAction{Path: "/process/three"}},
+							},
+						},
+					}
+				})
+
+				It("starts all of its substeps and becomes ready", func() {
+					waitCh := make(chan int)
+					defer close(waitCh)
+					gardenContainer.RunStub = func(processSpec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+						return &gardenfakes.FakeProcess{WaitStub: func() (int, error) {
+							return <-waitCh, nil
+						}}, nil
+					}
+
+					runner, err := optimusPrime.StepsRunner(logger, container, gardenContainer, logStreamer, cfg)
+					Expect(err).NotTo(HaveOccurred())
+					process := ifrit.Background(runner)
+
+					Eventually(gardenContainer.RunCallCount).Should(Equal(3))
+					Eventually(process.Ready()).Should(BeClosed())
+				})
+			})
+		})
+
+		Context("when there are init containers", func() {
+			BeforeEach(func() {
+				gardenContainer.HandleReturns("some-handle")
+				container.InitContainers = []executor.InitContainer{
+					{
+						Name:          "migrate",
+						Image:         "docker://migrations",
+						ImageUsername: "user",
+						ImagePassword: "password",
+						Action:        &models.RunAction{Path: "/migrate", Args: []string{"up"}},
+					},
+					{
+						Image:  "docker://secrets-agent",
+						Action: &models.RunAction{Path: "/fetch-secrets"},
+					},
+				}
+			})
+
+			It("runs them one after the other in their own image before the setup", func() {
+				waitCh := make(chan int)
+				defer close(waitCh)
+				gardenContainer.RunStub = func(processSpec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+					return &gardenfakes.FakeProcess{WaitStub: func() (int, error) {
+						return <-waitCh, nil
+					}}, nil
+				}
+
+				runner, err := optimusPrime.StepsRunner(logger, container, gardenContainer, logStreamer, cfg)
+				Expect(err).NotTo(HaveOccurred())
+				ifrit.Background(runner)
+
+				Eventually(gardenContainer.RunCallCount).Should(Equal(1))
+				Consistently(gardenContainer.RunCallCount).Should(Equal(1))
+				processSpec, _ := gardenContainer.RunArgsForCall(0)
+				Expect(processSpec.ID).To(Equal("some-handle-init-migrate"))
+				Expect(processSpec.Path).To(Equal("/migrate"))
+				Expect(processSpec.Args).To(Equal([]string{"up"}))
+				Expect(processSpec.Image).To(Equal(garden.ImageRef{
+					URI:      "docker://migrations",
+					Username: "user",
+					Password: "password",
+				}))
+				Expect(processSpec.BindMounts).To(Equal(cfg.BindMounts))
+
+				waitCh <- 0
+				Eventually(gardenContainer.RunCallCount).Should(Equal(2))
+				Consistently(gardenContainer.RunCallCount).Should(Equal(2))
+				processSpec, _ = gardenContainer.RunArgsForCall(1)
+				Expect(processSpec.ID).To(Equal("some-handle-init-1"))
+				Expect(processSpec.Path).To(Equal("/fetch-secrets"))
+				Expect(processSpec.Image).To(Equal(garden.ImageRef{URI: "docker://secrets-agent"}))
+
+				waitCh <- 0
+				Eventually(gardenContainer.RunCallCount).Should(Equal(3))
+				processSpec, _ = gardenContainer.RunArgsForCall(2)
+				Expect(processSpec.Path).To(Equal("/setup/path"))
+				Expect(processSpec.Image).To(Equal(garden.ImageRef{}))
+				waitCh <- 0
+			})
+
+			It("does not run the setup when an init container fails", func() {
+				gardenContainer.RunStub = func(processSpec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+					return &gardenfakes.FakeProcess{WaitStub: func() (int, error) {
+						return 1, nil
+					}}, nil
+				}
+
+				runner, err := optimusPrime.StepsRunner(logger, container, gardenContainer, logStreamer, cfg)
+				Expect(err).NotTo(HaveOccurred())
+				process := ifrit.Background(runner)
+
+				Eventually(process.Wait()).Should(Receive(HaveOccurred()))
+				Expect(gardenContainer.RunCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when a non-critical sidecar has a restart policy", func() {
+			var restarts chan string
+
+			BeforeEach(func() {
+				critical := false
+				container.Setup = nil
+				container.Monitor = nil
+				container.Sidecars = []executor.Sidecar{
+					{
+						Action: &models.Action{
+							RunAction: &models.RunAction{
+								Path: "/sidecar-action",
+							},
+						},
+						RestartPolicy: &executor.RestartPolicy{
+							Policy:           executor.RestartPolicyOnFailure,
+							MaxRestarts:      1,
+							InitialBackoffMs: 1000,
+						},
+						Critical: &critical,
+					},
+				}
+
+				restarts = make(chan string, 1)
+				cfg.OnSidecarRestart = func(sidecar int, restartCount int, err error) {
+					restarts <- fmt.Sprintf("%d %d %v", sidecar, restartCount, err != nil)
+				}
+			})
+
+			It("restarts the sidecar in place and keeps the action running once it gives up", func() {
+				waitCh := make(chan int)
+				defer close(waitCh)
+				gardenContainer.RunStub = func(processSpec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+					if processSpec.Path == "/sidecar-action" {
+						return &gardenfakes.FakeProcess{WaitStub: func() (int, error) {
+							return 1, nil
+						}}, nil
+					}
+					return &gardenfakes.FakeProcess{WaitStub: func() (int, error) {
+						return <-waitCh, nil
+					}}, nil
+				}
+
+				runner, err := optimusPrime.StepsRunner(logger, container, gardenContainer, logStreamer, cfg)
+				Expect(err).NotTo(HaveOccurred())
+				process := ifrit.Background(runner)
+
+				Eventually(gardenContainer.RunCallCount).Should(Equal(2))
+				clock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(restarts).Should(Receive(Equal("0 1 true")))
+				Eventually(gardenContainer.RunCallCount).Should(Equal(3))
+
+				Consistently(process.Wait()).ShouldNot(Receive())
+				Expect(gardenContainer.RunCallCount()).To(Equal(3))
+			})
+		})
+
+		Context("when the container has a restart policy", func() {
+			var restarts chan string
+
+			BeforeEach(func() {
+				container.Setup = nil
+				container.Monitor = nil
+				container.Tags = executor.Tags{executor.LifecycleTag: executor.LRPLifecycle}
+				container.RestartPolicy = &executor.RestartPolicy{
+					Policy:           executor.RestartPolicyOnFailure,
+					MaxRestarts:      1,
+					InitialBackoffMs: 1000,
+				}
+				container.Sidecars = []executor.Sidecar{
+					{
+						Action: &models.Action{
+							RunAction: &models.RunAction{
+								Path: "/sidecar-action",
+							},
+						},
+					},
+				}
+
+				restarts = make(chan string, 1)
+				cfg.OnRestart = func(restartCount int, err error) {
+					restarts <- fmt.Sprintf("%d %v", restartCount, err != nil)
+				}
+			})
+
+			It("restarts only the action and leaves the sidecars running", func() {
+				waitCh := make(chan int)
+				defer close(waitCh)
+				gardenContainer.RunStub = func(processSpec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+					if processSpec.Path == "/action/path" {
+						return &gardenfakes.FakeProcess{WaitStub: func() (int, error) {
+							return 1, nil
+						}}, nil
+					}
+					return &gardenfakes.FakeProcess{WaitStub: func() (int, error) {
+						return <-waitCh, nil
+					}}, nil
+				}
+
+				runner, err := optimusPrime.StepsRunner(logger, container, gardenContainer, logStreamer, cfg)
+				Expect(err).NotTo(HaveOccurred())
+				ifrit.Background(runner)
+
+				Eventually(gardenContainer.RunCallCount).Should(Equal(2))
+				clock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(restarts).Should(Receive(Equal("1 true")))
+				Eventually(gardenContainer.RunCallCount).Should(Equal(3))
+				Consistently(gardenContainer.RunCallCount).Should(Equal(3))
+
+				var paths []string
+				for i := 0; i < gardenContainer.RunCallCount(); i++ {
+					spec, _ := gardenContainer.RunArgsForCall(i)
+					paths = append(paths, spec.Path)
+				}
+				Expect(paths).To(ConsistOf("/action/path", "/action/path", "/sidecar-action"))
+			})
+		})
+
+		It("reports the progress of emit progress actions through the config", func() {
+			container.Setup = &models.Action{
+				EmitProgressAction: &models.EmitProgressAction{
+					Action: &models.Action{
+						RunAction: &models.RunAction{
+							Path: "/setup/path",
+						},
+					},
+					StartMessage: "Downloading...",
+				},
+			}
+
+			phases := make(chan string, 2)
+			cfg.OnProgress = func(step string, phase executor.StepPhase, duration time.Duration, err error) {
+				phases <- fmt.Sprintf("%s %s %v", step, phase, err)
+			}
+
+			runner, err := optimusPrime.StepsRunner(logger, container, gardenContainer, logStreamer, cfg)
+			Expect(err).NotTo(HaveOccurred())
+			process := ifrit.Background(runner)
+
+			Eventually(phases).Should(Receive(Equal("run /setup/path started <nil>")))
+			Eventually(phases).Should(Receive(Equal("run /setup/path succeeded <nil>")))
+
+			process.Signal(os.Interrupt)
+			clock.Increment(1 * time.Second)
+			Eventually(process.Wait()).Should(Receive())
+		})
+
 		It("does not become ready until the healthcheck passes", func() {
 			monitorProcess := &gardenfakes.FakeProcess{}
 			monitorProcess.WaitStub = func() (int, error) {
@@ -937,6 +1255,56 @@ var _ = Describe("Transformer", func() {
 						}))
 					})
 
+					Context("and health check thresholds are set", func() {
+						var checkResults chan int
+
+						BeforeEach(func() {
+							container.HealthCheckThresholds = &executor.HealthCheckThresholds{SuccessThreshold: 2, FailureThreshold: 2}
+
+							checkResults = make(chan int, 4)
+							results, specsCh, action := checkResults, specs, actionProcess
+							gardenContainer.RunStub = func(spec garden.ProcessSpec, io garden.ProcessIO) (garden.Process, error) {
+								if spec.Path == "/action/path" {
+									return action, nil
+								}
+								specsCh <- spec
+								return &gardenfakes.FakeProcess{WaitStub: func() (int, error) {
+									return <-results, nil
+								}}, nil
+							}
+						})
+
+						It("counts consecutive results of single probes", func() {
+							var spec garden.ProcessSpec
+							singleProbeArgs := []string{"-port=5432", "-timeout=100ms", "-uri=/some/path"}
+
+							checkResults <- 0
+							clock.WaitForWatcherAndIncrement(unhealthyMonitoringInterval)
+							Eventually(specs).Should(Receive(&spec))
+							Expect(spec.ID).To(Equal(fmt.Sprintf("%s-%s", gardenContainer.Handle(), "readiness-healthcheck-0")))
+							Expect(spec.Args).To(Equal(singleProbeArgs))
+							Consistently(process.Ready()).ShouldNot(BeClosed())
+
+							checkResults <- 0
+							clock.WaitForWatcherAndIncrement(unhealthyMonitoringInterval)
+							Eventually(specs).Should(Receive())
+							Eventually(process.Ready()).Should(BeClosed())
+
+							checkResults <- 1
+							clock.WaitForWatcherAndIncrement(healthyMonitoringInterval)
+							Eventually(specs).Should(Receive(&spec))
+							Expect(spec.ID).To(Equal(fmt.Sprintf("%s-%s", gardenContainer.Handle(), "liveness-healthcheck-0")))
+							Expect(spec.Args).To(Equal(singleProbeArgs))
+							Consistently(process.Wait()).ShouldNot(Receive())
+
+							checkResults <- 1
+							clock.WaitForWatcherAndIncrement(healthyMonitoringInterval)
+							Eventually(actionProcess.SignalCallCount).Should(Equal(1))
+							actionCh <- 2
+							Eventually(process.Wait()).Should(Receive(HaveOccurred()))
+						})
+					})
+
 					Context("when the readiness check times out", func() {
 						JustBeforeEach(func() {
 							By("waiting for the action and readiness check processes to start")
@@ -991,6 +1359,33 @@ var _ = Describe("Transformer", func() {
 							readinessCh <- 0
 						})
 
+						It("reports the outcome of the readiness probe", func() {
+							probeOutcomes := func() map[string]int {
+								outcomes := map[string]int{}
+								for i := 0; i < fakeMetronClient.SendMetricCallCount(); i++ {
+									name, value, opts := fakeMetronClient.SendMetricArgsForCall(i)
+									if name != steps.HealthCheckProbeSucceeded {
+										continue
+									}
+									e := &loggregator_v2.Envelope{Tags: map[string]string{}}
+									for _, opt := range opts {
+										opt(e)
+									}
+									outcomes[e.Tags["probe"]] = value
+								}
+								return outcomes
+							}
+							Eventually(probeOutcomes).Should(HaveKeyWithValue(steps.ReadinessProbe, 1))
+
+							latencies := 0
+							for i := 0; i < fakeMetronClient.SendDurationCallCount(); i++ {
+								if name, _, _ := fakeMetronClient.SendDurationArgsForCall(i); name == steps.HealthCheckProbeLatency {
+									latencies++
+								}
+							}
+							Expect(latencies).To(BeNumerically(">=", 1))
+						})
+
 						It("starts the liveness check", func() {
 							Eventually(gardenContainer.RunCallCount).Should(Equal(3))
 							ids := []string{}
@@ -1198,6 +1593,177 @@ var _ = Describe("Transformer", func() {
 					})
 				})
 
+				Context("and in-process health checks are enabled", func() {
+					var server *httptest.Server
+
+					BeforeEach(func() {
+						options = append(options, transformer.WithInProcessHealthchecks())
+
+						server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+							w.WriteHeader(http.StatusOK)
+						}))
+						host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+						Expect(err).NotTo(HaveOccurred())
+						port, err := strconv.Atoi(portStr)
+						Expect(err).NotTo(HaveOccurred())
+
+						container.InternalIP = host
+						container.CheckDefinition = &models.CheckDefinition{
+							Checks: []*models.Check{
+								{HttpCheck: &models.HTTPCheck{Port: uint32(port), Path: "/health"}},
+							},
+						}
+					})
+
+					AfterEach(func() {
+						server.Close()
+					})
+
+					It("probes the container without running the healthcheck binary", func() {
+						clock.WaitForWatcherAndIncrement(unhealthyMonitoringInterval)
+						Eventually(process.Ready()).Should(BeClosed())
+
+						for i := 0; i < gardenContainer.RunCallCount(); i++ {
+							spec, _ := gardenContainer.RunArgsForCall(i)
+							Expect(spec.Path).NotTo(Equal(filepath.Join(transformer.HealthCheckDstPath, "healthcheck")))
+						}
+					})
+
+					Context("and the container has a metrics config", func() {
+						BeforeEach(func() {
+							container.MetricsConfig = executor.MetricsConfig{Guid: "some-metrics-guid", Index: 2}
+						})
+
+						It("reports the latency of the probes with the metric tags of the container", func() {
+							clock.WaitForWatcherAndIncrement(unhealthyMonitoringInterval)
+							Eventually(process.Ready()).Should(BeClosed())
+
+							probeTags := func() map[string]string {
+								for i := 0; i < fakeMetronClient.SendDurationCallCount(); i++ {
+									name, _, opts := fakeMetronClient.SendDurationArgsForCall(i)
+									if name != steps.HealthCheckProbeLatency {
+										continue
+									}
+									e := &loggregator_v2.Envelope{Tags: map[string]string{}}
+									for _, opt := range opts {
+										opt(e)
+									}
+									return e.Tags
+								}
+								return nil
+							}
+							Eventually(probeTags).Should(Equal(map[string]string{
+								"source_id":   "some-metrics-guid",
+								"instance_id": "2",
+								"probe":       "readiness",
+							}))
+						})
+					})
+
+					Context("and a health check scheduler is configured", func() {
+						BeforeEach(func() {
+							scheduler := healthcheckscheduler.New(logger, healthCheckWorkPool, 10, 0, fakeMetronClient, time.Minute, clock)
+							options = append(options, transformer.WithHealthCheckScheduler(scheduler))
+						})
+
+						It("probes the container through the scheduler", func() {
+							clock.WaitForWatcherAndIncrement(unhealthyMonitoringInterval)
+							Eventually(process.Ready()).Should(BeClosed())
+						})
+					})
+				})
+
+				Context("and exec health checks are defined", func() {
+					var execCh chan int
+
+					BeforeEach(func() {
+						// get rid of race condition caused by read inside the RunStub
+						processLock.Lock()
+						defer processLock.Unlock()
+
+						execCh = make(chan int, 2)
+
+						container.CheckDefinition = nil
+						container.HealthChecks = []executor.HealthCheck{
+							{Exec: &executor.ExecCheck{
+								Path:      "/exec/check",
+								Args:      []string{"--ready"},
+								User:      "vcap",
+								LogSource: "EXEC",
+							}},
+						}
+
+						gardenContainer.RunStub = func(spec garden.ProcessSpec, io garden.ProcessIO) (garden.Process, error) {
+							defer GinkgoRecover()
+							processLock.Lock()
+							defer processLock.Unlock()
+
+							switch spec.Path {
+							case "/action/path":
+								return actionProcess, nil
+							case "/exec/check":
+								return makeProcess(execCh), nil
+							}
+
+							Fail("unexpected executable path: " + spec.Path)
+							return nil, errors.New("")
+						}
+					})
+
+					JustBeforeEach(func() {
+						clock.WaitForWatcherAndIncrement(unhealthyMonitoringInterval)
+					})
+
+					It("runs the check inside the container until it passes", func() {
+						Eventually(gardenContainer.RunCallCount).Should(Equal(2))
+						var checkSpec garden.ProcessSpec
+						for i := 0; i < gardenContainer.RunCallCount(); i++ {
+							spec, _ := gardenContainer.RunArgsForCall(i)
+							if spec.Path == "/exec/check" {
+								checkSpec = spec
+							}
+						}
+						Expect(checkSpec.Args).To(Equal([]string{"--ready"}))
+						Expect(checkSpec.User).To(Equal("vcap"))
+
+						Consistently(process.Ready()).ShouldNot(BeClosed())
+						execCh <- 0
+						Eventually(process.Ready()).Should(BeClosed())
+					})
+
+					It("logs liveness failures with the log source of the check", func() {
+						execCh <- 0
+						Eventually(process.Ready()).Should(BeClosed())
+
+						clock.WaitForWatcherAndIncrement(healthyMonitoringInterval)
+						execCh <- 1
+
+						Eventually(process.Wait()).Should(Receive(HaveOccurred()))
+						var sources []string
+						for i := 0; i < fakeMetronClient.SendAppErrorLogCallCount(); i++ {
+							_, sourceName, _ := fakeMetronClient.SendAppErrorLogArgsForCall(i)
+							sources = append(sources, sourceName)
+						}
+						Expect(sources).To(ContainElement("EXEC"))
+					})
+
+					Context("with a success threshold", func() {
+						BeforeEach(func() {
+							container.HealthCheckThresholds = &executor.HealthCheckThresholds{SuccessThreshold: 2}
+						})
+
+						It("becomes healthy after consecutive passing checks", func() {
+							execCh <- 0
+							Eventually(gardenContainer.RunCallCount).Should(Equal(2))
+							Consistently(process.Ready()).ShouldNot(BeClosed())
+
+							clock.WaitForWatcherAndIncrement(unhealthyMonitoringInterval)
+							execCh <- 0
+							Eventually(process.Ready()).Should(BeClosed())
+						})
+					})
+				})
+
 				Context("and multiple check definitions exists", func() {
 					var (
 						otherReadinessProcess *gardenfakes.FakeProcess
@@ -1486,6 +2052,146 @@ var _ = Describe("Transformer", func() {
 			})
 		})
 
+		Context("when there is a setup retry policy", func() {
+			BeforeEach(func() {
+				container.SetupRetryPolicy = &executor.RetryPolicy{
+					MaxAttempts:      2,
+					InitialBackoffMs: 1000,
+				}
+			})
+
+			It("retries the setup when it fails", func() {
+				setupProcess := &gardenfakes.FakeProcess{}
+				setupProcess.WaitStub = func() (int, error) {
+					if setupProcess.WaitCallCount() == 1 {
+						return 1, nil
+					}
+					return 0, nil
+				}
+				gardenContainer.RunStub = func(processSpec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+					if processSpec.Path == "/setup/path" {
+						return setupProcess, nil
+					}
+					return &gardenfakes.FakeProcess{}, nil
+				}
+
+				runner, err := optimusPrime.StepsRunner(logger, container, gardenContainer, logStreamer, cfg)
+				Expect(err).NotTo(HaveOccurred())
+				process := ifrit.Background(runner)
+				defer process.Signal(os.Kill)
+
+				Eventually(gardenContainer.RunCallCount).Should(Equal(1))
+				Consistently(gardenContainer.RunCallCount).Should(Equal(1))
+
+				clock.WaitForWatcherAndIncrement(1 * time.Second)
+				Eventually(gardenContainer.RunCallCount).Should(BeNumerically(">=", 3))
+
+				processSpec, _ := gardenContainer.RunArgsForCall(1)
+				Expect(processSpec.Path).To(Equal("/setup/path"))
+				processSpec, _ = gardenContainer.RunArgsForCall(2)
+				Expect(processSpec.Path).To(Equal("/action/path"))
+			})
+		})
+
+		Context("when there is a stop policy", func() {
+			var (
+				actionProcess *gardenfakes.FakeProcess
+				actionExited  chan struct{}
+			)
+
+			BeforeEach(func() {
+				container.Setup = nil
+				container.Monitor = nil
+				container.StopPolicy = &executor.StopPolicy{}
+
+				actionExited = make(chan struct{})
+				actionProcess = &gardenfakes.FakeProcess{}
+				actionProcess.WaitStub = func() (int, error) {
+					<-actionExited
+					return 143, nil
+				}
+				actionProcess.SignalStub = func(garden.Signal) error {
+					if actionProcess.SignalCallCount() == 1 {
+						close(actionExited)
+					}
+					return nil
+				}
+				gardenContainer.RunReturns(actionProcess, nil)
+			})
+
+			Context("with a pre-stop hook and a stop signal", func() {
+				var preStopExited chan struct{}
+
+				BeforeEach(func() {
+					container.StopPolicy.Signal = executor.StopSignalKill
+					container.StopPolicy.PreStop = &executor.PreStopHook{
+						Path: "/pre-stop/path",
+						Args: []string{"deregister"},
+					}
+
+					preStopExited = make(chan struct{})
+					preStopProcess := &gardenfakes.FakeProcess{}
+					preStopProcess.WaitStub = func() (int, error) {
+						<-preStopExited
+						return 0, nil
+					}
+					gardenContainer.RunStub = func(processSpec garden.ProcessSpec, processIO garden.ProcessIO) (garden.Process, error) {
+						if processSpec.Path == "/pre-stop/path" {
+							return preStopProcess, nil
+						}
+						return actionProcess, nil
+					}
+				})
+
+				It("runs the hook before sending the stop signal", func() {
+					runner, err := optimusPrime.StepsRunner(logger, container, gardenContainer, logStreamer, cfg)
+					Expect(err).NotTo(HaveOccurred())
+					process := ifrit.Background(runner)
+
+					Eventually(gardenContainer.RunCallCount).Should(Equal(1))
+					process.Signal(os.Interrupt)
+
+					Eventually(gardenContainer.RunCallCount).Should(Equal(2))
+					processSpec, _ := gardenContainer.RunArgsForCall(1)
+					Expect(processSpec.Path).To(Equal("/pre-stop/path"))
+					Expect(processSpec.Args).To(Equal([]string{"deregister"}))
+					Consistently(actionProcess.SignalCallCount).Should(BeZero())
+
+					close(preStopExited)
+					Eventually(actionProcess.SignalCallCount).Should(Equal(1))
+					Expect(actionProcess.SignalArgsForCall(0)).To(Equal(garden.SignalKill))
+					Eventually(process.Wait()).Should(Receive())
+				})
+			})
+
+			Context("with a graceful shutdown interval above the cell's", func() {
+				BeforeEach(func() {
+					container.StopPolicy.GracefulShutdownIntervalMs = 60000
+					actionProcess.SignalStub = nil
+				})
+
+				AfterEach(func() {
+					close(actionExited)
+				})
+
+				It("caps the interval at the cell's graceful shutdown interval", func() {
+					runner, err := optimusPrime.StepsRunner(logger, container, gardenContainer, logStreamer, cfg)
+					Expect(err).NotTo(HaveOccurred())
+					process := ifrit.Background(runner)
+
+					Eventually(gardenContainer.RunCallCount).Should(Equal(1))
+					process.Signal(os.Interrupt)
+
+					Eventually(actionProcess.SignalCallCount).Should(Equal(1))
+					Expect(actionProcess.SignalArgsForCall(0)).To(Equal(garden.SignalTerminate))
+
+					clock.WaitForWatcherAndIncrement(gracefulShutdownInterval + time.Second)
+					Eventually(actionProcess.SignalCallCount).Should(Equal(2))
+					Expect(actionProcess.SignalArgsForCall(1)).To(Equal(garden.SignalKill))
+				})
+			})
+		})
+
 		Context("when there is no monitor", func() {
 			BeforeEach(func() {
 				container.Monitor = nil
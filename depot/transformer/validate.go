@@ -0,0 +1,254 @@
+package transformer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/lager"
+)
+
+func (t *transformer) Validate(logger lager.Logger, runInfo executor.RunInfo, tags executor.Tags) error {
+	v := &validator{maxResourceLimits: t.maxResourceLimits}
+
+	if runInfo.Setup != nil {
+		v.validateAction("setup", runInfo.Setup)
+	}
+
+	if policy := runInfo.SetupRetryPolicy; policy != nil {
+		if policy.Jitter < 0 || policy.Jitter > 1 {
+			v.addProblem("setup_retry_policy", "jitter must be between 0 and 1")
+		}
+		if policy.MaxBackoffMs > 0 && policy.MaxBackoffMs < policy.InitialBackoffMs {
+			v.addProblem("setup_retry_policy", "max backoff must not be less than the initial backoff")
+		}
+	}
+
+	v.validateResourceLimits("resource_limits", runInfo.ResourceLimits)
+
+	if policy := runInfo.RestartPolicy; policy != nil {
+		v.validateRestartPolicy("restart_policy", policy)
+		restarts := policy.Policy != "" && policy.Policy != executor.RestartPolicyNever
+		if restarts && tags[executor.LifecycleTag] != executor.LRPLifecycle {
+			v.addProblem("restart_policy", "is only supported for long-running processes")
+		}
+	}
+
+	if policy := runInfo.StopPolicy; policy != nil {
+		switch policy.Signal {
+		case "", executor.StopSignalTerminate, executor.StopSignalKill:
+		default:
+			v.addProblem("stop_policy", fmt.Sprintf("unsupported signal: %q", policy.Signal))
+		}
+		if hook := policy.PreStop; hook != nil {
+			if hook.Path == "" {
+				v.addProblem("stop_policy.pre_stop", "path is required")
+			}
+			v.validateUser("stop_policy.pre_stop", hook.User)
+		}
+	}
+
+	if runInfo.Action == nil {
+		v.addProblem("action", "is required")
+	} else {
+		v.validateAction("action", runInfo.Action)
+	}
+
+	if runInfo.Monitor != nil {
+		v.validateAction("monitor", runInfo.Monitor)
+	}
+
+	for i, initContainer := range runInfo.InitContainers {
+		path := fmt.Sprintf("init_containers[%d]", i)
+		if initContainer.Image == "" {
+			v.addProblem(path, "image is required")
+		}
+		if initContainer.Action == nil {
+			v.addProblem(path, "run is required")
+			continue
+		}
+		v.validateAction(path, models.WrapAction(initContainer.Action))
+	}
+
+	for i, sidecar := range runInfo.Sidecars {
+		path := fmt.Sprintf("sidecars[%d]", i)
+		if sidecar.Action == nil {
+			v.addProblem(path, "action is required")
+			continue
+		}
+		v.validateAction(path, sidecar.Action)
+		if sidecar.RestartPolicy != nil {
+			v.validateRestartPolicy(path+".restart_policy", sidecar.RestartPolicy)
+		}
+	}
+
+	// check definitions are ignored when declarative health checks are disabled
+	if runInfo.CheckDefinition != nil && t.useDeclarativeHealthCheck {
+		for i, check := range runInfo.CheckDefinition.Checks {
+			path := fmt.Sprintf("check_definition.checks[%d]", i)
+			if check == nil {
+				v.addProblem(path, "is empty")
+				continue
+			}
+			if err := check.Validate(); err != nil {
+				v.addProblem(path, err.Error())
+			}
+		}
+	}
+
+	for i, check := range runInfo.HealthChecks {
+		v.validateHealthCheck(fmt.Sprintf("health_checks[%d]", i), check)
+	}
+
+	if len(v.problems) > 0 {
+		err := executor.NewStepsInvalidError(v.problems)
+		logger.Error("invalid-steps", err)
+		return err
+	}
+
+	return nil
+}
+
+type validator struct {
+	maxResourceLimits executor.ResourceLimits
+	problems          []string
+}
+
+func (v *validator) addProblem(path, problem string) {
+	v.problems = append(v.problems, fmt.Sprintf("%s: %s", path, problem))
+}
+
+func (v *validator) validateHealthCheck(path string, check executor.HealthCheck) {
+	switch {
+	case check.Exec != nil && check.GRPC != nil:
+		v.addProblem(path, "only one of exec and grpc may be set")
+	case check.Exec != nil:
+		if check.Exec.Path == "" {
+			v.addProblem(path+".exec", "path is required")
+		}
+		v.validateUser(path+".exec", check.Exec.User)
+	case check.GRPC != nil:
+		if check.GRPC.Port == 0 || check.GRPC.Port > 65535 {
+			v.addProblem(path+".grpc", fmt.Sprintf("port %d is out of range", check.GRPC.Port))
+		}
+	default:
+		v.addProblem(path, "one of exec and grpc is required")
+	}
+}
+
+func (v *validator) validateAction(path string, action *models.Action) {
+	switch actionModel := action.GetValue().(type) {
+	case *models.RunAction:
+		path += ".run"
+		if actionModel.Path == "" {
+			v.addProblem(path, "path is required")
+		}
+		v.validateUser(path, actionModel.User)
+		if actionModel.ResourceLimits != nil {
+			v.validateResourceLimits(path+".resource_limits", &executor.ResourceLimits{
+				Nofile: actionModel.ResourceLimits.GetNofilePtr(),
+			})
+		}
+
+	case *models.DownloadAction:
+		path += ".download"
+		v.validateURL(path, "from", actionModel.From)
+		if actionModel.To == "" {
+			v.addProblem(path, "to is required")
+		}
+		v.validateUser(path, actionModel.User)
+
+	case *models.UploadAction:
+		path += ".upload"
+		if actionModel.From == "" {
+			v.addProblem(path, "from is required")
+		}
+		v.validateURL(path, "to", actionModel.To)
+		v.validateUser(path, actionModel.User)
+
+	case *models.EmitProgressAction:
+		v.validateSubAction(path+".emit_progress", actionModel.Action)
+
+	case *models.TimeoutAction:
+		path += ".timeout"
+		if actionModel.TimeoutMs <= 0 {
+			v.addProblem(path, "timeout must be greater than zero")
+		}
+		v.validateSubAction(path, actionModel.Action)
+
+	case *models.TryAction:
+		v.validateSubAction(path+".try", actionModel.Action)
+
+	case *models.ParallelAction:
+		v.validateSubActions(path+".parallel", actionModel.Actions)
+
+	case *models.CodependentAction:
+		v.validateSubActions(path+".codependent", actionModel.Actions)
+
+	case *models.SerialAction:
+		v.validateSubActions(path+".serial", actionModel.Actions)
+
+	default:
+		v.addProblem(path, fmt.Sprintf("unknown action: %T", actionModel))
+	}
+}
+
+func (v *validator) validateResourceLimits(path string, limits *executor.ResourceLimits) {
+	for _, name := range limits.Exceeding(v.maxResourceLimits) {
+		v.addProblem(path, fmt.Sprintf("%s exceeds the cell maximum", name))
+	}
+}
+
+func (v *validator) validateRestartPolicy(path string, policy *executor.RestartPolicy) {
+	switch policy.Policy {
+	case "", executor.RestartPolicyNever, executor.RestartPolicyOnFailure, executor.RestartPolicyAlways:
+	default:
+		v.addProblem(path, fmt.Sprintf("unknown policy %q", policy.Policy))
+	}
+	if policy.MaxBackoffMs > 0 && policy.MaxBackoffMs < policy.InitialBackoffMs {
+		v.addProblem(path, "max backoff must not be less than the initial backoff")
+	}
+}
+
+func (v *validator) validateSubAction(path string, action *models.Action) {
+	if action == nil {
+		v.addProblem(path, "action is required")
+		return
+	}
+	v.validateAction(path, action)
+}
+
+func (v *validator) validateSubActions(path string, actions []*models.Action) {
+	if len(actions) == 0 {
+		v.addProblem(path, "actions must not be empty")
+		return
+	}
+	for i, action := range actions {
+		v.validateSubAction(fmt.Sprintf("%s[%d]", path, i), action)
+	}
+}
+
+func (v *validator) validateURL(path, field, rawURL string) {
+	if rawURL == "" {
+		v.addProblem(path, field+" is required")
+		return
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		v.addProblem(path, fmt.Sprintf("%s is not a valid url: %s", field, err.Error()))
+		return
+	}
+	if u.Scheme == "" {
+		v.addProblem(path, fmt.Sprintf("%s is not an absolute url: %q", field, rawURL))
+	}
+}
+
+// an empty user runs as the default user of the container
+func (v *validator) validateUser(path, user string) {
+	if strings.ContainsAny(user, ":/ \t\n") {
+		v.addProblem(path, fmt.Sprintf("user is invalid: %q", user))
+	}
+}
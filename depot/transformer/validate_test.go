@@ -0,0 +1,329 @@
+package transformer_test
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/executor"
+	"code.cloudfoundry.org/executor/depot/transformer"
+	"code.cloudfoundry.org/lager/lagertest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate", func() {
+	var (
+		logger       *lagertest.TestLogger
+		optimusPrime transformer.Transformer
+		runInfo      executor.RunInfo
+		tags         executor.Tags
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		optimusPrime = transformer.NewTransformer(
+			fakeclock.NewFakeClock(time.Now()),
+			nil, nil, nil, nil,
+			os.TempDir(),
+			time.Second,
+			time.Millisecond,
+			time.Second,
+			nil,
+		)
+
+		runInfo = executor.RunInfo{
+			Setup: models.WrapAction(&models.DownloadAction{
+				From: "http://example.com/droplet",
+				To:   "/tmp",
+				User: "vcap",
+			}),
+			Action: models.WrapAction(&models.RunAction{
+				Path: "/action/path",
+				User: "vcap",
+			}),
+			Monitor: models.WrapAction(models.Timeout(
+				&models.RunAction{Path: "/monitor/path", User: "vcap"},
+				time.Second,
+			)),
+		}
+		tags = executor.Tags{executor.LifecycleTag: executor.LRPLifecycle}
+	})
+
+	It("succeeds for a valid action tree", func() {
+		Expect(optimusPrime.Validate(logger, runInfo, tags)).To(Succeed())
+	})
+
+	Context("when there is no action", func() {
+		BeforeEach(func() {
+			runInfo.Action = nil
+		})
+
+		It("returns a steps invalid error", func() {
+			err := optimusPrime.Validate(logger, runInfo, tags)
+			Expect(err).To(BeAssignableToTypeOf(executor.StepsInvalidError{}))
+			Expect(err.(executor.Error).Name()).To(Equal(executor.ErrStepsInvalid.Name()))
+			Expect(err.(executor.StepsInvalidError).Problems).To(ConsistOf("action: is required"))
+		})
+	})
+
+	Context("when the action is empty", func() {
+		BeforeEach(func() {
+			runInfo.Action = &models.Action{}
+		})
+
+		It("reports an unknown action", func() {
+			err := optimusPrime.Validate(logger, runInfo, tags)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(executor.StepsInvalidError).Problems).To(ConsistOf(ContainSubstring("action: unknown action")))
+		})
+	})
+
+	Context("when the tree has several problems", func() {
+		BeforeEach(func() {
+			runInfo.Setup = models.WrapAction(models.Serial(
+				&models.DownloadAction{From: "not a url", To: "/tmp"},
+				&models.UploadAction{From: "/tmp/file", To: ""},
+			))
+			runInfo.Action = models.WrapAction(models.Codependent(
+				&models.RunAction{Path: "", User: "vcap"},
+				models.Parallel(),
+			))
+			runInfo.Monitor = models.WrapAction(&models.TimeoutAction{
+				Action: models.WrapAction(&models.RunAction{Path: "/monitor/path", User: "bad user"}),
+			})
+			runInfo.Sidecars = []executor.Sidecar{{}}
+		})
+
+		It("reports every problem at once", func() {
+			err := optimusPrime.Validate(logger, runInfo, tags)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(executor.StepsInvalidError).Problems).To(ConsistOf(
+				`setup.serial[0].download: from is not an absolute url: "not a url"`,
+				"setup.serial[1].upload: to is required",
+				"action.codependent[0].run: path is required",
+				"action.codependent[1].parallel: actions must not be empty",
+				"monitor.timeout: timeout must be greater than zero",
+				`monitor.timeout.run: user is invalid: "bad user"`,
+				"sidecars[0]: action is required",
+			))
+		})
+	})
+
+	Context("when the setup retry policy is invalid", func() {
+		BeforeEach(func() {
+			runInfo.SetupRetryPolicy = &executor.RetryPolicy{
+				MaxAttempts: 3,
+				Jitter:      1.5,
+			}
+		})
+
+		It("reports the invalid policy", func() {
+			err := optimusPrime.Validate(logger, runInfo, tags)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(executor.StepsInvalidError).Problems).To(ConsistOf("setup_retry_policy: jitter must be between 0 and 1"))
+		})
+	})
+
+	Context("when an init container is invalid", func() {
+		BeforeEach(func() {
+			runInfo.InitContainers = []executor.InitContainer{
+				{Action: &models.RunAction{Path: "/migrate", User: "bad user"}},
+				{Image: "docker://secrets-agent"},
+			}
+		})
+
+		It("reports the invalid init containers", func() {
+			err := optimusPrime.Validate(logger, runInfo, tags)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(executor.StepsInvalidError).Problems).To(ConsistOf(
+				"init_containers[0]: image is required",
+				`init_containers[0].run: user is invalid: "bad user"`,
+				"init_containers[1]: run is required",
+			))
+		})
+	})
+
+	Context("when a sidecar restart policy is invalid", func() {
+		BeforeEach(func() {
+			runInfo.Sidecars = []executor.Sidecar{
+				{
+					Action: models.WrapAction(&models.RunAction{Path: "sidecar", User: "vcap"}),
+					RestartPolicy: &executor.RestartPolicy{
+						Policy:           "sometimes",
+						InitialBackoffMs: 2000,
+						MaxBackoffMs:     1000,
+					},
+				},
+			}
+		})
+
+		It("reports the invalid policy", func() {
+			err := optimusPrime.Validate(logger, runInfo, tags)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(executor.StepsInvalidError).Problems).To(ConsistOf(
+				`sidecars[0].restart_policy: unknown policy "sometimes"`,
+				"sidecars[0].restart_policy: max backoff must not be less than the initial backoff",
+			))
+		})
+	})
+
+	Context("when the container has a restart policy", func() {
+		BeforeEach(func() {
+			runInfo.RestartPolicy = &executor.RestartPolicy{Policy: executor.RestartPolicyAlways}
+		})
+
+		It("accepts it for a long-running process", func() {
+			Expect(optimusPrime.Validate(logger, runInfo, tags)).To(Succeed())
+		})
+
+		It("rejects it for a task", func() {
+			tags = executor.Tags{executor.LifecycleTag: executor.TaskLifecycle}
+			err := optimusPrime.Validate(logger, runInfo, tags)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(executor.StepsInvalidError).Problems).To(ConsistOf(
+				"restart_policy: is only supported for long-running processes",
+			))
+		})
+
+		It("rejects it when the lifecycle of the container is unknown", func() {
+			err := optimusPrime.Validate(logger, runInfo, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("accepts a policy that never restarts for a task", func() {
+			runInfo.RestartPolicy.Policy = executor.RestartPolicyNever
+			tags = executor.Tags{executor.LifecycleTag: executor.TaskLifecycle}
+			Expect(optimusPrime.Validate(logger, runInfo, tags)).To(Succeed())
+		})
+
+		It("reports an unknown policy", func() {
+			runInfo.RestartPolicy.Policy = "sometimes"
+			err := optimusPrime.Validate(logger, runInfo, tags)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(executor.StepsInvalidError).Problems).To(ConsistOf(
+				`restart_policy: unknown policy "sometimes"`,
+			))
+		})
+	})
+
+	Context("when a check definition is invalid", func() {
+		BeforeEach(func() {
+			runInfo.CheckDefinition = &models.CheckDefinition{
+				Checks: []*models.Check{
+					{HttpCheck: &models.HTTPCheck{Port: 8080}},
+					{},
+				},
+			}
+		})
+
+		It("ignores the checks", func() {
+			Expect(optimusPrime.Validate(logger, runInfo, tags)).To(Succeed())
+		})
+
+		Context("when declarative health checks are enabled", func() {
+			BeforeEach(func() {
+				optimusPrime = transformer.NewTransformer(
+					fakeclock.NewFakeClock(time.Now()),
+					nil, nil, nil, nil,
+					os.TempDir(),
+					time.Second,
+					time.Millisecond,
+					time.Second,
+					nil,
+					transformer.WithDeclarativeHealthchecks(),
+				)
+			})
+
+			It("reports the invalid check", func() {
+				err := optimusPrime.Validate(logger, runInfo, tags)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(executor.StepsInvalidError).Problems).To(ConsistOf(HavePrefix("check_definition.checks[1]: ")))
+			})
+		})
+	})
+
+	Context("when health checks are invalid", func() {
+		BeforeEach(func() {
+			runInfo.HealthChecks = []executor.HealthCheck{
+				{Exec: &executor.ExecCheck{Path: "/bin/check"}},
+				{GRPC: &executor.GRPCCheck{Port: 8080}},
+				{},
+				{Exec: &executor.ExecCheck{User: "bad user"}},
+				{GRPC: &executor.GRPCCheck{Port: 70000}},
+				{Exec: &executor.ExecCheck{Path: "/bin/check"}, GRPC: &executor.GRPCCheck{Port: 8080}},
+			}
+		})
+
+		It("reports every invalid check", func() {
+			err := optimusPrime.Validate(logger, runInfo, tags)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(executor.StepsInvalidError).Problems).To(ConsistOf(
+				"health_checks[2]: one of exec and grpc is required",
+				"health_checks[3].exec: path is required",
+				`health_checks[3].exec: user is invalid: "bad user"`,
+				"health_checks[4].grpc: port 70000 is out of range",
+				"health_checks[5]: only one of exec and grpc may be set",
+			))
+		})
+	})
+
+	Context("when resource limits exceed the cell maxima", func() {
+		BeforeEach(func() {
+			maxCore, maxNofile, core, nofile, stack := uint64(0), uint64(1024), uint64(1), uint64(2048), uint64(1<<30)
+			optimusPrime = transformer.NewTransformer(
+				fakeclock.NewFakeClock(time.Now()),
+				nil, nil, nil, nil,
+				os.TempDir(),
+				time.Second,
+				time.Millisecond,
+				time.Second,
+				nil,
+				transformer.WithMaxResourceLimits(executor.ResourceLimits{
+					Core:   &maxCore,
+					Nofile: &maxNofile,
+				}),
+			)
+
+			runInfo.ResourceLimits = &executor.ResourceLimits{
+				Core:  &core,
+				Stack: &stack,
+			}
+
+			rl := &models.ResourceLimits{}
+			rl.SetNofile(nofile)
+			runInfo.Action = models.WrapAction(&models.RunAction{
+				Path:           "/action/path",
+				ResourceLimits: rl,
+			})
+		})
+
+		It("reports every limit above its maximum", func() {
+			err := optimusPrime.Validate(logger, runInfo, tags)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(executor.StepsInvalidError).Problems).To(ConsistOf(
+				"resource_limits: core exceeds the cell maximum",
+				"action.run.resource_limits: nofile exceeds the cell maximum",
+			))
+		})
+	})
+
+	Context("when the stop policy is invalid", func() {
+		BeforeEach(func() {
+			runInfo.StopPolicy = &executor.StopPolicy{
+				Signal:  "QUIT",
+				PreStop: &executor.PreStopHook{},
+			}
+		})
+
+		It("reports the invalid policy", func() {
+			err := optimusPrime.Validate(logger, runInfo, tags)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(executor.StepsInvalidError).Problems).To(ConsistOf(
+				`stop_policy: unsupported signal: "QUIT"`,
+				"stop_policy.pre_stop: path is required",
+			))
+		})
+	})
+})
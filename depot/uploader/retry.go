@@ -0,0 +1,194 @@
+package uploader
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultUploadAttempts = 3
+
+	UploadSucceededCounter         = "UploadSucceeded"
+	UploadRetriedCounter           = "UploadRetried"
+	UploadCancelledCounter         = "UploadCancelled"
+	UploadFailedAuthCounter        = "UploadFailedAuth"
+	UploadFailedClientErrorCounter = "UploadFailedClientError"
+	UploadFailedServerErrorCounter = "UploadFailedServerError"
+	UploadFailedTimeoutCounter     = "UploadFailedTimeout"
+	UploadFailedNetworkCounter     = "UploadFailedNetwork"
+	UploadFailedOtherCounter       = "UploadFailedOther"
+)
+
+// DefaultRetryableStatusCodes are retried unless the operator configures
+// another set. Timeouts and network errors are always retried.
+var DefaultRetryableStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+type FailureClass string
+
+const (
+	FailureAuth        FailureClass = "auth"
+	FailureClientError FailureClass = "client-error"
+	FailureServerError FailureClass = "server-error"
+	FailureTimeout     FailureClass = "timeout"
+	FailureNetwork     FailureClass = "network"
+)
+
+// UploadError is a failed upload request, classified by its cause.
+// Cancelled uploads return ErrUploadCancelled instead.
+type UploadError struct {
+	Class      FailureClass
+	StatusCode int
+	Err        error
+}
+
+func (e *UploadError) Error() string {
+	return e.Err.Error()
+}
+
+// IsRetryable is false for client errors other than timeouts and rate
+// limiting, which fail the same way when the upload is repeated.
+func (e *UploadError) IsRetryable() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return e.StatusCode < 400 || e.StatusCode >= 500
+}
+
+func statusCodeError(statusCode int) *UploadError {
+	class := FailureServerError
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		class = FailureAuth
+	case statusCode < 500:
+		class = FailureClientError
+	}
+
+	return &UploadError{
+		Class:      class,
+		StatusCode: statusCode,
+		Err:        fmt.Errorf("Upload failed: Status code %d", statusCode),
+	}
+}
+
+func requestError(err error) *UploadError {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return &UploadError{Class: FailureTimeout, Err: err}
+	}
+	return &UploadError{Class: FailureNetwork, Err: err}
+}
+
+func (uploader *URLUploader) isRetryable(err error) bool {
+	uploadErr, ok := err.(*UploadError)
+	if !ok {
+		return false
+	}
+
+	switch uploadErr.Class {
+	case FailureTimeout, FailureNetwork:
+		return true
+	}
+
+	for _, code := range uploader.retryableStatusCodes {
+		if code == uploadErr.StatusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectsStreaming reports whether the destination refused a chunked request,
+// as receivers that require a Content-Length or a Content-MD5 header do.
+func rejectsStreaming(err error) bool {
+	uploadErr, ok := err.(*UploadError)
+	if !ok {
+		return false
+	}
+
+	switch uploadErr.StatusCode {
+	case http.StatusBadRequest, http.StatusLengthRequired, http.StatusUnsupportedMediaType:
+		return true
+	}
+	return false
+}
+
+// backoff returns how long to wait before the given retry. Without an initial
+// backoff in the policy, retries are made immediately.
+func (uploader *URLUploader) backoff(retry int) time.Duration {
+	if uploader.retryPolicy.InitialBackoffMs == 0 {
+		return 0
+	}
+
+	backoff := time.Duration(uploader.retryPolicy.InitialBackoffMs) * time.Millisecond
+	max := time.Duration(uploader.retryPolicy.MaxBackoffMs) * time.Millisecond
+	for i := 1; i < retry && (max == 0 || backoff < max); i++ {
+		backoff *= 2
+	}
+	if max > 0 && backoff > max {
+		backoff = max
+	}
+
+	jitter := uploader.retryPolicy.Jitter
+	if jitter > 1 {
+		jitter = 1
+	}
+	if jitter > 0 {
+		backoff -= time.Duration(jitter * rand.Float64() * float64(backoff))
+	}
+
+	return backoff
+}
+
+func (uploader *URLUploader) maxAttempts() int {
+	if uploader.retryPolicy.MaxAttempts < 1 {
+		return DefaultUploadAttempts
+	}
+	return uploader.retryPolicy.MaxAttempts
+}
+
+func (uploader *URLUploader) emitOutcome(err error) {
+	if uploader.metronClient == nil {
+		return
+	}
+
+	counter := UploadFailedOtherCounter
+	switch e := err.(type) {
+	case nil:
+		counter = UploadSucceededCounter
+	case *UploadError:
+		switch e.Class {
+		case FailureAuth:
+			counter = UploadFailedAuthCounter
+		case FailureClientError:
+			counter = UploadFailedClientErrorCounter
+		case FailureServerError:
+			counter = UploadFailedServerErrorCounter
+		case FailureTimeout:
+			counter = UploadFailedTimeoutCounter
+		case FailureNetwork:
+			counter = UploadFailedNetworkCounter
+		}
+	default:
+		if err == ErrUploadCancelled {
+			counter = UploadCancelledCounter
+		}
+	}
+
+	uploader.metronClient.IncrementCounter(counter)
+}
+
+func (uploader *URLUploader) emitRetry() {
+	if uploader.metronClient != nil {
+		uploader.metronClient.IncrementCounter(UploadRetriedCounter)
+	}
+}
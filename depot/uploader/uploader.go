@@ -2,10 +2,11 @@ package uploader
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
 	"crypto/tls"
 	"encoding/base64"
 	"errors"
-	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"net"
@@ -14,13 +15,26 @@ import (
 	"os"
 	"time"
 
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/lager"
 )
 
 var ErrUploadCancelled = errors.New("upload cancelled")
 
+// StreamSource opens the content of a streaming upload. It is called again
+// when the content has to be spooled to disk for a retry.
+type StreamSource func() (io.ReadCloser, error)
+
 type Uploader interface {
 	Upload(fileLocation string, destinationUrl *url.URL, cancel <-chan struct{}) (int64, error)
+
+	// UploadStream streams the content with chunked transfer encoding and sends
+	// its digests as trailers. If that fails, the content is spooled into
+	// spoolDir and the remaining attempts are made from disk. Destinations
+	// that reject the chunked request get all of the attempts from disk.
+	UploadStream(source StreamSource, spoolDir string, destinationUrl *url.URL, cancel <-chan struct{}) (int64, error)
 }
 
 type URLUploader struct {
@@ -28,9 +42,41 @@ type URLUploader struct {
 	tlsConfig  *tls.Config
 	transport  *http.Transport
 	logger     lager.Logger
+
+	retryPolicy          executor.RetryPolicy
+	retryableStatusCodes []int
+	metronClient         loggingclient.IngressClient
+	clock                clock.Clock
+}
+
+type Option func(*URLUploader)
+
+// WithRetryPolicy replaces the default of three immediate attempts. Requests
+// failing with one of retryableStatusCodes are retried; nil keeps
+// DefaultRetryableStatusCodes.
+func WithRetryPolicy(policy executor.RetryPolicy, retryableStatusCodes []int) Option {
+	return func(u *URLUploader) {
+		u.retryPolicy = policy
+		if retryableStatusCodes != nil {
+			u.retryableStatusCodes = retryableStatusCodes
+		}
+	}
+}
+
+// WithMetronClient emits a counter for the outcome of every upload.
+func WithMetronClient(metronClient loggingclient.IngressClient) Option {
+	return func(u *URLUploader) {
+		u.metronClient = metronClient
+	}
+}
+
+func WithClock(clock clock.Clock) Option {
+	return func(u *URLUploader) {
+		u.clock = clock
+	}
 }
 
-func New(logger lager.Logger, timeout time.Duration, tlsConfig *tls.Config) Uploader {
+func New(logger lager.Logger, timeout time.Duration, tlsConfig *tls.Config, opts ...Option) Uploader {
 	transport := &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		Dial: (&net.Dialer{
@@ -46,31 +92,108 @@ func New(logger lager.Logger, timeout time.Duration, tlsConfig *tls.Config) Uplo
 		Timeout:   timeout,
 	}
 
-	return &URLUploader{
-		httpClient: httpClient,
-		tlsConfig:  tlsConfig,
-		transport:  transport,
-		logger:     logger.Session("URLUploader"),
+	uploader := &URLUploader{
+		httpClient:           httpClient,
+		tlsConfig:            tlsConfig,
+		transport:            transport,
+		logger:               logger.Session("URLUploader"),
+		retryableStatusCodes: DefaultRetryableStatusCodes,
+		clock:                clock.NewClock(),
 	}
+
+	for _, opt := range opts {
+		opt(uploader)
+	}
+
+	return uploader
 }
 
-func (uploader *URLUploader) Upload(fileLocation string, url *url.URL, cancel <-chan struct{}) (int64, error) {
+func (uploader *URLUploader) Upload(fileLocation string, url *url.URL, cancel <-chan struct{}) (_ int64, err error) {
+	defer func() { uploader.emitOutcome(err) }()
+
 	logger := uploader.logger.WithData(lager.Data{"fileLocation": fileLocation})
 
-	sourceFile, bytesToUpload, contentMD5, err := uploader.prepareFileForUpload(fileLocation, logger)
+	sourceFile, bytesToUpload, digests, err := uploader.prepareFileForUpload(fileLocation, logger)
 	if err != nil {
 		return 0, err
 	}
 	defer sourceFile.Close()
 
+	return uploader.uploadFile(sourceFile, bytesToUpload, digests, url, 0, cancel, logger)
+}
+
+func (uploader *URLUploader) UploadStream(source StreamSource, spoolDir string, url *url.URL, cancel <-chan struct{}) (_ int64, err error) {
+	defer func() { uploader.emitOutcome(err) }()
+
+	logger := uploader.logger
+
+	attemptLogger := logger.WithData(lager.Data{"attempt": 0})
+	attemptLogger.Info("uploading")
+
+	bytesUploaded, err := uploader.attemptStreamingUpload(source, url.String(), cancel, attemptLogger)
+	switch err {
+	case nil:
+		attemptLogger.Info("succeeded-uploading")
+		return bytesUploaded, nil
+	case ErrUploadCancelled:
+		attemptLogger.Info("cancelled-uploading")
+		return 0, err
+	default:
+		attemptLogger.Error("failed-uploading", err)
+	}
+
+	firstAttempt := 1
+	switch {
+	case rejectsStreaming(err):
+		// the destination cannot take the stream itself, so every attempt is
+		// made from disk with a Content-Length
+		logger.Info("falling-back-to-spooled-upload")
+		firstAttempt = 0
+	case uploader.maxAttempts() < 2 || !uploader.isRetryable(err):
+		logger.Error("failed-all-upload-attempts", err)
+		return 0, err
+	}
+
+	spoolFile, bytesToUpload, digests, err := uploader.spool(source, spoolDir, logger)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		spoolFile.Close()
+		os.Remove(spoolFile.Name())
+	}()
+
+	return uploader.uploadFile(spoolFile, bytesToUpload, digests, url, firstAttempt, cancel, logger)
+}
+
+func (uploader *URLUploader) uploadFile(
+	sourceFile *os.File,
+	bytesToUpload int64,
+	digests contentDigests,
+	url *url.URL,
+	firstAttempt int,
+	cancel <-chan struct{},
+	logger lager.Logger,
+) (int64, error) {
+	var err error
+
 UPLOAD_ATTEMPTS:
-	for attempt := 0; attempt < 3; attempt++ {
+	for attempt := firstAttempt; attempt < uploader.maxAttempts(); attempt++ {
 		logger := logger.WithData(lager.Data{"attempt": attempt})
+
+		if attempt > 0 {
+			uploader.emitRetry()
+			err = uploader.waitForRetry(attempt, cancel, logger)
+			if err != nil {
+				break UPLOAD_ATTEMPTS
+			}
+		}
+
 		logger.Info("uploading")
 		err = uploader.attemptUpload(
 			sourceFile,
 			bytesToUpload,
-			contentMD5,
+			digests,
 			url.String(),
 			cancel,
 			logger,
@@ -84,6 +207,9 @@ UPLOAD_ATTEMPTS:
 			break UPLOAD_ATTEMPTS
 		default:
 			logger.Error("failed-uploading", err)
+			if !uploader.isRetryable(err) {
+				break UPLOAD_ATTEMPTS
+			}
 		}
 	}
 
@@ -95,35 +221,82 @@ UPLOAD_ATTEMPTS:
 	return int64(bytesToUpload), nil
 }
 
-func (uploader *URLUploader) prepareFileForUpload(fileLocation string, logger lager.Logger) (*os.File, int64, string, error) {
+func (uploader *URLUploader) waitForRetry(retry int, cancel <-chan struct{}, logger lager.Logger) error {
+	backoff := uploader.backoff(retry)
+	if backoff == 0 {
+		return nil
+	}
+
+	logger.Info("backing-off", lager.Data{"backoff": backoff.String()})
+	timer := uploader.clock.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return nil
+	case <-cancel:
+		logger.Info("cancelled-during-backoff")
+		return ErrUploadCancelled
+	}
+}
+
+// spool copies a fresh stream of the content to disk, so that it can be
+// retried without streaming it out of the source again for every attempt.
+func (uploader *URLUploader) spool(source StreamSource, spoolDir string, logger lager.Logger) (*os.File, int64, contentDigests, error) {
+	logger.Info("spooling")
+
+	reader, err := source()
+	if err != nil {
+		logger.Error("failed-to-open-source", err)
+		return nil, 0, contentDigests{}, err
+	}
+	defer reader.Close()
+
+	spoolFile, err := ioutil.TempFile(spoolDir, "upload-spool")
+	if err != nil {
+		logger.Error("failed-to-create-spool-file", err)
+		return nil, 0, contentDigests{}, err
+	}
+
+	hashes := newContentHashes()
+	size, err := io.Copy(io.MultiWriter(spoolFile, hashes), reader)
+	if err != nil {
+		logger.Error("failed-to-spool", err)
+		spoolFile.Close()
+		os.Remove(spoolFile.Name())
+		return nil, 0, contentDigests{}, err
+	}
+
+	return spoolFile, size, hashes.digests(), nil
+}
+
+func (uploader *URLUploader) prepareFileForUpload(fileLocation string, logger lager.Logger) (*os.File, int64, contentDigests, error) {
 	sourceFile, err := os.Open(fileLocation)
 	if err != nil {
 		logger.Error("failed-open", err)
-		return nil, 0, "", err
+		return nil, 0, contentDigests{}, err
 	}
 
 	fileInfo, err := sourceFile.Stat()
 	if err != nil {
 		logger.Error("failed-stat", err)
-		return nil, 0, "", err
+		return nil, 0, contentDigests{}, err
 	}
 
-	contentHash := md5.New()
-	_, err = io.Copy(contentHash, sourceFile)
+	hashes := newContentHashes()
+	_, err = io.Copy(hashes, sourceFile)
 	if err != nil {
 		logger.Error("failed-copy", err)
-		return nil, 0, "", err
+		return nil, 0, contentDigests{}, err
 	}
 
-	contentMD5 := base64.StdEncoding.EncodeToString(contentHash.Sum(nil))
-
-	return sourceFile, fileInfo.Size(), contentMD5, nil
+	return sourceFile, fileInfo.Size(), hashes.digests(), nil
 }
 
 func (uploader *URLUploader) attemptUpload(
 	sourceFile *os.File,
 	bytesToUpload int64,
-	contentMD5 string,
+	digests contentDigests,
 	url string,
 	cancelCh <-chan struct{},
 	logger lager.Logger,
@@ -142,8 +315,50 @@ func (uploader *URLUploader) attemptUpload(
 
 	request.ContentLength = bytesToUpload
 	request.Header.Set("Content-Type", "application/octet-stream")
-	request.Header.Set("Content-MD5", contentMD5)
+	request.Header.Set("Content-MD5", digests.md5)
+	request.Header.Set("Digest", digests.digest())
 
+	return uploader.do(request, cancelCh, logger)
+}
+
+func (uploader *URLUploader) attemptStreamingUpload(
+	source StreamSource,
+	url string,
+	cancelCh <-chan struct{},
+	logger lager.Logger,
+) (int64, error) {
+	reader, err := source()
+	if err != nil {
+		logger.Error("failed-to-open-source", err)
+		return 0, err
+	}
+	defer reader.Close()
+
+	request, err := http.NewRequest("POST", url, nil)
+	if err != nil {
+		logger.Error("somehow-failed-to-create-request", err)
+		return 0, err
+	}
+
+	body := &digestingReader{
+		reader:  reader,
+		hashes:  newContentHashes(),
+		trailer: http.Header{"Content-MD5": nil, "Digest": nil},
+	}
+	request.Body = ioutil.NopCloser(body)
+	request.ContentLength = -1
+	request.Trailer = body.trailer
+	request.Header.Set("Content-Type", "application/octet-stream")
+
+	err = uploader.do(request, cancelCh, logger)
+	if err != nil {
+		return 0, err
+	}
+
+	return body.bytesRead, nil
+}
+
+func (uploader *URLUploader) do(request *http.Request, cancelCh <-chan struct{}, logger lager.Logger) error {
 	var resp *http.Response
 	reqComplete := make(chan error)
 	go func() {
@@ -160,7 +375,7 @@ func (uploader *URLUploader) attemptUpload(
 		return ErrUploadCancelled
 	case err := <-reqComplete:
 		if err != nil {
-			return err
+			return requestError(err)
 		}
 	}
 
@@ -168,8 +383,62 @@ func (uploader *URLUploader) attemptUpload(
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("Upload failed: Status code %d", resp.StatusCode)
+		return statusCodeError(resp.StatusCode)
 	}
 
 	return nil
 }
+
+type contentDigests struct {
+	md5    string
+	sha256 string
+}
+
+// digest is the value of the RFC 3230 Digest header
+func (d contentDigests) digest() string {
+	return "SHA-256=" + d.sha256
+}
+
+type contentHashes struct {
+	io.Writer
+	md5    hash.Hash
+	sha256 hash.Hash
+}
+
+func newContentHashes() *contentHashes {
+	h := &contentHashes{md5: md5.New(), sha256: sha256.New()}
+	h.Writer = io.MultiWriter(h.md5, h.sha256)
+	return h
+}
+
+func (h *contentHashes) digests() contentDigests {
+	return contentDigests{
+		md5:    base64.StdEncoding.EncodeToString(h.md5.Sum(nil)),
+		sha256: base64.StdEncoding.EncodeToString(h.sha256.Sum(nil)),
+	}
+}
+
+// digestingReader fills in the digest trailers once the content has been
+// read to the end, which is before the transport sends the trailers.
+type digestingReader struct {
+	reader    io.Reader
+	hashes    *contentHashes
+	trailer   http.Header
+	bytesRead int64
+}
+
+func (r *digestingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.hashes.Write(p[:n])
+		r.bytesRead += int64(n)
+	}
+
+	if err == io.EOF {
+		digests := r.hashes.digests()
+		r.trailer.Set("Content-MD5", digests.md5)
+		r.trailer.Set("Digest", digests.digest())
+	}
+
+	return n, err
+}
@@ -1,10 +1,13 @@
 package uploader_test
 
 import (
+	"bytes"
 	"crypto/md5"
+	"crypto/sha256"
 	"crypto/tls"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -14,6 +17,9 @@ import (
 	"sync"
 	"time"
 
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/executor/depot/uploader"
 	"code.cloudfoundry.org/lager/lagertest"
 	"code.cloudfoundry.org/tlsconfig"
@@ -95,6 +101,7 @@ var _ = Describe("Uploader", func() {
 				Expect(request.URL.Path).To(Equal("/somepath"))
 				Expect(request.Header.Get("Content-Type")).To(Equal("application/octet-stream"))
 				Expect(request.Header.Get("Content-MD5")).To(Equal(expectedMD5))
+				Expect(request.Header.Get("Digest")).To(HavePrefix("SHA-256="))
 				Expect(strconv.Atoi(request.Header.Get("Content-Length"))).To(BeNumerically("==", 31))
 				Expect(string(data)).To(Equal("content that we can check later"))
 			})
@@ -217,6 +224,314 @@ var _ = Describe("Uploader", func() {
 				_, err := upldr.Upload(file.Name(), url, nil)
 				Expect(err).NotTo(BeNil())
 			})
+
+			It("classifies the error as a client error", func() {
+				_, err := upldr.Upload(file.Name(), url, nil)
+				Expect(err).To(Equal(&uploader.UploadError{
+					Class:      uploader.FailureClientError,
+					StatusCode: http.StatusNotFound,
+					Err:        fmt.Errorf("Upload failed: Status code 404"),
+				}))
+			})
+		})
+	})
+
+	Describe("retry policy", func() {
+		var (
+			statusCodes  []int
+			fakeClock    *fakeclock.FakeClock
+			metronClient *mfakes.FakeIngressClient
+			opts         []uploader.Option
+		)
+
+		BeforeEach(func() {
+			statusCodes = nil
+			fakeClock = fakeclock.NewFakeClock(time.Now())
+			metronClient = &mfakes.FakeIngressClient{}
+			opts = []uploader.Option{uploader.WithClock(fakeClock), uploader.WithMetronClient(metronClient)}
+
+			testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				serverRequests = append(serverRequests, r)
+				ioutil.ReadAll(r.Body)
+
+				if len(statusCodes) > 0 {
+					w.WriteHeader(statusCodes[0])
+					statusCodes = statusCodes[1:]
+					return
+				}
+				fmt.Fprintln(w, "Hello, client")
+			}))
+
+			url, _ = url.Parse(testServer.URL + "/somepath")
+		})
+
+		JustBeforeEach(func() {
+			upldr = uploader.New(logger, time.Second, nil, opts...)
+		})
+
+		upload := func() error {
+			_, err := upldr.Upload(file.Name(), url, nil)
+			return err
+		}
+
+		It("emits the outcome of the upload", func() {
+			Expect(upload()).To(Succeed())
+			Expect(metronClient.IncrementCounterCallCount()).To(Equal(1))
+			Expect(metronClient.IncrementCounterArgsForCall(0)).To(Equal(uploader.UploadSucceededCounter))
+		})
+
+		Context("when the server is unavailable", func() {
+			BeforeEach(func() {
+				statusCodes = []int{http.StatusServiceUnavailable}
+			})
+
+			It("retries the upload", func() {
+				Expect(upload()).To(Succeed())
+				Expect(serverRequests).To(HaveLen(2))
+				Expect(metronClient.IncrementCounterArgsForCall(0)).To(Equal(uploader.UploadRetriedCounter))
+				Expect(metronClient.IncrementCounterArgsForCall(1)).To(Equal(uploader.UploadSucceededCounter))
+			})
+		})
+
+		Context("when the upload is not authorized", func() {
+			BeforeEach(func() {
+				statusCodes = []int{http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}
+			})
+
+			It("fails without retrying", func() {
+				err := upload()
+				Expect(err).To(BeAssignableToTypeOf(&uploader.UploadError{}))
+				Expect(err.(*uploader.UploadError).Class).To(Equal(uploader.FailureAuth))
+				Expect(serverRequests).To(HaveLen(1))
+			})
+
+			It("emits an auth failure", func() {
+				upload()
+				Expect(metronClient.IncrementCounterCallCount()).To(Equal(1))
+				Expect(metronClient.IncrementCounterArgsForCall(0)).To(Equal(uploader.UploadFailedAuthCounter))
+			})
+		})
+
+		Context("when the retry policy is configured", func() {
+			BeforeEach(func() {
+				statusCodes = []int{http.StatusConflict, http.StatusConflict, http.StatusConflict}
+				opts = append(opts, uploader.WithRetryPolicy(executor.RetryPolicy{
+					MaxAttempts:      2,
+					InitialBackoffMs: 1000,
+				}, []int{http.StatusConflict}))
+			})
+
+			It("retries the configured status codes after backing off", func() {
+				errs := make(chan error)
+				go func() {
+					errs <- upload()
+				}()
+
+				Eventually(fakeClock.WatcherCount).Should(Equal(1))
+				Expect(serverRequests).To(HaveLen(1))
+
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+
+				var err error
+				Eventually(errs).Should(Receive(&err))
+				Expect(err.(*uploader.UploadError).Class).To(Equal(uploader.FailureClientError))
+				Expect(serverRequests).To(HaveLen(2))
+			})
+
+			It("stops backing off when cancelled", func() {
+				cancel := make(chan struct{})
+				errs := make(chan error)
+				go func() {
+					_, err := upldr.Upload(file.Name(), url, cancel)
+					errs <- err
+				}()
+
+				Eventually(fakeClock.WatcherCount).Should(Equal(1))
+				close(cancel)
+
+				Eventually(errs).Should(Receive(Equal(uploader.ErrUploadCancelled)))
+				Expect(metronClient.IncrementCounterArgsForCall(1)).To(Equal(uploader.UploadCancelledCounter))
+			})
+		})
+	})
+
+	Describe("UploadStream", func() {
+		var (
+			content        string
+			sources        int
+			source         uploader.StreamSource
+			spoolDir       string
+			serverTrailers []http.Header
+			failures       int
+			rejectChunked  int
+			expectedDigest string
+		)
+
+		BeforeEach(func() {
+			upldr = uploader.New(logger, 100*time.Millisecond, nil)
+
+			content = "content that we can check later"
+			rawSHA256 := sha256.Sum256([]byte(content))
+			expectedDigest = "SHA-256=" + base64.StdEncoding.EncodeToString(rawSHA256[:])
+
+			sources = 0
+			source = func() (io.ReadCloser, error) {
+				sources++
+				return ioutil.NopCloser(bytes.NewBufferString(content)), nil
+			}
+
+			var err error
+			spoolDir, err = ioutil.TempDir("", "spool")
+			Expect(err).NotTo(HaveOccurred())
+
+			failures = 0
+			rejectChunked = 0
+			serverTrailers = nil
+			testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				serverRequests = append(serverRequests, r)
+
+				data, err := ioutil.ReadAll(r.Body)
+				Expect(err).NotTo(HaveOccurred())
+				serverRequestBody = append(serverRequestBody, string(data))
+				serverTrailers = append(serverTrailers, r.Trailer)
+
+				if rejectChunked != 0 && len(r.TransferEncoding) > 0 {
+					w.WriteHeader(rejectChunked)
+					return
+				}
+
+				if failures > 0 {
+					failures--
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+
+				fmt.Fprintln(w, "Hello, client")
+			}))
+
+			url, _ = url.Parse(testServer.URL + "/somepath")
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(spoolDir)
+		})
+
+		It("streams the content with chunked transfer encoding", func() {
+			numBytes, err := upldr.UploadStream(source, spoolDir, url, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(numBytes).To(Equal(int64(len(content))))
+
+			Expect(serverRequests).To(HaveLen(1))
+			request := serverRequests[0]
+			Expect(request.TransferEncoding).To(Equal([]string{"chunked"}))
+			Expect(request.Header.Get("Content-Type")).To(Equal("application/octet-stream"))
+			Expect(serverRequestBody[0]).To(Equal(content))
+		})
+
+		It("sends the MD5 and SHA-256 digests as trailers", func() {
+			_, err := upldr.UploadStream(source, spoolDir, url, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(serverTrailers[0].Get("Content-MD5")).To(Equal(expectedMD5))
+			Expect(serverTrailers[0].Get("Digest")).To(Equal(expectedDigest))
+		})
+
+		It("does not spool the content", func() {
+			_, err := upldr.UploadStream(source, spoolDir, url, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sources).To(Equal(1))
+		})
+
+		Context("when the streaming attempt fails", func() {
+			BeforeEach(func() {
+				failures = 1
+			})
+
+			It("spools the content and retries from disk", func() {
+				numBytes, err := upldr.UploadStream(source, spoolDir, url, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numBytes).To(Equal(int64(len(content))))
+				Expect(sources).To(Equal(2))
+
+				Expect(serverRequests).To(HaveLen(2))
+				request := serverRequests[1]
+				Expect(request.Header.Get("Content-MD5")).To(Equal(expectedMD5))
+				Expect(request.Header.Get("Digest")).To(Equal(expectedDigest))
+				Expect(strconv.Atoi(request.Header.Get("Content-Length"))).To(BeNumerically("==", len(content)))
+				Expect(serverRequestBody[1]).To(Equal(content))
+			})
+
+			It("removes the spooled file", func() {
+				_, err := upldr.UploadStream(source, spoolDir, url, nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				files, err := ioutil.ReadDir(spoolDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(files).To(BeEmpty())
+			})
+
+			Context("when every attempt fails", func() {
+				BeforeEach(func() {
+					failures = 3
+				})
+
+				It("makes three attempts in total", func() {
+					_, err := upldr.UploadStream(source, spoolDir, url, nil)
+					Expect(err).To(MatchError("Upload failed: Status code 500"))
+					Expect(serverRequests).To(HaveLen(3))
+				})
+			})
+		})
+
+		Context("when the destination rejects chunked requests", func() {
+			BeforeEach(func() {
+				rejectChunked = http.StatusLengthRequired
+			})
+
+			It("uploads the spooled content with a Content-Length and a Content-MD5 header", func() {
+				numBytes, err := upldr.UploadStream(source, spoolDir, url, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(numBytes).To(Equal(int64(len(content))))
+
+				Expect(serverRequests).To(HaveLen(2))
+				request := serverRequests[1]
+				Expect(request.TransferEncoding).To(BeEmpty())
+				Expect(request.Header.Get("Content-MD5")).To(Equal(expectedMD5))
+				Expect(strconv.Atoi(request.Header.Get("Content-Length"))).To(BeNumerically("==", len(content)))
+				Expect(serverRequestBody[1]).To(Equal(content))
+			})
+
+			It("gives the spooled upload all of its attempts", func() {
+				failures = 3
+
+				_, err := upldr.UploadStream(source, spoolDir, url, nil)
+				Expect(err).To(MatchError("Upload failed: Status code 500"))
+				Expect(serverRequests).To(HaveLen(4))
+			})
+
+			Context("with a bad request", func() {
+				BeforeEach(func() {
+					rejectChunked = http.StatusBadRequest
+				})
+
+				It("falls back to the spooled upload", func() {
+					_, err := upldr.UploadStream(source, spoolDir, url, nil)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(serverRequests).To(HaveLen(2))
+				})
+			})
+
+			Context("when only a single attempt is allowed", func() {
+				BeforeEach(func() {
+					upldr = uploader.New(logger, 100*time.Millisecond, nil, uploader.WithRetryPolicy(executor.RetryPolicy{MaxAttempts: 1}, nil))
+				})
+
+				It("still makes the spooled attempt", func() {
+					_, err := upldr.UploadStream(source, spoolDir, url, nil)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(serverRequests).To(HaveLen(2))
+				})
+			})
 		})
 	})
 
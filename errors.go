@@ -1,5 +1,7 @@
 package executor
 
+import "strings"
+
 type Error interface {
 	error
 
@@ -39,4 +41,26 @@ var (
 	ErrFailureToCheckSpace            = registerError("ErrFailureToCheckSpace", "failed to check available space")
 	ErrInvalidSecurityGroup           = registerError("ErrInvalidSecurityGroup", "security group has invalid values")
 	ErrNoProcessToStop                = registerError("ErrNoProcessToStop", "failed to find a process to stop")
+	ErrCellDraining                   = registerError("CellDraining", "cell is draining")
+	ErrTenantQuotaExceeded            = registerError("TenantQuotaExceeded", "tenant quota exceeded")
 )
+
+type StepsInvalidError struct {
+	Problems []string
+}
+
+func NewStepsInvalidError(problems []string) StepsInvalidError {
+	return StepsInvalidError{Problems: problems}
+}
+
+func (err StepsInvalidError) Name() string {
+	return ErrStepsInvalid.Name()
+}
+
+func (err StepsInvalidError) Error() string {
+	return ErrStepsInvalid.Error() + ": " + strings.Join(err.Problems, "; ")
+}
+
+func (err StepsInvalidError) IsRetryable() bool {
+	return false
+}
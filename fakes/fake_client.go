@@ -39,6 +39,18 @@ type FakeClient struct {
 	deleteContainerReturnsOnCall map[int]struct {
 		result1 error
 	}
+	DrainStub        func(lager.Logger, executor.DrainOptions) error
+	drainMutex       sync.RWMutex
+	drainArgsForCall []struct {
+		arg1 lager.Logger
+		arg2 executor.DrainOptions
+	}
+	drainReturns struct {
+		result1 error
+	}
+	drainReturnsOnCall map[int]struct {
+		result1 error
+	}
 	GetBulkMetricsStub        func(lager.Logger) (map[string]executor.Metrics, error)
 	getBulkMetricsMutex       sync.RWMutex
 	getBulkMetricsArgsForCall []struct {
@@ -81,6 +93,20 @@ type FakeClient struct {
 		result1 io.ReadCloser
 		result2 error
 	}
+	GetStepStatusStub        func(lager.Logger, string) (executor.StepStatus, error)
+	getStepStatusMutex       sync.RWMutex
+	getStepStatusArgsForCall []struct {
+		arg1 lager.Logger
+		arg2 string
+	}
+	getStepStatusReturns struct {
+		result1 executor.StepStatus
+		result2 error
+	}
+	getStepStatusReturnsOnCall map[int]struct {
+		result1 executor.StepStatus
+		result2 error
+	}
 	HealthyStub        func(lager.Logger) bool
 	healthyMutex       sync.RWMutex
 	healthyArgsForCall []struct {
@@ -172,6 +198,19 @@ type FakeClient struct {
 		result1 executor.EventSource
 		result2 error
 	}
+	TenantResourcesStub        func(lager.Logger) (map[string]executor.TenantResources, error)
+	tenantResourcesMutex       sync.RWMutex
+	tenantResourcesArgsForCall []struct {
+		arg1 lager.Logger
+	}
+	tenantResourcesReturns struct {
+		result1 map[string]executor.TenantResources
+		result2 error
+	}
+	tenantResourcesReturnsOnCall map[int]struct {
+		result1 map[string]executor.TenantResources
+		result2 error
+	}
 	TotalResourcesStub        func(lager.Logger) (executor.ExecutorResources, error)
 	totalResourcesMutex       sync.RWMutex
 	totalResourcesArgsForCall []struct {
@@ -375,6 +414,68 @@ func (fake *FakeClient) DeleteContainerReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeClient) Drain(arg1 lager.Logger, arg2 executor.DrainOptions) error {
+	fake.drainMutex.Lock()
+	ret, specificReturn := fake.drainReturnsOnCall[len(fake.drainArgsForCall)]
+	fake.drainArgsForCall = append(fake.drainArgsForCall, struct {
+		arg1 lager.Logger
+		arg2 executor.DrainOptions
+	}{arg1, arg2})
+	stub := fake.DrainStub
+	fakeReturns := fake.drainReturns
+	fake.recordInvocation("Drain", []interface{}{arg1, arg2})
+	fake.drainMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeClient) DrainCallCount() int {
+	fake.drainMutex.RLock()
+	defer fake.drainMutex.RUnlock()
+	return len(fake.drainArgsForCall)
+}
+
+func (fake *FakeClient) DrainCalls(stub func(lager.Logger, executor.DrainOptions) error) {
+	fake.drainMutex.Lock()
+	defer fake.drainMutex.Unlock()
+	fake.DrainStub = stub
+}
+
+func (fake *FakeClient) DrainArgsForCall(i int) (lager.Logger, executor.DrainOptions) {
+	fake.drainMutex.RLock()
+	defer fake.drainMutex.RUnlock()
+	argsForCall := fake.drainArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeClient) DrainReturns(result1 error) {
+	fake.drainMutex.Lock()
+	defer fake.drainMutex.Unlock()
+	fake.DrainStub = nil
+	fake.drainReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeClient) DrainReturnsOnCall(i int, result1 error) {
+	fake.drainMutex.Lock()
+	defer fake.drainMutex.Unlock()
+	fake.DrainStub = nil
+	if fake.drainReturnsOnCall == nil {
+		fake.drainReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.drainReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeClient) GetBulkMetrics(arg1 lager.Logger) (map[string]executor.Metrics, error) {
 	fake.getBulkMetricsMutex.Lock()
 	ret, specificReturn := fake.getBulkMetricsReturnsOnCall[len(fake.getBulkMetricsArgsForCall)]
@@ -570,6 +671,71 @@ func (fake *FakeClient) GetFilesReturnsOnCall(i int, result1 io.ReadCloser, resu
 	}{result1, result2}
 }
 
+func (fake *FakeClient) GetStepStatus(arg1 lager.Logger, arg2 string) (executor.StepStatus, error) {
+	fake.getStepStatusMutex.Lock()
+	ret, specificReturn := fake.getStepStatusReturnsOnCall[len(fake.getStepStatusArgsForCall)]
+	fake.getStepStatusArgsForCall = append(fake.getStepStatusArgsForCall, struct {
+		arg1 lager.Logger
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetStepStatusStub
+	fakeReturns := fake.getStepStatusReturns
+	fake.recordInvocation("GetStepStatus", []interface{}{arg1, arg2})
+	fake.getStepStatusMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeClient) GetStepStatusCallCount() int {
+	fake.getStepStatusMutex.RLock()
+	defer fake.getStepStatusMutex.RUnlock()
+	return len(fake.getStepStatusArgsForCall)
+}
+
+func (fake *FakeClient) GetStepStatusCalls(stub func(lager.Logger, string) (executor.StepStatus, error)) {
+	fake.getStepStatusMutex.Lock()
+	defer fake.getStepStatusMutex.Unlock()
+	fake.GetStepStatusStub = stub
+}
+
+func (fake *FakeClient) GetStepStatusArgsForCall(i int) (lager.Logger, string) {
+	fake.getStepStatusMutex.RLock()
+	defer fake.getStepStatusMutex.RUnlock()
+	argsForCall := fake.getStepStatusArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeClient) GetStepStatusReturns(result1 executor.StepStatus, result2 error) {
+	fake.getStepStatusMutex.Lock()
+	defer fake.getStepStatusMutex.Unlock()
+	fake.GetStepStatusStub = nil
+	fake.getStepStatusReturns = struct {
+		result1 executor.StepStatus
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) GetStepStatusReturnsOnCall(i int, result1 executor.StepStatus, result2 error) {
+	fake.getStepStatusMutex.Lock()
+	defer fake.getStepStatusMutex.Unlock()
+	fake.GetStepStatusStub = nil
+	if fake.getStepStatusReturnsOnCall == nil {
+		fake.getStepStatusReturnsOnCall = make(map[int]struct {
+			result1 executor.StepStatus
+			result2 error
+		})
+	}
+	fake.getStepStatusReturnsOnCall[i] = struct {
+		result1 executor.StepStatus
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeClient) Healthy(arg1 lager.Logger) bool {
 	fake.healthyMutex.Lock()
 	ret, specificReturn := fake.healthyReturnsOnCall[len(fake.healthyArgsForCall)]
@@ -1041,6 +1207,70 @@ func (fake *FakeClient) SubscribeToEventsReturnsOnCall(i int, result1 executor.E
 	}{result1, result2}
 }
 
+func (fake *FakeClient) TenantResources(arg1 lager.Logger) (map[string]executor.TenantResources, error) {
+	fake.tenantResourcesMutex.Lock()
+	ret, specificReturn := fake.tenantResourcesReturnsOnCall[len(fake.tenantResourcesArgsForCall)]
+	fake.tenantResourcesArgsForCall = append(fake.tenantResourcesArgsForCall, struct {
+		arg1 lager.Logger
+	}{arg1})
+	stub := fake.TenantResourcesStub
+	fakeReturns := fake.tenantResourcesReturns
+	fake.recordInvocation("TenantResources", []interface{}{arg1})
+	fake.tenantResourcesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeClient) TenantResourcesCallCount() int {
+	fake.tenantResourcesMutex.RLock()
+	defer fake.tenantResourcesMutex.RUnlock()
+	return len(fake.tenantResourcesArgsForCall)
+}
+
+func (fake *FakeClient) TenantResourcesCalls(stub func(lager.Logger) (map[string]executor.TenantResources, error)) {
+	fake.tenantResourcesMutex.Lock()
+	defer fake.tenantResourcesMutex.Unlock()
+	fake.TenantResourcesStub = stub
+}
+
+func (fake *FakeClient) TenantResourcesArgsForCall(i int) lager.Logger {
+	fake.tenantResourcesMutex.RLock()
+	defer fake.tenantResourcesMutex.RUnlock()
+	argsForCall := fake.tenantResourcesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeClient) TenantResourcesReturns(result1 map[string]executor.TenantResources, result2 error) {
+	fake.tenantResourcesMutex.Lock()
+	defer fake.tenantResourcesMutex.Unlock()
+	fake.TenantResourcesStub = nil
+	fake.tenantResourcesReturns = struct {
+		result1 map[string]executor.TenantResources
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeClient) TenantResourcesReturnsOnCall(i int, result1 map[string]executor.TenantResources, result2 error) {
+	fake.tenantResourcesMutex.Lock()
+	defer fake.tenantResourcesMutex.Unlock()
+	fake.TenantResourcesStub = nil
+	if fake.tenantResourcesReturnsOnCall == nil {
+		fake.tenantResourcesReturnsOnCall = make(map[int]struct {
+			result1 map[string]executor.TenantResources
+			result2 error
+		})
+	}
+	fake.tenantResourcesReturnsOnCall[i] = struct {
+		result1 map[string]executor.TenantResources
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeClient) TotalResources(arg1 lager.Logger) (executor.ExecutorResources, error) {
 	fake.totalResourcesMutex.Lock()
 	ret, specificReturn := fake.totalResourcesReturnsOnCall[len(fake.totalResourcesArgsForCall)]
@@ -1240,12 +1470,16 @@ func (fake *FakeClient) Invocations() map[string][][]interface{} {
 	defer fake.cleanupMutex.RUnlock()
 	fake.deleteContainerMutex.RLock()
 	defer fake.deleteContainerMutex.RUnlock()
+	fake.drainMutex.RLock()
+	defer fake.drainMutex.RUnlock()
 	fake.getBulkMetricsMutex.RLock()
 	defer fake.getBulkMetricsMutex.RUnlock()
 	fake.getContainerMutex.RLock()
 	defer fake.getContainerMutex.RUnlock()
 	fake.getFilesMutex.RLock()
 	defer fake.getFilesMutex.RUnlock()
+	fake.getStepStatusMutex.RLock()
+	defer fake.getStepStatusMutex.RUnlock()
 	fake.healthyMutex.RLock()
 	defer fake.healthyMutex.RUnlock()
 	fake.listContainersMutex.RLock()
@@ -1262,6 +1496,8 @@ func (fake *FakeClient) Invocations() map[string][][]interface{} {
 	defer fake.stopContainerMutex.RUnlock()
 	fake.subscribeToEventsMutex.RLock()
 	defer fake.subscribeToEventsMutex.RUnlock()
+	fake.tenantResourcesMutex.RLock()
+	defer fake.tenantResourcesMutex.RUnlock()
 	fake.totalResourcesMutex.RLock()
 	defer fake.totalResourcesMutex.RUnlock()
 	fake.updateContainerMutex.RLock()
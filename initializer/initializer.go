@@ -15,7 +15,6 @@ import (
 	"sync/atomic"
 	"time"
 
-	"code.cloudfoundry.org/archiver/compressor"
 	"code.cloudfoundry.org/cacheddownloader"
 	"code.cloudfoundry.org/clock"
 	loggingclient "code.cloudfoundry.org/diego-logging-client"
@@ -23,9 +22,13 @@ import (
 	"code.cloudfoundry.org/executor"
 	"code.cloudfoundry.org/executor/containermetrics"
 	"code.cloudfoundry.org/executor/depot"
+	"code.cloudfoundry.org/executor/depot/chunkeddownloader"
 	"code.cloudfoundry.org/executor/depot/containerstore"
 	"code.cloudfoundry.org/executor/depot/event"
+	"code.cloudfoundry.org/executor/depot/healthcheckscheduler"
 	"code.cloudfoundry.org/executor/depot/metrics"
+	"code.cloudfoundry.org/executor/depot/signatureverifier"
+	"code.cloudfoundry.org/executor/depot/tracing"
 	"code.cloudfoundry.org/executor/depot/transformer"
 	"code.cloudfoundry.org/executor/depot/uploader"
 	"code.cloudfoundry.org/executor/gardenhealth"
@@ -42,6 +45,7 @@ import (
 	"github.com/google/shlex"
 	"github.com/tedsuo/ifrit"
 	"github.com/tedsuo/ifrit/grouper"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -83,72 +87,88 @@ func (s systemcertsRetriever) SystemCerts() (*x509.CertPool, error) {
 }
 
 type ExecutorConfig struct {
-	AdvertisePreferenceForInstanceAddress bool                  `json:"advertise_preference_for_instance_address"`
-	AutoDiskOverheadMB                    int                   `json:"auto_disk_capacity_overhead_mb"`
-	CachePath                             string                `json:"cache_path,omitempty"`
-	ContainerInodeLimit                   uint64                `json:"container_inode_limit,omitempty"`
-	ContainerMaxCpuShares                 uint64                `json:"container_max_cpu_shares,omitempty"`
-	ContainerMetricsReportInterval        durationjson.Duration `json:"container_metrics_report_interval,omitempty"`
-	ContainerOwnerName                    string                `json:"container_owner_name,omitempty"`
-	ContainerProxyADSServers              []string              `json:"container_proxy_ads_addresses,omitempty"`
-	ContainerProxyConfigPath              string                `json:"container_proxy_config_path,omitempty"`
-	ContainerProxyPath                    string                `json:"container_proxy_path,omitempty"`
-	ContainerProxyRequireClientCerts      bool                  `json:"container_proxy_require_and_verify_client_certs"`
-	ContainerProxyTrustedCACerts          []string              `json:"container_proxy_trusted_ca_certs"`
-	ContainerProxyVerifySubjectAltName    []string              `json:"container_proxy_verify_subject_alt_name"`
-	ContainerReapInterval                 durationjson.Duration `json:"container_reap_interval,omitempty"`
-	CreateWorkPoolSize                    int                   `json:"create_work_pool_size,omitempty"`
-	DeclarativeHealthcheckPath            string                `json:"declarative_healthcheck_path,omitempty"`
-	DeleteWorkPoolSize                    int                   `json:"delete_work_pool_size,omitempty"`
-	DiskMB                                string                `json:"disk_mb,omitempty"`
-	EnableContainerProxy                  bool                  `json:"enable_container_proxy,omitempty"`
-	EnableDeclarativeHealthcheck          bool                  `json:"enable_declarative_healthcheck,omitempty"`
-	EnableUnproxiedPortMappings           bool                  `json:"enable_unproxied_port_mappings"`
-	EnvoyConfigRefreshDelay               durationjson.Duration `json:"envoy_config_refresh_delay"`
-	EnvoyConfigReloadDuration             durationjson.Duration `json:"envoy_config_reload_duration"`
-	EnvoyDrainTimeout                     durationjson.Duration `json:"envoy_drain_timeout,omitempty"`
-	ExportNetworkEnvVars                  bool                  `json:"export_network_env_vars,omitempty"` // DEPRECATED. Kept around for dusts compatability
-	GardenAddr                            string                `json:"garden_addr,omitempty"`
-	GardenHealthcheckCommandRetryPause    durationjson.Duration `json:"garden_healthcheck_command_retry_pause,omitempty"`
-	GardenHealthcheckEmissionInterval     durationjson.Duration `json:"garden_healthcheck_emission_interval,omitempty"`
-	GardenHealthcheckInterval             durationjson.Duration `json:"garden_healthcheck_interval,omitempty"`
-	GardenHealthcheckProcessArgs          []string              `json:"garden_healthcheck_process_args,omitempty"`
-	GardenHealthcheckProcessDir           string                `json:"garden_healthcheck_process_dir"`
-	GardenHealthcheckProcessEnv           []string              `json:"garden_healthcheck_process_env,omitempty"`
-	GardenHealthcheckProcessPath          string                `json:"garden_healthcheck_process_path"`
-	GardenHealthcheckProcessUser          string                `json:"garden_healthcheck_process_user"`
-	GardenHealthcheckTimeout              durationjson.Duration `json:"garden_healthcheck_timeout,omitempty"`
-	GardenNetwork                         string                `json:"garden_network,omitempty"`
-	GracefulShutdownInterval              durationjson.Duration `json:"graceful_shutdown_interval,omitempty"`
-	HealthCheckContainerOwnerName         string                `json:"healthcheck_container_owner_name,omitempty"`
-	HealthCheckWorkPoolSize               int                   `json:"healthcheck_work_pool_size,omitempty"`
-	HealthyMonitoringInterval             durationjson.Duration `json:"healthy_monitoring_interval,omitempty"`
-	InstanceIdentityCAPath                string                `json:"instance_identity_ca_path,omitempty"`
-	InstanceIdentityCredDir               string                `json:"instance_identity_cred_dir,omitempty"`
-	InstanceIdentityPrivateKeyPath        string                `json:"instance_identity_private_key_path,omitempty"`
-	InstanceIdentityValidityPeriod        durationjson.Duration `json:"instance_identity_validity_period,omitempty"`
-	MaxCacheSizeInBytes                   uint64                `json:"max_cache_size_in_bytes,omitempty"`
-	MaxConcurrentDownloads                int                   `json:"max_concurrent_downloads,omitempty"`
-	MaxLogLinesPerSecond                  int                   `json:"max_log_lines_per_second"`
-	MemoryMB                              string                `json:"memory_mb,omitempty"`
-	MetricsWorkPoolSize                   int                   `json:"metrics_work_pool_size,omitempty"`
-	PathToCACertsForDownloads             string                `json:"path_to_ca_certs_for_downloads"`
-	PathToTLSCACert                       string                `json:"path_to_tls_ca_cert"`
-	PathToTLSCert                         string                `json:"path_to_tls_cert"`
-	PathToTLSKey                          string                `json:"path_to_tls_key"`
-	PostSetupHook                         string                `json:"post_setup_hook"`
-	PostSetupUser                         string                `json:"post_setup_user"`
-	ProxyEnableHttp2                      bool                  `json:"proxy_enable_http2"`
-	ProxyMemoryAllocationMB               int                   `json:"proxy_memory_allocation_mb,omitempty"`
-	ReadWorkPoolSize                      int                   `json:"read_work_pool_size,omitempty"`
-	ReservedExpirationTime                durationjson.Duration `json:"reserved_expiration_time,omitempty"`
-	SetCPUWeight                          bool                  `json:"set_cpu_weight,omitempty"`
-	SkipCertVerify                        bool                  `json:"skip_cert_verify,omitempty"`
-	TempDir                               string                `json:"temp_dir,omitempty"`
-	TrustedSystemCertificatesPath         string                `json:"trusted_system_certificates_path"`
-	UnhealthyMonitoringInterval           durationjson.Duration `json:"unhealthy_monitoring_interval,omitempty"`
-	UseSchedulableDiskSize                bool                  `json:"use_schedulable_disk_size,omitempty"`
-	VolmanDriverPaths                     string                `json:"volman_driver_paths"`
+	AdvertisePreferenceForInstanceAddress bool                                  `json:"advertise_preference_for_instance_address"`
+	ArtifactSigningKeys                   []string                              `json:"artifact_signing_keys,omitempty"`
+	AutoDiskOverheadMB                    int                                   `json:"auto_disk_capacity_overhead_mb"`
+	CachePath                             string                                `json:"cache_path,omitempty"`
+	ContainerInodeLimit                   uint64                                `json:"container_inode_limit,omitempty"`
+	ContainerMaxCpuShares                 uint64                                `json:"container_max_cpu_shares,omitempty"`
+	ContainerMetricsReportInterval        durationjson.Duration                 `json:"container_metrics_report_interval,omitempty"`
+	ContainerOwnerName                    string                                `json:"container_owner_name,omitempty"`
+	ContainerProxyADSServers              []string                              `json:"container_proxy_ads_addresses,omitempty"`
+	ContainerProxyConfigPath              string                                `json:"container_proxy_config_path,omitempty"`
+	ContainerProxyPath                    string                                `json:"container_proxy_path,omitempty"`
+	ContainerProxyRequireClientCerts      bool                                  `json:"container_proxy_require_and_verify_client_certs"`
+	ContainerProxyTrustedCACerts          []string                              `json:"container_proxy_trusted_ca_certs"`
+	ContainerProxyVerifySubjectAltName    []string                              `json:"container_proxy_verify_subject_alt_name"`
+	ContainerReapInterval                 durationjson.Duration                 `json:"container_reap_interval,omitempty"`
+	CreateWorkPoolSize                    int                                   `json:"create_work_pool_size,omitempty"`
+	DeclarativeHealthcheckPath            string                                `json:"declarative_healthcheck_path,omitempty"`
+	DefaultTenantQuota                    executor.ExecutorResources            `json:"default_tenant_quota,omitempty"`
+	DeleteWorkPoolSize                    int                                   `json:"delete_work_pool_size,omitempty"`
+	DiskMB                                string                                `json:"disk_mb,omitempty"`
+	DownloadChunkSizeBytes                int64                                 `json:"download_chunk_size_bytes,omitempty"`
+	DownloadParallelism                   int                                   `json:"download_parallelism,omitempty"`
+	EnableChunkedDownloads                bool                                  `json:"enable_chunked_downloads,omitempty"`
+	EnableContainerProxy                  bool                                  `json:"enable_container_proxy,omitempty"`
+	EnableDeclarativeHealthcheck          bool                                  `json:"enable_declarative_healthcheck,omitempty"`
+	EnableHealthcheckScheduler            bool                                  `json:"enable_healthcheck_scheduler,omitempty"` // Jitters health check intervals and stretches them, up to 4x, while the health check work pool is saturated
+	EnableInProcessHealthchecks           bool                                  `json:"enable_in_process_healthchecks,omitempty"`
+	EnableUnproxiedPortMappings           bool                                  `json:"enable_unproxied_port_mappings"`
+	EnvoyConfigRefreshDelay               durationjson.Duration                 `json:"envoy_config_refresh_delay"`
+	EnvoyConfigReloadDuration             durationjson.Duration                 `json:"envoy_config_reload_duration"`
+	EnvoyDrainTimeout                     durationjson.Duration                 `json:"envoy_drain_timeout,omitempty"`
+	ExportNetworkEnvVars                  bool                                  `json:"export_network_env_vars,omitempty"` // DEPRECATED. Kept around for dusts compatability
+	GardenAddr                            string                                `json:"garden_addr,omitempty"`
+	GardenHealthcheckCommandRetryPause    durationjson.Duration                 `json:"garden_healthcheck_command_retry_pause,omitempty"`
+	GardenHealthcheckEmissionInterval     durationjson.Duration                 `json:"garden_healthcheck_emission_interval,omitempty"`
+	GardenHealthcheckInterval             durationjson.Duration                 `json:"garden_healthcheck_interval,omitempty"`
+	GardenHealthcheckProcessArgs          []string                              `json:"garden_healthcheck_process_args,omitempty"`
+	GardenHealthcheckProcessDir           string                                `json:"garden_healthcheck_process_dir"`
+	GardenHealthcheckProcessEnv           []string                              `json:"garden_healthcheck_process_env,omitempty"`
+	GardenHealthcheckProcessPath          string                                `json:"garden_healthcheck_process_path"`
+	GardenHealthcheckProcessUser          string                                `json:"garden_healthcheck_process_user"`
+	GardenHealthcheckTimeout              durationjson.Duration                 `json:"garden_healthcheck_timeout,omitempty"`
+	GardenNetwork                         string                                `json:"garden_network,omitempty"`
+	GracefulShutdownInterval              durationjson.Duration                 `json:"graceful_shutdown_interval,omitempty"`
+	HealthCheckContainerOwnerName         string                                `json:"healthcheck_container_owner_name,omitempty"`
+	HealthCheckIntervalJitter             float64                               `json:"healthcheck_interval_jitter,omitempty"`
+	HealthCheckWorkPoolSize               int                                   `json:"healthcheck_work_pool_size,omitempty"`
+	HealthyMonitoringInterval             durationjson.Duration                 `json:"healthy_monitoring_interval,omitempty"`
+	InstanceIdentityCAPath                string                                `json:"instance_identity_ca_path,omitempty"`
+	InstanceIdentityCredDir               string                                `json:"instance_identity_cred_dir,omitempty"`
+	InstanceIdentityPrivateKeyPath        string                                `json:"instance_identity_private_key_path,omitempty"`
+	InstanceIdentityValidityPeriod        durationjson.Duration                 `json:"instance_identity_validity_period,omitempty"`
+	MaxCacheSizeInBytes                   uint64                                `json:"max_cache_size_in_bytes,omitempty"`
+	MaxConcurrentDownloads                int                                   `json:"max_concurrent_downloads,omitempty"`
+	MaxConcurrentParallelSteps            int                                   `json:"max_concurrent_parallel_steps,omitempty"`
+	MaxGracefulShutdownInterval           durationjson.Duration                 `json:"max_graceful_shutdown_interval,omitempty"`
+	MaxLogLinesPerSecond                  int                                   `json:"max_log_lines_per_second"`
+	MaxResourceLimits                     executor.ResourceLimits               `json:"max_resource_limits,omitempty"`
+	MemoryMB                              string                                `json:"memory_mb,omitempty"`
+	MetricsWorkPoolSize                   int                                   `json:"metrics_work_pool_size,omitempty"`
+	PathToCACertsForDownloads             string                                `json:"path_to_ca_certs_for_downloads"`
+	PathToTLSCACert                       string                                `json:"path_to_tls_ca_cert"`
+	PathToTLSCert                         string                                `json:"path_to_tls_cert"`
+	PathToTLSKey                          string                                `json:"path_to_tls_key"`
+	PostSetupHook                         string                                `json:"post_setup_hook"`
+	PostSetupUser                         string                                `json:"post_setup_user"`
+	ProxyEnableHttp2                      bool                                  `json:"proxy_enable_http2"`
+	ProxyMemoryAllocationMB               int                                   `json:"proxy_memory_allocation_mb,omitempty"`
+	ReadWorkPoolSize                      int                                   `json:"read_work_pool_size,omitempty"`
+	ReservedExpirationTime                durationjson.Duration                 `json:"reserved_expiration_time,omitempty"`
+	SetCPUWeight                          bool                                  `json:"set_cpu_weight,omitempty"`
+	SkipCertVerify                        bool                                  `json:"skip_cert_verify,omitempty"`
+	TempDir                               string                                `json:"temp_dir,omitempty"`
+	TenantQuotaTag                        string                                `json:"tenant_quota_tag,omitempty"`
+	TenantQuotas                          map[string]executor.ExecutorResources `json:"tenant_quotas,omitempty"`
+	TracingExporter                       string                                `json:"tracing_exporter,omitempty"`
+	TrustedSystemCertificatesPath         string                                `json:"trusted_system_certificates_path"`
+	UnhealthyMonitoringInterval           durationjson.Duration                 `json:"unhealthy_monitoring_interval,omitempty"`
+	UploadRetryPolicy                     executor.RetryPolicy                  `json:"upload_retry_policy,omitempty"`
+	UploadRetryableStatusCodes            []int                                 `json:"upload_retryable_status_codes,omitempty"`
+	UseSchedulableDiskSize                bool                                  `json:"use_schedulable_disk_size,omitempty"`
+	VolmanDriverPaths                     string                                `json:"volman_driver_paths"`
 }
 
 var (
@@ -209,6 +229,22 @@ func Initialize(logger lager.Logger, config ExecutorConfig, cellID, zone string,
 	if err != nil {
 		return nil, nil, grouper.Members{}, err
 	}
+	healthCheckScheduler := healthcheckscheduler.New(
+		logger,
+		healthCheckWorkPool,
+		config.HealthCheckWorkPoolSize,
+		config.HealthCheckIntervalJitter,
+		metronClient,
+		metricsReportInterval,
+		clock,
+	)
+	probeLatencyReporter := &metrics.ProbeLatencyReporter{
+		Interval:     metricsReportInterval,
+		Clock:        clock,
+		Logger:       logger,
+		MetronClient: metronClient,
+		Tags:         map[string]string{"zone": zone},
+	}
 
 	certsRetriever := systemcertsRetriever{}
 	assetTLSConfig, err := TLSConfigFromConfig(logger, certsRetriever, config)
@@ -217,7 +253,14 @@ func Initialize(logger lager.Logger, config ExecutorConfig, cellID, zone string,
 	}
 
 	downloader := cacheddownloader.NewDownloader(10*time.Minute, int(math.MaxInt8), assetTLSConfig)
-	uploader := uploader.New(logger, 10*time.Minute, assetTLSConfig)
+	uploader := uploader.New(
+		logger,
+		10*time.Minute,
+		assetTLSConfig,
+		uploader.WithRetryPolicy(config.UploadRetryPolicy, config.UploadRetryableStatusCodes),
+		uploader.WithMetronClient(metronClient),
+		uploader.WithClock(clock),
+	)
 
 	cache := cacheddownloader.NewCache(config.CachePath, int64(config.MaxCacheSizeInBytes))
 	cachedDownloader := cacheddownloader.New(
@@ -233,23 +276,64 @@ func Initialize(logger lager.Logger, config ExecutorConfig, cellID, zone string,
 
 	downloadRateLimiter := make(chan struct{}, uint(config.MaxConcurrentDownloads))
 
+	tracerProvider, err := tracing.NewTracerProvider(config.TracingExporter, os.Stdout)
+	if err != nil {
+		logger.Error("failed-to-create-tracer-provider", err)
+		return nil, nil, grouper.Members{}, err
+	}
+	tracer := tracing.NewTracer(tracerProvider)
+
+	workDir := setupWorkDir(logger, config.TempDir)
+
+	var chunkedDownloader chunkeddownloader.ChunkedDownloader
+	if config.EnableChunkedDownloads {
+		chunkedDownloader = chunkeddownloader.NewWithCache(
+			workDir,
+			config.DownloadChunkSizeBytes,
+			config.DownloadParallelism,
+			10*time.Minute,
+			assetTLSConfig,
+			cache,
+		)
+	}
+
+	var signatureVerifier signatureverifier.Verifier
+	if len(config.ArtifactSigningKeys) > 0 {
+		keys, err := signatureverifier.ParsePublicKeys(config.ArtifactSigningKeys)
+		if err != nil {
+			logger.Error("failed-to-parse-artifact-signing-keys", err)
+			return nil, nil, grouper.Members{}, err
+		}
+		signatureVerifier = signatureverifier.New(keys, 10*time.Minute, assetTLSConfig)
+	}
+
 	transformer := initializeTransformer(
 		cachedDownloader,
-		setupWorkDir(logger, config.TempDir),
+		chunkedDownloader,
+		signatureVerifier,
+		workDir,
 		downloadRateLimiter,
 		maxConcurrentUploads,
 		uploader,
 		time.Duration(config.HealthyMonitoringInterval),
 		time.Duration(config.UnhealthyMonitoringInterval),
 		time.Duration(config.GracefulShutdownInterval),
+		time.Duration(config.MaxGracefulShutdownInterval),
 		healthCheckWorkPool,
+		healthCheckScheduler,
+		probeLatencyReporter,
 		clock,
 		postSetupHook,
 		config.PostSetupUser,
 		config.EnableDeclarativeHealthcheck,
+		config.EnableInProcessHealthchecks,
+		config.EnableHealthcheckScheduler,
 		gardenHealthcheckRootFS,
 		config.EnableContainerProxy,
 		time.Duration(config.EnvoyDrainTimeout),
+		tracer,
+		config.MaxResourceLimits,
+		config.MaxConcurrentParallelSteps,
 	)
 
 	hub := event.NewHub()
@@ -272,6 +356,10 @@ func Initialize(logger lager.Logger, config ExecutorConfig, cellID, zone string,
 		ReapInterval:           time.Duration(config.ContainerReapInterval),
 		MaxLogLinesPerSecond:   config.MaxLogLinesPerSecond,
 		MetricReportInterval:   time.Duration(config.ContainerMetricsReportInterval),
+		TenantQuotaTag:         config.TenantQuotaTag,
+		TenantQuotas:           config.TenantQuotas,
+		DefaultTenantQuota:     config.DefaultTenantQuota,
+		Tracer:                 tracer,
 	}
 
 	driverConfig := vollocal.NewDriverConfig()
@@ -310,7 +398,7 @@ func Initialize(logger lager.Logger, config ExecutorConfig, cellID, zone string,
 		containerConfig,
 		&totalCapacity,
 		gardenClient,
-		containerstore.NewDependencyManager(cachedDownloader, downloadRateLimiter),
+		containerstore.NewDependencyManager(cachedDownloader, signatureVerifier, downloadRateLimiter),
 		volmanClient,
 		credManager,
 		clock,
@@ -337,6 +425,7 @@ func Initialize(logger lager.Logger, config ExecutorConfig, cellID, zone string,
 		deletionWorkPool,
 		readWorkPool,
 		metricsWorkPool,
+		clock,
 	)
 
 	healthcheckSpec := garden.ProcessSpec{
@@ -385,6 +474,8 @@ func Initialize(logger lager.Logger, config ExecutorConfig, cellID, zone string,
 				MetronClient:   metronClient,
 				Tags:           map[string]string{"zone": zone},
 			}},
+			{"healthcheck-scheduler", healthCheckScheduler},
+			{"probe-latency-reporter", probeLatencyReporter},
 			{"hub-closer", closeHub(logger, hub)},
 			{"container-metrics-reporter", reportersRunner},
 			{"garden_health_checker", gardenhealth.NewRunner(
@@ -529,6 +620,8 @@ func setupWorkDir(logger lager.Logger, tempDir string) string {
 
 func initializeTransformer(
 	cache cacheddownloader.CachedDownloader,
+	chunkedDownloader chunkeddownloader.ChunkedDownloader,
+	signatureVerifier signatureverifier.Verifier,
 	workDir string,
 	downloadRateLimiter chan struct{},
 	maxConcurrentUploads uint,
@@ -536,17 +629,24 @@ func initializeTransformer(
 	healthyMonitoringInterval time.Duration,
 	unhealthyMonitoringInterval time.Duration,
 	gracefulShutdownInterval time.Duration,
+	maxGracefulShutdownInterval time.Duration,
 	healthCheckWorkPool *workpool.WorkPool,
+	healthCheckScheduler *healthcheckscheduler.Scheduler,
+	probeLatencyReporter *metrics.ProbeLatencyReporter,
 	clock clock.Clock,
 	postSetupHook []string,
 	postSetupUser string,
 	useDeclarativeHealthCheck bool,
+	useInProcessHealthChecks bool,
+	useHealthCheckScheduler bool,
 	declarativeHealthcheckRootFS string,
 	enableContainerProxy bool,
 	drainWait time.Duration,
+	tracer trace.Tracer,
+	maxResourceLimits executor.ResourceLimits,
+	maxConcurrentParallelSteps int,
 ) transformer.Transformer {
 	var options []transformer.Option
-	compressor := compressor.NewTgz()
 
 	options = append(options, transformer.WithSidecarRootfs(declarativeHealthcheckRootFS))
 
@@ -554,17 +654,37 @@ func initializeTransformer(
 		options = append(options, transformer.WithDeclarativeHealthchecks())
 	}
 
+	if useInProcessHealthChecks {
+		options = append(options, transformer.WithInProcessHealthchecks())
+	}
+
+	if useHealthCheckScheduler {
+		options = append(options, transformer.WithHealthCheckScheduler(healthCheckScheduler))
+	}
+
 	if enableContainerProxy {
 		options = append(options, transformer.WithContainerProxy(drainWait))
 	}
 
+	options = append(options, transformer.WithProbeLatencyReporter(probeLatencyReporter))
 	options = append(options, transformer.WithPostSetupHook(postSetupUser, postSetupHook))
+	options = append(options, transformer.WithTracer(tracer))
+	options = append(options, transformer.WithMaxResourceLimits(maxResourceLimits))
+	options = append(options, transformer.WithMaxParallelConcurrency(maxConcurrentParallelSteps))
+	options = append(options, transformer.WithMaxGracefulShutdownInterval(maxGracefulShutdownInterval))
+
+	if chunkedDownloader != nil {
+		options = append(options, transformer.WithChunkedDownloader(chunkedDownloader))
+	}
+
+	if signatureVerifier != nil {
+		options = append(options, transformer.WithSignatureVerifier(signatureVerifier))
+	}
 
 	return transformer.NewTransformer(
 		clock,
 		cache,
 		uploader,
-		compressor,
 		downloadRateLimiter,
 		make(chan struct{}, maxConcurrentUploads),
 		workDir,
@@ -721,6 +841,11 @@ func (config *ExecutorConfig) Validate(logger lager.Logger) bool {
 		valid = false
 	}
 
+	if config.MaxConcurrentParallelSteps < 0 {
+		logger.Error("max-concurrent-parallel-steps-invalid", nil)
+		valid = false
+	}
+
 	if config.PostSetupHook != "" && config.PostSetupUser == "" {
 		logger.Error("post-setup-hook-requires-a-user", nil)
 		valid = false
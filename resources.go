@@ -3,6 +3,7 @@ package executor
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"time"
 
@@ -46,6 +47,8 @@ type Container struct {
 	MemoryLimit                           uint64             `json:"memory_limit"`
 	DiskLimit                             uint64             `json:"disk_limit"`
 	AdvertisePreferenceForInstanceAddress bool               `json:"advertise_preference_for_instance_address"`
+	RestartCount                          int                `json:"restart_count"`
+	Health                                *ContainerHealth   `json:"health,omitempty"`
 }
 
 func NewContainerFromResource(guid string, resource *Resource, tags Tags) Container {
@@ -164,10 +167,181 @@ type CertificateProperties struct {
 	OrganizationalUnit []string `json:"organizational_unit"`
 }
 
+// InitContainer runs to completion in its own image before the setup of the
+// container. It sees the bind mounts of the container, including its volume
+// mounts, so it can leave files behind for the app.
+type InitContainer struct {
+	Name          string            `json:"name"`
+	Image         string            `json:"image"`
+	ImageUsername string            `json:"image_username,omitempty"`
+	ImagePassword string            `json:"image_password,omitempty"`
+	Action        *models.RunAction `json:"run"`
+}
+
+// A sidecar is critical unless Critical is set to false. The instance fails
+// when a critical sidecar fails and cannot be restarted, while a non-critical
+// sidecar is given up on without affecting the rest of the instance.
 type Sidecar struct {
-	Action   *models.Action `json:"run"`
-	DiskMB   int32          `json:"disk_mb"`
-	MemoryMB int32          `json:"memory_mb"`
+	Action        *models.Action `json:"run"`
+	DiskMB        int32          `json:"disk_mb"`
+	MemoryMB      int32          `json:"memory_mb"`
+	RestartPolicy *RestartPolicy `json:"restart_policy,omitempty"`
+	Critical      *bool          `json:"critical,omitempty"`
+}
+
+func (s Sidecar) IsCritical() bool {
+	return s.Critical == nil || *s.Critical
+}
+
+// SidecarResource is the memory and disk the sidecars use on top of the
+// resources reserved for the container itself
+func (runInfo *RunInfo) SidecarResource() Resource {
+	var resource Resource
+	for _, sidecar := range runInfo.Sidecars {
+		resource.MemoryMB += int(sidecar.MemoryMB)
+		resource.DiskMB += int(sidecar.DiskMB)
+	}
+	return resource
+}
+
+type RestartPolicyType string
+
+const (
+	RestartPolicyNever     RestartPolicyType = "never"
+	RestartPolicyOnFailure RestartPolicyType = "on-failure"
+	RestartPolicyAlways    RestartPolicyType = "always"
+)
+
+// MaxRestarts <= 0 means the number of restarts is unlimited
+type RestartPolicy struct {
+	Policy           RestartPolicyType `json:"policy"`
+	MaxRestarts      int               `json:"max_restarts,omitempty"`
+	InitialBackoffMs uint              `json:"initial_backoff_ms,omitempty"`
+	MaxBackoffMs     uint              `json:"max_backoff_ms,omitempty"`
+}
+
+// Jitter is the fraction, between 0 and 1, by which every backoff is randomly
+// shortened
+type RetryPolicy struct {
+	MaxAttempts      int     `json:"max_attempts"`
+	InitialBackoffMs uint    `json:"initial_backoff_ms,omitempty"`
+	MaxBackoffMs     uint    `json:"max_backoff_ms,omitempty"`
+	Jitter           float64 `json:"jitter,omitempty"`
+}
+
+type StopSignal string
+
+const (
+	StopSignalTerminate StopSignal = "TERM"
+	StopSignalKill      StopSignal = "KILL"
+)
+
+// StopPolicy configures how the processes of a container are stopped. The
+// pre-stop hook runs inside the container before any process is signalled,
+// and the graceful shutdown interval is capped by the cell.
+type StopPolicy struct {
+	Signal                     StopSignal   `json:"signal,omitempty"`
+	PreStop                    *PreStopHook `json:"pre_stop,omitempty"`
+	GracefulShutdownIntervalMs uint         `json:"graceful_shutdown_interval_ms,omitempty"`
+}
+
+type PreStopHook struct {
+	Path      string   `json:"path"`
+	Args      []string `json:"args,omitempty"`
+	Dir       string   `json:"dir,omitempty"`
+	User      string   `json:"user,omitempty"`
+	TimeoutMs uint     `json:"timeout_ms,omitempty"`
+}
+
+// HealthCheck is a declarative health check that complements the checks in
+// the CheckDefinition. Exactly one of Exec and GRPC is set.
+type HealthCheck struct {
+	Exec *ExecCheck `json:"exec,omitempty"`
+	GRPC *GRPCCheck `json:"grpc,omitempty"`
+}
+
+// HealthCheckThresholds let health checks tolerate brief failures. A
+// container becomes healthy after SuccessThreshold consecutive passing probes
+// and unhealthy after FailureThreshold consecutive failing ones. Unset
+// thresholds default to 1.
+type HealthCheckThresholds struct {
+	SuccessThreshold uint `json:"success_threshold,omitempty"`
+	FailureThreshold uint `json:"failure_threshold,omitempty"`
+}
+
+// ExecCheck runs a command inside the container and passes when it exits 0.
+type ExecCheck struct {
+	Path      string   `json:"path"`
+	Args      []string `json:"args,omitempty"`
+	Dir       string   `json:"dir,omitempty"`
+	User      string   `json:"user,omitempty"`
+	TimeoutMs uint     `json:"timeout_ms,omitempty"`
+	LogSource string   `json:"log_source,omitempty"`
+}
+
+// GRPCCheck calls the grpc.health.v1 Health service on the container port and
+// passes when the service reports SERVING. An empty Service checks the
+// overall health of the server.
+type GRPCCheck struct {
+	Port      uint32 `json:"port"`
+	Service   string `json:"service,omitempty"`
+	TimeoutMs uint   `json:"timeout_ms,omitempty"`
+	LogSource string `json:"log_source,omitempty"`
+}
+
+// ResourceLimits are the rlimits applied to each process run from the
+// container's actions. Unset limits are not enforced.
+type ResourceLimits struct {
+	As         *uint64 `json:"as,omitempty"`
+	Core       *uint64 `json:"core,omitempty"`
+	Cpu        *uint64 `json:"cpu,omitempty"`
+	Data       *uint64 `json:"data,omitempty"`
+	Fsize      *uint64 `json:"fsize,omitempty"`
+	Memlock    *uint64 `json:"memlock,omitempty"`
+	Msgqueue   *uint64 `json:"msgqueue,omitempty"`
+	Nice       *uint64 `json:"nice,omitempty"`
+	Nofile     *uint64 `json:"nofile,omitempty"`
+	Nproc      *uint64 `json:"nproc,omitempty"`
+	Rtprio     *uint64 `json:"rtprio,omitempty"`
+	Sigpending *uint64 `json:"sigpending,omitempty"`
+	Stack      *uint64 `json:"stack,omitempty"`
+}
+
+func (l *ResourceLimits) named() map[string]*uint64 {
+	return map[string]*uint64{
+		"as":         l.As,
+		"core":       l.Core,
+		"cpu":        l.Cpu,
+		"data":       l.Data,
+		"fsize":      l.Fsize,
+		"memlock":    l.Memlock,
+		"msgqueue":   l.Msgqueue,
+		"nice":       l.Nice,
+		"nofile":     l.Nofile,
+		"nproc":      l.Nproc,
+		"rtprio":     l.Rtprio,
+		"sigpending": l.Sigpending,
+		"stack":      l.Stack,
+	}
+}
+
+// Exceeding returns the sorted names of the limits that are set above the
+// corresponding limit in max. Limits unset in max are unbounded.
+func (l *ResourceLimits) Exceeding(max ResourceLimits) []string {
+	if l == nil {
+		return nil
+	}
+
+	maxima := max.named()
+	var exceeding []string
+	for name, value := range l.named() {
+		if value != nil && maxima[name] != nil && *value > *maxima[name] {
+			exceeding = append(exceeding, name)
+		}
+	}
+	sort.Strings(exceeding)
+
+	return exceeding
 }
 
 type RunInfo struct {
@@ -184,6 +358,8 @@ type RunInfo struct {
 	Action                        *models.Action                `json:"run"`
 	Monitor                       *models.Action                `json:"monitor"`
 	CheckDefinition               *models.CheckDefinition       `json:"check_definition"`
+	HealthChecks                  []HealthCheck                 `json:"health_checks,omitempty"`
+	HealthCheckThresholds         *HealthCheckThresholds        `json:"health_check_thresholds,omitempty"`
 	EgressRules                   []*models.SecurityGroupRule   `json:"egress_rules,omitempty"`
 	Env                           []EnvironmentVariable         `json:"env,omitempty"`
 	TrustedSystemCertificatesPath string                        `json:"trusted_system_certificates_path,omitempty"`
@@ -194,7 +370,74 @@ type RunInfo struct {
 	ImagePassword                 string                        `json:"image_password"`
 	EnableContainerProxy          bool                          `json:"enable_container_proxy"`
 	Sidecars                      []Sidecar                     `json:"sidecars"`
+	InitContainers                []InitContainer               `json:"init_containers,omitempty"`
 	LogRateLimitBytesPerSecond    int64                         `json:"log_rate_limit_bytes_per_second"`
+	RestartPolicy                 *RestartPolicy                `json:"restart_policy,omitempty"`
+	SetupRetryPolicy              *RetryPolicy                  `json:"setup_retry_policy,omitempty"`
+	ResourceLimits                *ResourceLimits               `json:"resource_limits,omitempty"`
+	StopPolicy                    *StopPolicy                   `json:"stop_policy,omitempty"`
+}
+
+type StepState string
+
+const (
+	StepPending   StepState = "pending"
+	StepRunning   StepState = "running"
+	StepSucceeded StepState = "succeeded"
+	StepFailed    StepState = "failed"
+	StepCancelled StepState = "cancelled"
+)
+
+type StepStatus struct {
+	Name      string       `json:"name"`
+	State     StepState    `json:"state"`
+	StartTime int64        `json:"start_time,omitempty"`
+	EndTime   int64        `json:"end_time,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Children  []StepStatus `json:"children,omitempty"`
+}
+
+type CheckStatus string
+
+const (
+	CheckStatusUnknown CheckStatus = "unknown"
+	CheckStatusPassing CheckStatus = "passing"
+	CheckStatusFailing CheckStatus = "failing"
+)
+
+const MaxProbeOutputLength = 1024
+
+// ContainerHealth is the state of the health checks of a container. Times
+// are in nanoseconds since the epoch.
+type ContainerHealth struct {
+	Readiness           CheckStatus `json:"readiness"`
+	Liveness            CheckStatus `json:"liveness"`
+	LastProbeTime       int64       `json:"last_probe_time,omitempty"`
+	LastProbeOutput     string      `json:"last_probe_output,omitempty"`
+	ConsecutiveFailures int         `json:"consecutive_failures"`
+	HealthySince        int64       `json:"healthy_since,omitempty"`
+}
+
+// RecordProbe updates the health with the result of a single probe.
+func (h *ContainerHealth) RecordProbe(now time.Time, err error) {
+	h.LastProbeTime = now.UnixNano()
+	if err == nil {
+		h.ConsecutiveFailures = 0
+		h.LastProbeOutput = ""
+		return
+	}
+
+	h.ConsecutiveFailures++
+	h.LastProbeOutput = truncateString(err.Error(), MaxProbeOutputLength)
+}
+
+// TimeSinceHealthy is how long the container has been healthy, or zero if it
+// is not.
+func (h *ContainerHealth) TimeSinceHealthy(now time.Time) time.Duration {
+	if h.HealthySince == 0 {
+		return 0
+	}
+	return now.Sub(time.Unix(0, h.HealthySince))
 }
 
 type BindMountMode uint8
@@ -284,6 +527,10 @@ type ContainerRunResult struct {
 	FailureReason string `json:"failure_reason"`
 	Retryable     bool
 
+	// OutputExcerpt holds the last lines of output of the process whose
+	// failure completed the container
+	OutputExcerpt string `json:"output_excerpt,omitempty"`
+
 	Stopped bool `json:"stopped"`
 }
 
@@ -293,6 +540,12 @@ type ExecutorResources struct {
 	Containers int `json:"containers"`
 }
 
+// A zero value in a quota means that resource is not capped for the tenant
+type TenantResources struct {
+	Quota ExecutorResources `json:"quota"`
+	Used  ExecutorResources `json:"used"`
+}
+
 func NewExecutorResources(memoryMB, diskMB, containers int) ExecutorResources {
 	return ExecutorResources{
 		MemoryMB:   memoryMB,
@@ -305,6 +558,19 @@ func (e ExecutorResources) Copy() ExecutorResources {
 	return e
 }
 
+func (r *ExecutorResources) WithinQuota(quota *ExecutorResources, res *Resource) bool {
+	if quota.MemoryMB > 0 && r.MemoryMB+res.MemoryMB > quota.MemoryMB {
+		return false
+	}
+	if quota.DiskMB > 0 && r.DiskMB+res.DiskMB > quota.DiskMB {
+		return false
+	}
+	if quota.Containers > 0 && r.Containers+1 > quota.Containers {
+		return false
+	}
+	return true
+}
+
 func (r *ExecutorResources) canSubtract(res *Resource) bool {
 	return r.MemoryMB >= res.MemoryMB && r.DiskMB >= res.DiskMB && r.Containers > 0
 }
@@ -325,6 +591,22 @@ func (r *ExecutorResources) Add(res *Resource) {
 	r.Containers += 1
 }
 
+// Grow and Shrink change the memory and disk of an existing allocation without
+// affecting the container count
+func (r *ExecutorResources) Grow(res *Resource) {
+	r.MemoryMB += res.MemoryMB
+	r.DiskMB += res.DiskMB
+}
+
+func (r *ExecutorResources) Shrink(res *Resource) bool {
+	if r.MemoryMB < res.MemoryMB || r.DiskMB < res.DiskMB {
+		return false
+	}
+	r.MemoryMB -= res.MemoryMB
+	r.DiskMB -= res.DiskMB
+	return true
+}
+
 type Tags map[string]string
 
 func (t Tags) Copy() Tags {
@@ -356,6 +638,14 @@ const (
 	EventTypeContainerComplete EventType = "container_complete"
 	EventTypeContainerRunning  EventType = "container_running"
 	EventTypeContainerReserved EventType = "container_reserved"
+
+	EventTypeContainerRestarted EventType = "container_restarted"
+	EventTypeSidecarRestarted   EventType = "sidecar_restarted"
+
+	EventTypeDrainProgress EventType = "drain_progress"
+	EventTypeDrainComplete EventType = "drain_complete"
+
+	EventTypeStepProgress EventType = "step_progress"
 )
 
 type LifecycleEvent interface {
@@ -405,6 +695,111 @@ func (ContainerReservedEvent) EventType() EventType   { return EventTypeContaine
 func (e ContainerReservedEvent) Container() Container { return e.RawContainer }
 func (ContainerReservedEvent) lifecycleEvent()        {}
 
+type ContainerRestartedEvent struct {
+	RawContainer Container `json:"container"`
+	Reason       string    `json:"reason"`
+}
+
+func NewContainerRestartedEvent(container Container, reason string) ContainerRestartedEvent {
+	return ContainerRestartedEvent{
+		RawContainer: container,
+		Reason:       reason,
+	}
+}
+
+func (ContainerRestartedEvent) EventType() EventType   { return EventTypeContainerRestarted }
+func (e ContainerRestartedEvent) Container() Container { return e.RawContainer }
+func (ContainerRestartedEvent) lifecycleEvent()        {}
+
+// SidecarRestartedEvent identifies the sidecar by its index in the sidecars
+// of the container.
+type SidecarRestartedEvent struct {
+	RawContainer Container `json:"container"`
+	Sidecar      int       `json:"sidecar"`
+	RestartCount int       `json:"restart_count"`
+	Reason       string    `json:"reason"`
+}
+
+func NewSidecarRestartedEvent(container Container, sidecar, restartCount int, reason string) SidecarRestartedEvent {
+	return SidecarRestartedEvent{
+		RawContainer: container,
+		Sidecar:      sidecar,
+		RestartCount: restartCount,
+		Reason:       reason,
+	}
+}
+
+func (SidecarRestartedEvent) EventType() EventType   { return EventTypeSidecarRestarted }
+func (e SidecarRestartedEvent) Container() Container { return e.RawContainer }
+func (SidecarRestartedEvent) lifecycleEvent()        {}
+
+type DrainProgressEvent struct {
+	Guid      string `json:"guid"`
+	TimedOut  bool   `json:"timed_out"`
+	Processed int    `json:"processed"`
+	Total     int    `json:"total"`
+}
+
+func NewDrainProgressEvent(guid string, timedOut bool, processed, total int) DrainProgressEvent {
+	return DrainProgressEvent{
+		Guid:      guid,
+		TimedOut:  timedOut,
+		Processed: processed,
+		Total:     total,
+	}
+}
+
+func (DrainProgressEvent) EventType() EventType { return EventTypeDrainProgress }
+
+type DrainCompleteEvent struct {
+	Stopped  int `json:"stopped"`
+	TimedOut int `json:"timed_out"`
+	Total    int `json:"total"`
+}
+
+func NewDrainCompleteEvent(stopped, timedOut, total int) DrainCompleteEvent {
+	return DrainCompleteEvent{
+		Stopped:  stopped,
+		TimedOut: timedOut,
+		Total:    total,
+	}
+}
+
+func (DrainCompleteEvent) EventType() EventType { return EventTypeDrainComplete }
+
+type StepPhase string
+
+const (
+	StepPhaseStarted   StepPhase = "started"
+	StepPhaseSucceeded StepPhase = "succeeded"
+	StepPhaseFailed    StepPhase = "failed"
+)
+
+// StepProgressEvent reports the progress of an emit progress action of a
+// container. Duration is zero when the step has started.
+type StepProgressEvent struct {
+	Guid     string        `json:"guid"`
+	Step     string        `json:"step"`
+	Phase    StepPhase     `json:"phase"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+func NewStepProgressEvent(guid, step string, phase StepPhase, duration time.Duration, err error) StepProgressEvent {
+	event := StepProgressEvent{
+		Guid:     guid,
+		Step:     step,
+		Phase:    phase,
+		Duration: duration,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	return event
+}
+
+func (StepProgressEvent) EventType() EventType { return EventTypeStepProgress }
+
 func truncateString(s string, length int) string {
 	if len(s) <= length {
 		return s
@@ -1,7 +1,9 @@
 package executor_test
 
 import (
+	"errors"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -76,6 +78,44 @@ var _ = Describe("Container", func() {
 		})
 	})
 
+	Describe("WithinQuota", func() {
+		var used executor.ExecutorResources
+
+		BeforeEach(func() {
+			used = executor.NewExecutorResources(10, 20, 1)
+		})
+
+		It("returns true when the resource fits in the quota", func() {
+			quota := executor.NewExecutorResources(20, 40, 2)
+			resource := executor.NewResource(10, 20, -1)
+			Expect(used.WithinQuota(&quota, &resource)).To(BeTrue())
+		})
+
+		It("returns false when memory exceeds the quota", func() {
+			quota := executor.NewExecutorResources(20, 40, 2)
+			resource := executor.NewResource(11, 20, -1)
+			Expect(used.WithinQuota(&quota, &resource)).To(BeFalse())
+		})
+
+		It("returns false when disk size exceeds the quota", func() {
+			quota := executor.NewExecutorResources(20, 40, 2)
+			resource := executor.NewResource(10, 21, -1)
+			Expect(used.WithinQuota(&quota, &resource)).To(BeFalse())
+		})
+
+		It("returns false when the number of containers exceeds the quota", func() {
+			quota := executor.NewExecutorResources(20, 40, 1)
+			resource := executor.NewResource(1, 1, -1)
+			Expect(used.WithinQuota(&quota, &resource)).To(BeFalse())
+		})
+
+		It("treats zero values in the quota as unlimited", func() {
+			quota := executor.NewExecutorResources(0, 0, 0)
+			resource := executor.NewResource(1000, 1000, -1)
+			Expect(used.WithinQuota(&quota, &resource)).To(BeTrue())
+		})
+	})
+
 	Describe("TransitionToComplete", func() {
 		var (
 			container     *executor.Container
@@ -107,3 +147,78 @@ var _ = Describe("Container", func() {
 		})
 	})
 })
+
+var _ = Describe("ResourceLimits", func() {
+	Describe("Exceeding", func() {
+		var max executor.ResourceLimits
+
+		BeforeEach(func() {
+			maxCore, maxStack := uint64(0), uint64(8388608)
+			max = executor.ResourceLimits{Core: &maxCore, Stack: &maxStack}
+		})
+
+		It("returns the limits above their maximum", func() {
+			core, stack, nproc := uint64(1), uint64(16777216), uint64(1<<20)
+			limits := &executor.ResourceLimits{Core: &core, Stack: &stack, Nproc: &nproc}
+			Expect(limits.Exceeding(max)).To(Equal([]string{"core", "stack"}))
+		})
+
+		It("returns nothing when the limits are within their maximum", func() {
+			core := uint64(0)
+			limits := &executor.ResourceLimits{Core: &core}
+			Expect(limits.Exceeding(max)).To(BeEmpty())
+		})
+
+		It("returns nothing for nil limits", func() {
+			var limits *executor.ResourceLimits
+			Expect(limits.Exceeding(max)).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("ContainerHealth", func() {
+	var (
+		health executor.ContainerHealth
+		now    time.Time
+	)
+
+	BeforeEach(func() {
+		health = executor.ContainerHealth{}
+		now = time.Unix(1000, 0)
+	})
+
+	Describe("RecordProbe", func() {
+		It("counts consecutive failures and keeps the last output", func() {
+			health.RecordProbe(now, errors.New("connection refused"))
+			health.RecordProbe(now.Add(time.Second), errors.New("timed out"))
+
+			Expect(health.ConsecutiveFailures).To(Equal(2))
+			Expect(health.LastProbeOutput).To(Equal("timed out"))
+			Expect(health.LastProbeTime).To(Equal(now.Add(time.Second).UnixNano()))
+		})
+
+		It("resets the failures after a passing probe", func() {
+			health.RecordProbe(now, errors.New("connection refused"))
+			health.RecordProbe(now, nil)
+
+			Expect(health.ConsecutiveFailures).To(BeZero())
+			Expect(health.LastProbeOutput).To(BeEmpty())
+		})
+
+		It("truncates long output", func() {
+			health.RecordProbe(now, errors.New(strings.Repeat("a", 2*executor.MaxProbeOutputLength)))
+			Expect(health.LastProbeOutput).To(HaveLen(executor.MaxProbeOutputLength))
+		})
+	})
+
+	Describe("TimeSinceHealthy", func() {
+		It("is zero when the container is not healthy", func() {
+			Expect(health.TimeSinceHealthy(now)).To(BeZero())
+		})
+
+		It("is the time since the container became healthy", func() {
+			health.HealthySince = now.UnixNano()
+			Expect(health.TimeSinceHealthy(now.Add(time.Minute))).To(Equal(time.Minute))
+		})
+	})
+})